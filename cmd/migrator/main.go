@@ -6,13 +6,15 @@ import (
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
 func main() {
-	var migrationPath, database_url string
+	var migrationPath, database_url, driver string
 	flag.StringVar(&database_url, "database_url", "", "Path to database URL")
 	flag.StringVar(&migrationPath, "migration-path", "", "Path to store the migrations")
+	flag.StringVar(&driver, "driver", "postgres", "Database driver: postgres or sqlite")
 	flag.Parse()
 
 	if database_url == "" {
@@ -21,8 +23,11 @@ func main() {
 	if migrationPath == "" {
 		panic("migrationPath is required")
 	}
+	if driver != "postgres" && driver != "sqlite" {
+		panic("unknown driver " + driver)
+	}
 
-	m, err := migrate.New("file://"+migrationPath, fmt.Sprintf("postgres://%s", database_url))
+	m, err := migrate.New("file://"+migrationPath, fmt.Sprintf("%s://%s", driver, database_url))
 
 	if err != nil {
 		panic(err)
@@ -4,24 +4,41 @@ import (
 	"auth-service/internal/app"
 	"auth-service/internal/config"
 	"auth-service/internal/lib/crypto"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/tracing"
+	"context"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 const (
 	envLocal = "local"
+	envDev   = "dev"
+	envProd  = "prod"
 )
 
 func main() {
 	cfg := config.MustLoad()
 	crypto.InitCrypto(cfg.Telegram.SECRET_TGID_KEY)
-	log := setupLogger(cfg.Env)
+	log := setupLogger(cfg.Env, cfg.LogLevel)
 
 	log.Info("Loading config")
 
-	application := app.New(log, cfg.GRPC.Port, cfg.Database_url, cfg.TokenTTL, cfg.Telegram.TG_BOT_KEY)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	application := app.New(log, cfg)
 
 	application.AuthServer.MustRun()
 
@@ -31,17 +48,43 @@ func main() {
 
 	sign := <-stop
 
-	log.Info("Signal", sign.String())
-	application.AuthServer.Stop()
+	log.Info("Signal", slog.String("signal", sign.String()))
+	application.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error("tracing shutdown", sl.Err(err))
+	}
 	log.Info("Shutting down...")
 }
 
-func setupLogger(env string) *slog.Logger {
-	var log *slog.Logger
+func setupLogger(env string, level string) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       parseLevel(env, level),
+		ReplaceAttr: sl.Redact,
+	}
+
+	var handler slog.Handler
 	switch env {
 	case envLocal:
-		log = slog.New(
-			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	default:
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+	return slog.New(sl.NewTraceHandler(handler))
+}
+
+// parseLevel берет уровень из конфига, а если он не задан - уровень по умолчанию для окружения.
+func parseLevel(env string, level string) slog.Level {
+	var l slog.Level
+	if level != "" && l.UnmarshalText([]byte(level)) == nil {
+		return l
+	}
+	switch env {
+	case envLocal, envDev:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
 	}
-	return log
 }
@@ -2,26 +2,374 @@ package app
 
 import (
 	"auth-service/internal/app/grpc"
+	"auth-service/internal/config"
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/health"
+	"auth-service/internal/lib/crypto"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/mail"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/oidc"
+	"auth-service/internal/lib/ratelimit"
+	"auth-service/internal/lib/telegram"
 	"auth-service/internal/services/auth"
+	"auth-service/internal/services/webhooks"
+	"auth-service/internal/storage"
+	"auth-service/internal/storage/instrumented"
+	"auth-service/internal/storage/memory"
 	"auth-service/internal/storage/postgres"
+	"auth-service/internal/storage/sqlite"
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 )
 
+const (
+	driverPostgres = "postgres"
+	driverSQLite   = "sqlite"
+	driverMemory   = "memory"
+)
+
+const rateLimitCleanupInterval = 10 * time.Minute
+
 type App struct {
 	AuthServer *authApp.App
+
+	storage     storage.Storage
+	stopWorkers context.CancelFunc
+}
+
+// migrator реализуют хранилища со схемой, которую накатывает cmd/migrator.
+type migrator interface {
+	MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
 }
 
-func New(log *slog.Logger, grpcPort string, storageUrl string, tokenTTL time.Duration, tgToken string) *App {
+func New(log *slog.Logger, cfg *config.Config) *App {
+
+	backend, schemaVersion, err := newStorage(cfg)
+	if err != nil {
+		panic(err)
+	}
+	if pool, ok := backend.(metrics.PoolStater); ok {
+		metrics.RegisterPool(pool)
+	}
+	st := instrumented.New(backend)
+
+	mailer, err := newMailer(log, cfg.Mail)
+	if err != nil {
+		panic(err)
+	}
+	keys, err := signingKeys(cfg.Signing)
+	if err != nil {
+		panic(err)
+	}
+
+	authService := auth.New(auth.Deps{
+		Log:          log,
+		UserSaver:    st,
+		UserProvider: st,
+		AppProvider:  st,
+		Sessions:     st,
+		Audit:        st,
+		Outbox:       st,
+		Accounts:     st,
+		Handoffs:     st,
+		Identities:   st,
+		Passwords:    st,
+		Factors:      st,
+		Providers:    identityProviders(cfg.Identities),
+		Mailer:       mailer,
+	}, auth.Config{
+		TokenTTL:        cfg.TokenTTL,
+		ServiceTokenTTL: cfg.ServiceTokenTTL,
+		ErasureGrace:    cfg.Erasure.GracePeriod,
+		Exchange:        exchangePolicy(cfg.TokenExchange),
+		Handoff:         auth.HandoffPolicy{TTL: cfg.Handoff.TTL, MiniAppURL: cfg.Handoff.MiniAppURL},
+		Password:        passwordPolicy(cfg.Password),
+		TwoFactor:       twoFactorPolicy(cfg.TwoFactor),
+		BotToken:        cfg.Telegram.TG_BOT_KEY,
+		Keys:            keys,
+	})
 
-	storage, err := postgres.InitDB(storageUrl)
+	healthService := newHealth(backend, schemaVersion)
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+
+	limiter, err := newLimiter(workersCtx, log, cfg, backend)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Webhooks.IsEnabled() {
+		go newDispatcher(log, cfg.Webhooks, st).Run(workersCtx)
+	}
+	if cfg.Erasure.IsEnabled() {
+		go runErasure(workersCtx, log, authService, cfg.Erasure)
+	}
+	botClient, err := newBot(workersCtx, log, cfg)
 	if err != nil {
 		panic(err)
 	}
-	authService := auth.New(log, storage, storage, storage, tokenTTL, tgToken)
 
-	authApp := authApp.New(log, authService, grpcPort)
+	authApp := authApp.New(log, cfg, authService, healthService, limiter, st, botClient)
 	return &App{
-		AuthServer: authApp,
+		AuthServer:  authApp,
+		storage:     backend,
+		stopWorkers: stopWorkers,
+	}
+}
+
+// Stop останавливает серверы, фоновые задачи и закрывает хранилище.
+func (a *App) Stop() {
+	a.AuthServer.Stop()
+	a.stopWorkers()
+	a.storage.Close()
+}
+
+func newLimiter(ctx context.Context, log *slog.Logger, cfg *config.Config, backend storage.Storage) (*ratelimit.Limiter, error) {
+	rl := cfg.RateLimit
+	if !rl.IsEnabled() {
+		return ratelimit.New(log, ratelimit.NewMemoryStore(), ratelimit.Limit{}, ratelimit.Limit{}, ratelimit.Limit{}), nil
+	}
+
+	var store ratelimit.Store
+	switch rl.Backend {
+	case "memory", "":
+		store = ratelimit.NewMemoryStore()
+	case driverPostgres:
+		pg, ok := backend.(*postgres.Storage)
+		if !ok {
+			return nil, fmt.Errorf("rate_limit.backend %q requires storage.driver %q", driverPostgres, driverPostgres)
+		}
+		store = pg
+		go cleanupRateLimits(ctx, log, pg)
+	default:
+		return nil, fmt.Errorf("unknown rate_limit.backend %q", rl.Backend)
+	}
+
+	return ratelimit.New(log, store,
+		ratelimit.Limit{Rate: rl.IP.Rate, Burst: rl.IP.Burst},
+		ratelimit.Limit{Rate: rl.User.Rate, Burst: rl.User.Burst},
+		ratelimit.Limit{Rate: rl.App.Rate, Burst: rl.App.Burst},
+	), nil
+}
+
+func cleanupRateLimits(ctx context.Context, log *slog.Logger, pg *postgres.Storage) {
+	ticker := time.NewTicker(rateLimitCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := pg.DeleteIdleRateLimits(ctx, time.Hour); err != nil {
+				log.Warn("rate limit cleanup failed", sl.Err(err))
+			}
+		}
 	}
 }
+
+// runErasure удаляет аккаунты, у которых истек срок на отмену удаления.
+// Несколько инстансов могут работать одновременно: уже удаленный другим
+// инстансом аккаунт просто не найдется.
+func runErasure(ctx context.Context, log *slog.Logger, authService *auth.Auth, cfg config.ErasureConfig) {
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := authService.EraseDue(ctx, cfg.BatchSize)
+				if err != nil {
+					log.Error("account erasure failed", sl.Err(err))
+				}
+				if err != nil || n < cfg.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// newBot возвращает клиент Bot API для вебхука команд модераторов или nil,
+// если бот выключен. Вебхук регистрируется в фоне: недоступный Telegram не
+// должен мешать старту сервиса.
+func newBot(ctx context.Context, log *slog.Logger, cfg *config.Config) (*telegram.Client, error) {
+	if !cfg.Bot.Enabled {
+		return nil, nil
+	}
+	if cfg.Bot.WebhookSecret == "" {
+		return nil, errors.New("bot.webhook_secret is required when bot is enabled")
+	}
+	client := telegram.New(cfg.Bot.APIURL, cfg.Telegram.TG_BOT_KEY, cfg.Bot.Timeout)
+	if cfg.Bot.WebhookURL != "" {
+		go func() {
+			if err := client.SetWebhook(ctx, cfg.Bot.WebhookURL, cfg.Bot.WebhookSecret); err != nil {
+				log.Error("failed to set bot webhook", sl.Err(err))
+			}
+		}()
+	}
+	return client, nil
+}
+
+func newStorage(cfg *config.Config) (storage.Storage, uint, error) {
+	switch cfg.Storage.Driver {
+	case driverPostgres:
+		if cfg.Database_url == "" {
+			return nil, 0, fmt.Errorf("database_url is required for driver %q", driverPostgres)
+		}
+		pg := cfg.Storage.Postgres
+		st, err := postgres.InitDB(cfg.Database_url, postgres.PoolConfig{
+			MaxConns:          pg.MaxConns,
+			MinConns:          pg.MinConns,
+			MaxConnIdleTime:   pg.MaxConnIdleTime,
+			MaxConnLifetime:   pg.MaxConnLifetime,
+			HealthCheckPeriod: pg.HealthCheckPeriod,
+			StatementTimeout:  pg.StatementTimeout,
+			ConnectTimeout:    pg.ConnectTimeout,
+			ConnectRetries:    pg.ConnectRetries,
+			RetryBackoff:      pg.RetryBackoff,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		return st, postgres.SchemaVersion, nil
+	case driverSQLite:
+		if cfg.Database_url == "" {
+			return nil, 0, fmt.Errorf("database_url is required for driver %q", driverSQLite)
+		}
+		st, err := sqlite.New(cfg.Database_url)
+		if err != nil {
+			return nil, 0, err
+		}
+		return st, sqlite.SchemaVersion, nil
+	case driverMemory:
+		return memory.New(), 0, nil
+	default:
+		return nil, 0, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+func newDispatcher(log *slog.Logger, cfg config.WebhooksConfig, st webhooks.Store) *webhooks.Dispatcher {
+	return webhooks.New(log, st, webhooks.Config{
+		PollInterval: cfg.PollInterval,
+		BatchSize:    cfg.BatchSize,
+		Timeout:      cfg.Timeout,
+		MaxAttempts:  cfg.MaxAttempts,
+		BackoffBase:  cfg.BackoffBase,
+		BackoffMax:   cfg.BackoffMax,
+	})
+}
+
+func exchangePolicy(cfg config.TokenExchangeConfig) auth.ExchangePolicy {
+	policy := auth.ExchangePolicy{TTL: cfg.TTL}
+	for _, trust := range cfg.Trust {
+		policy.Rules = append(policy.Rules, auth.TrustRule{From: trust.From, To: trust.To, Scopes: trust.Scopes})
+	}
+	return policy
+}
+
+// identityProviders создает верификаторы ID-токенов для провайдеров с client_ids.
+func identityProviders(cfg config.IdentitiesConfig) map[string]auth.IdentityVerifier {
+	providers := make(map[string]auth.IdentityVerifier)
+	for name, p := range map[string]struct {
+		provider oidc.Provider
+		cfg      config.OIDCConfig
+	}{
+		models.ProviderGoogle: {oidc.Google, cfg.Google},
+		models.ProviderApple:  {oidc.Apple, cfg.Apple},
+	} {
+		if len(p.cfg.ClientIDs) == 0 {
+			continue
+		}
+		p.provider.ClientIDs = p.cfg.ClientIDs
+		providers[name] = oidc.New(p.provider, cfg.Timeout)
+	}
+	return providers
+}
+
+func passwordPolicy(cfg config.PasswordConfig) auth.PasswordPolicy {
+	return auth.PasswordPolicy{
+		Enabled:         cfg.Enabled,
+		MaxFailures:     cfg.MaxFailures,
+		LockoutDuration: cfg.LockoutDuration,
+		VerifyTTL:       cfg.VerifyTTL,
+		ResetTTL:        cfg.ResetTTL,
+		VerifyURL:       cfg.VerifyURL,
+		ResetURL:        cfg.ResetURL,
+	}
+}
+
+func twoFactorPolicy(cfg config.TwoFactorConfig) auth.TwoFactorPolicy {
+	return auth.TwoFactorPolicy{
+		Enabled:           cfg.IsEnabled(),
+		RequiredForAdmins: cfg.AdminsRequired(),
+		StepUpTTL:         cfg.StepUpTTL,
+		Issuer:            cfg.Issuer,
+		MaxFailures:       cfg.MaxFailures,
+		LockoutDuration:   cfg.LockoutDuration,
+	}
+}
+
+func newMailer(log *slog.Logger, cfg config.MailConfig) (auth.Mailer, error) {
+	switch cfg.Driver {
+	case "log", "":
+		return mail.NewLog(log), nil
+	case "file":
+		if cfg.File == "" {
+			return nil, errors.New("mail.file is required for mail.driver \"file\"")
+		}
+		return mail.NewFile(cfg.File), nil
+	case "smtp":
+		if cfg.SMTP.Addr == "" || cfg.From == "" {
+			return nil, errors.New("mail.smtp.addr and mail.from are required for mail.driver \"smtp\"")
+		}
+		return mail.NewSMTP(cfg.SMTP.Addr, cfg.From, cfg.SMTP.Username, cfg.SMTP.Password, cfg.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown mail.driver %q", cfg.Driver)
+	}
+}
+
+// signingKeys загружает ключи подписи токенов. Без key_files токены, как и
+// раньше, подписываются HS256 секретом приложения.
+func signingKeys(cfg config.SigningConfig) (*jwt.Keys, error) {
+	if len(cfg.KeyFiles) == 0 {
+		return nil, nil
+	}
+	return jwt.LoadKeys(cfg.KeyFiles...)
+}
+
+func newHealth(backend storage.Storage, schemaVersion uint) *health.Health {
+	h := health.New()
+
+	h.AddCheck("database", backend.Ping)
+	if m, ok := backend.(migrator); ok {
+		h.AddCheck("migrations", func(ctx context.Context) error {
+			version, dirty, err := m.MigrationVersion(ctx)
+			if err != nil {
+				return err
+			}
+			if dirty {
+				return fmt.Errorf("migration %d is dirty", version)
+			}
+			if version < schemaVersion {
+				return fmt.Errorf("schema version %d, want %d", version, schemaVersion)
+			}
+			return nil
+		})
+	}
+	h.AddCheck("crypto", func(ctx context.Context) error {
+		if !crypto.Initialized() {
+			return errors.New("crypto key is not initialized")
+		}
+		return nil
+	})
+
+	return h
+}
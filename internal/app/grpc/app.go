@@ -1,30 +1,104 @@
 package authApp
 
 import (
+	"auth-service/internal/config"
+	"auth-service/internal/grpc/api"
 	authgrpc "auth-service/internal/grpc/auth"
+	"auth-service/internal/grpc/bot"
+	"auth-service/internal/grpc/health"
+	"auth-service/internal/grpc/middleware"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/ratelimit"
+	"auth-service/internal/lib/telegram"
 	"auth-service/internal/services/auth"
 	"context"
+	"errors"
+	"fmt"
+	"net"
 
 	"log/slog"
 
 	"net/http"
-	"os"
 	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type App struct {
-	log        *slog.Logger
-	controller *http.Server
-	port       string
+	log          *slog.Logger
+	controller   *http.Server
+	grpcServer   *grpc.Server
+	health       *health.Health
+	port         string
+	grpcPort     string
+	drainTimeout time.Duration
 }
 
 func New(log *slog.Logger,
+	cfg *config.Config,
 	authService *auth.Auth,
-	port string) *App {
+	healthService *health.Health,
+	limiter *ratelimit.Limiter,
+	apps middleware.AppLister,
+	botClient *telegram.Client) *App {
+
+	proxyHops := 0
+	if cfg.RateLimit.TrustProxy {
+		proxyHops = cfg.RateLimit.ProxyHops
+	}
 
-	controllers := authgrpc.Register(*authService, port)
+	router := mux.NewRouter()
+	router.Use(otelmux.Middleware("auth-sso"))
+	router.Use(middleware.RequestLogger(log))
+	router.Use(middleware.ClientInfo(proxyHops))
+	router.Use(metrics.Middleware)
+	router.Use(middleware.RateLimit(limiter, authService.UserPseudonym, proxyHops))
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+	healthService.Register(router)
+	api.RegisterDocs(router)
+	authgrpc.RegisterV1(router, *authService)
+	if cfg.API.LegacyRoutesEnabled() {
+		authgrpc.Register(router, *authService)
+	}
+	if botClient != nil {
+		bot.Register(router, log, *authService, botClient, cfg.Bot.WebhookSecret)
+	}
 
-	return &App{log, controllers, port}
+	grpcServer := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			middleware.UnaryRequestLogger(log),
+			middleware.UnaryClientInfo(),
+			middleware.UnaryRateLimit(limiter, authService.UserPseudonym),
+		),
+		grpc.ChainStreamInterceptor(
+			middleware.StreamRequestLogger(log),
+			middleware.StreamClientInfo(),
+			middleware.StreamRateLimit(limiter),
+		),
+	)
+	healthpb.RegisterHealthServer(grpcServer, healthService.GRPC())
+	authgrpc.RegisterGRPC(grpcServer, *authService)
+
+	var handler http.Handler = router
+	if cfg.CORS.IsEnabled() {
+		handler = middleware.CORS(middleware.NewOrigins(log, apps, cfg.CORS.CacheTTL))(router)
+	}
+
+	return &App{
+		log:          log,
+		controller:   &http.Server{Addr: cfg.GRPC.Port, Handler: handler},
+		grpcServer:   grpcServer,
+		health:       healthService,
+		port:         cfg.GRPC.Port,
+		grpcPort:     cfg.GRPC.GRPCPort,
+		drainTimeout: cfg.GRPC.DrainTimeout,
+	}
 
 }
 
@@ -36,25 +110,56 @@ func (a *App) MustRun() {
 func (a *App) Run() error {
 	const op = "authapp.Run"
 
-	log := a.log.With(slog.String("op", op), slog.String("port", a.port))
+	log := a.log.With(slog.String("op", op), slog.String("port", a.port), slog.String("grpc_port", a.grpcPort))
+
+	l, err := net.Listen("tcp", a.grpcPort)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
 	go func() {
-		if err := a.controller.ListenAndServe(); err != nil {
-			log.Info("start", slog.String("running on", a.port))
+		if err := a.controller.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("http server stopped", sl.Err(err))
 		}
 	}()
+	go func() {
+		if err := a.grpcServer.Serve(l); err != nil {
+			log.Error("grpc server stopped", sl.Err(err))
+		}
+	}()
+
+	a.health.SetServing()
+	log.Info("start", slog.String("running on", a.port))
 	return nil
 }
 
+// Stop сначала переводит сервис в состояние draining, чтобы /readyz и grpc.health.v1
+// сообщили балансировщику о выводе из ротации, и только потом закрывает серверы.
 func (a *App) Stop() {
 	const op = "authApp.Stop"
-	a.log.Info("shutting down")
-	a.log.With(slog.String("op", op)).Info("stopping")
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("draining", slog.Duration("drain_timeout", a.drainTimeout))
+	a.health.SetDraining()
+	time.Sleep(a.drainTimeout)
+
+	log.Info("stopping")
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
 	defer cancel()
 
-	a.controller.Shutdown(ctx)
+	if err := a.controller.Shutdown(ctx); err != nil {
+		log.Error("http shutdown", sl.Err(err))
+	}
 
-	os.Exit(0)
+	stopped := make(chan struct{})
+	go func() {
+		a.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		a.grpcServer.Stop()
+	}
 }
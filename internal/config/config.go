@@ -2,7 +2,6 @@ package config
 
 import (
 	"flag"
-	"fmt"
 	"github.com/ilyakaznacheev/cleanenv"
 	"log"
 	"os"
@@ -11,21 +10,250 @@ import (
 
 type Config struct {
 	Env          string         `yaml:"env" env-default:"local"`
-	Database_url string         `yaml:"database_url" env-required:"true"`
+	LogLevel     string         `yaml:"log_level"`
+	Database_url string         `yaml:"database_url"`
+	Storage      StorageConfig  `yaml:"storage"`
 	GRPC         GRPCConfig     `yaml:"grpc" env-required:"true"`
 	Telegram     TelegramConfig `yaml:"telegram" env-required:"true"`
 	TokenTTL     time.Duration  `yaml:"token_ttl" env-default:"5h"`
+	// ServiceTokenTTL - срок жизни токенов client_credentials.
+	ServiceTokenTTL time.Duration   `yaml:"service_token_ttl" env-default:"15m"`
+	Tracing         TracingConfig   `yaml:"tracing"`
+	RateLimit       RateLimitConfig `yaml:"rate_limit"`
+	CORS            CORSConfig      `yaml:"cors"`
+	API             APIConfig       `yaml:"api"`
+	// TokenExchange - обмен пользовательских токенов между приложениями (RFC 8693).
+	TokenExchange TokenExchangeConfig `yaml:"token_exchange"`
+	Webhooks      WebhooksConfig      `yaml:"webhooks"`
+	Erasure       ErasureConfig       `yaml:"erasure"`
+	Bot           BotConfig           `yaml:"bot"`
+	Handoff       HandoffConfig       `yaml:"handoff"`
+	Identities    IdentitiesConfig    `yaml:"identities"`
+	Password      PasswordConfig      `yaml:"password"`
+	Mail          MailConfig          `yaml:"mail"`
+	TwoFactor     TwoFactorConfig     `yaml:"two_factor"`
+	Signing       SigningConfig       `yaml:"signing"`
+}
+
+// SigningConfig - закрытые ключи ES256 (P-256, PEM) для подписи токенов.
+// Первый ключ подписывает, остальные остаются в /.well-known/jwks.json после
+// ротации, пока не истекут выданные ими токены. Без ключей токены
+// подписываются HS256 секретом приложения и проверяются только им.
+type SigningConfig struct {
+	KeyFiles []string `yaml:"key_files"`
+}
+
+// PasswordConfig - вход по email и паролю для пользователей без Telegram.
+// После MaxFailures неудачных попыток подряд вход блокируется на LockoutDuration.
+// VerifyURL и ResetURL - страницы клиента, куда ведут ссылки из писем,
+// к ним добавляется ?token=<токен>.
+type PasswordConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	MaxFailures     int           `yaml:"max_failures" env-default:"5"`
+	LockoutDuration time.Duration `yaml:"lockout_duration" env-default:"15m"`
+	VerifyTTL       time.Duration `yaml:"verify_ttl" env-default:"24h"`
+	ResetTTL        time.Duration `yaml:"reset_ttl" env-default:"1h"`
+	VerifyURL       string        `yaml:"verify_url"`
+	ResetURL        string        `yaml:"reset_url"`
+}
+
+// TwoFactorConfig - второй фактор TOTP для администраторов. Администратор
+// получает роль admin только после подтверждения кода в пределах StepUpTTL.
+// RequiredForAdmins требует этого от всех администраторов: не подключившему TOTP
+// admin-check отказывает, пока он его не заведет. Оба флага включены, пока явно
+// не заданы false.
+type TwoFactorConfig struct {
+	Enabled           *bool         `yaml:"enabled"`
+	RequiredForAdmins *bool         `yaml:"required_for_admins"`
+	StepUpTTL         time.Duration `yaml:"step_up_ttl" env-default:"15m"`
+	Issuer            string        `yaml:"issuer" env-default:"SSO"`
+	MaxFailures       int           `yaml:"max_failures" env-default:"5"`
+	LockoutDuration   time.Duration `yaml:"lockout_duration" env-default:"15m"`
+}
+
+func (c TwoFactorConfig) IsEnabled() bool { return enabledByDefault(c.Enabled) }
+
+func (c TwoFactorConfig) AdminsRequired() bool { return enabledByDefault(c.RequiredForAdmins) }
+
+// MailConfig - отправка писем. Driver: log - письма пишутся в лог, file -
+// дописываются в File по одному JSON на строку, smtp - отправляются через SMTP.
+type MailConfig struct {
+	Driver  string        `yaml:"driver" env-default:"log"`
+	File    string        `yaml:"file"`
+	From    string        `yaml:"from"`
+	SMTP    SMTPConfig    `yaml:"smtp"`
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+type SMTPConfig struct {
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// IdentitiesConfig - учетные записи Google и Apple, которые можно привязать к
+// аккаунту и входить через них. Провайдер включен, если заданы его client_ids -
+// допустимые aud ID-токенов (веб-клиент и мобильные приложения).
+type IdentitiesConfig struct {
+	Google  OIDCConfig    `yaml:"google"`
+	Apple   OIDCConfig    `yaml:"apple"`
+	Timeout time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+type OIDCConfig struct {
+	ClientIDs []string `yaml:"client_ids"`
+}
+
+// HandoffConfig - вход в браузере по QR-коду через мини-приложение. MiniAppURL -
+// ссылка на мини-приложение (https://t.me/<bot> или https://t.me/<bot>/<app>),
+// к ней добавляется ?startapp=<code>; без нее браузер строит ссылку сам.
+type HandoffConfig struct {
+	TTL        time.Duration `yaml:"ttl" env-default:"5m"`
+	MiniAppURL string        `yaml:"mini_app_url"`
+}
+
+// BotConfig - вебхук Telegram-бота с командами модераторов. Бот работает с
+// токеном telegram.TG_BOT_KEY, APIURL заменяется в тестах на локальный фейк.
+// Если задан WebhookURL, сервис сам регистрирует вебхук при старте.
+type BotConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	APIURL  string `yaml:"api_url" env-default:"https://api.telegram.org"`
+	// WebhookSecret - secret_token вебхука, обязателен при Enabled.
+	WebhookSecret string        `yaml:"webhook_secret"`
+	WebhookURL    string        `yaml:"webhook_url"`
+	Timeout       time.Duration `yaml:"timeout" env-default:"10s"`
+}
+
+// ErasureConfig - удаление аккаунтов по DELETE /v1/me. Аккаунт стирается через
+// GracePeriod после запроса, до этого пользователь может отменить удаление.
+// Фоновое удаление включено, пока явно не задано enabled: false.
+type ErasureConfig struct {
+	Enabled      *bool         `yaml:"enabled"`
+	GracePeriod  time.Duration `yaml:"grace_period" env-default:"720h"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1m"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+}
+
+func (c ErasureConfig) IsEnabled() bool { return enabledByDefault(c.Enabled) }
+
+// WebhooksConfig - фоновая доставка событий outbox в вебхуки приложений.
+// Неудачная доставка повторяется через BackoffBase, 2*BackoffBase, ... но не
+// реже BackoffMax; после MaxAttempts попыток она попадает в dead. Доставка
+// включена, пока явно не задано enabled: false.
+type WebhooksConfig struct {
+	Enabled      *bool         `yaml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"2s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"10"`
+	BackoffBase  time.Duration `yaml:"backoff_base" env-default:"30s"`
+	BackoffMax   time.Duration `yaml:"backoff_max" env-default:"1h"`
+}
+
+func (c WebhooksConfig) IsEnabled() bool { return enabledByDefault(c.Enabled) }
+
+// TokenExchangeConfig - Trust перечисляет, какие приложения могут обменивать
+// токены своих пользователей на токены каких приложений. Без правил обмен запрещен.
+type TokenExchangeConfig struct {
+	TTL   time.Duration `yaml:"ttl" env-default:"15m"`
+	Trust []TrustConfig `yaml:"trust"`
+}
+
+type TrustConfig struct {
+	From   int64    `yaml:"from"`
+	To     []int64  `yaml:"to"`
+	Scopes []string `yaml:"scopes"`
+}
+
+// APIConfig - LegacyRoutes оставляет старые /register, /validate и /isAdmin
+// рядом с /v1 на время перехода клиентов. Они включены, пока явно не задано
+// legacy_routes: false.
+type APIConfig struct {
+	LegacyRoutes *bool `yaml:"legacy_routes"`
+}
+
+func (c APIConfig) LegacyRoutesEnabled() bool { return enabledByDefault(c.LegacyRoutes) }
+
+// CORSConfig - разрешенные origin-ы берутся из apps.allowed_origins и кэшируются на CacheTTL.
+// CORS включен, пока явно не задано enabled: false.
+type CORSConfig struct {
+	Enabled  *bool         `yaml:"enabled"`
+	CacheTTL time.Duration `yaml:"cache_ttl" env-default:"1m"`
+}
+
+func (c CORSConfig) IsEnabled() bool { return enabledByDefault(c.Enabled) }
+
+// RateLimitConfig - лимиты запросов, включены, пока явно не задано enabled: false.
+type RateLimitConfig struct {
+	Enabled *bool `yaml:"enabled"`
+	// Backend: memory - лимиты в пределах инстанса, postgres - общие для всех инстансов.
+	Backend string `yaml:"backend" env-default:"memory"`
+	// TrustProxy разрешает брать IP клиента из X-Forwarded-For - для лимитов и сессий.
+	TrustProxy bool `yaml:"trust_proxy"`
+	// ProxyHops - сколько своих прокси стоит перед сервисом. IP клиента - запись
+	// X-Forwarded-For, добавленная самым дальним из них; все, что левее, прислал клиент.
+	ProxyHops int         `yaml:"proxy_hops" env-default:"1"`
+	IP        LimitConfig `yaml:"ip"`
+	User      LimitConfig `yaml:"user"`
+	App       LimitConfig `yaml:"app"`
+}
+
+func (c RateLimitConfig) IsEnabled() bool { return enabledByDefault(c.Enabled) }
+
+// LimitConfig - Rate запросов в секунду с запасом Burst. Нулевой Rate отключает лимит.
+type LimitConfig struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+type TracingConfig struct {
+	// Exporter: none, stdout или otlp.
+	Exporter string `yaml:"exporter" env-default:"none"`
+	Endpoint string `yaml:"endpoint" env-default:"localhost:4317"`
+	// Insecure отключает TLS у OTLP-экспортера, по умолчанию соединение шифруется.
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+	ServiceName string  `yaml:"service_name" env-default:"auth-sso"`
+}
+
+type StorageConfig struct {
+	// Driver выбирает бэкенд хранилища: postgres, sqlite или memory.
+	// Для sqlite в database_url указывается путь к файлу базы.
+	Driver   string         `yaml:"driver" env-default:"postgres"`
+	Postgres PostgresConfig `yaml:"postgres"`
+}
+
+type PostgresConfig struct {
+	MaxConns          int32         `yaml:"max_conns" env-default:"10"`
+	MinConns          int32         `yaml:"min_conns" env-default:"2"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time" env-default:"5m"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime" env-default:"1h"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" env-default:"1m"`
+	StatementTimeout  time.Duration `yaml:"statement_timeout" env-default:"5s"`
+	ConnectTimeout    time.Duration `yaml:"connect_timeout" env-default:"5s"`
+	ConnectRetries    int           `yaml:"connect_retries" env-default:"5"`
+	RetryBackoff      time.Duration `yaml:"retry_backoff" env-default:"500ms"`
 }
 
 type GRPCConfig struct {
-	Port    string `yaml:"port" env-default:"8080"`
-	Timeout string `yaml:"timeout" env-default:"5h"`
+	Port     string `yaml:"port" env-default:"8080"`
+	GRPCPort string `yaml:"grpc_port" env-default:":9090"`
+	Timeout  string `yaml:"timeout" env-default:"5h"`
+	// DrainTimeout - сколько сервис отвечает "draining" на /readyz перед остановкой.
+	DrainTimeout time.Duration `yaml:"drain_timeout" env-default:"5s"`
 }
 type TelegramConfig struct {
 	SECRET_TGID_KEY string `yaml:"SECRET_TGID_KEY" env-default:"dop_dop_yes_yes"`
 	TG_BOT_KEY      string `yaml:"TG_BOT_KEY" env-required:"true"`
 }
 
+// enabledByDefault - значение флага, включенного по умолчанию. cleanenv
+// подставляет env-default и поверх явного false из yaml (false для него пустое
+// значение), поэтому такие флаги - указатели, а nil значит "не задан".
+func enabledByDefault(flag *bool) bool {
+	return flag == nil || *flag
+}
+
 func MustLoad() *Config {
 	path := fetchConfigPath()
 
@@ -33,6 +261,11 @@ func MustLoad() *Config {
 		panic("config file path is empty")
 	}
 
+	return MustLoadPath(path)
+}
+
+// MustLoadPath читает конфиг из файла path, переменные окружения переопределяют его.
+func MustLoadPath(path string) *Config {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		panic("config file not found")
 	}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const baseYAML = `
+grpc:
+  port: ":8080"
+telegram:
+  TG_BOT_KEY: "123:test"
+`
+
+func load(t *testing.T, yaml string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(baseYAML+yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return MustLoadPath(path)
+}
+
+func TestTracingUsesTLSByDefault(t *testing.T) {
+	if load(t, "").Tracing.Insecure {
+		t.Fatal("Tracing.Insecure = true without insecure in yaml")
+	}
+	if !load(t, "tracing:\n  insecure: true\n").Tracing.Insecure {
+		t.Fatal("Tracing.Insecure = false with insecure: true")
+	}
+}
+
+func TestRateLimitCanBeDisabled(t *testing.T) {
+	if !load(t, "").RateLimit.IsEnabled() {
+		t.Fatal("rate limit disabled by default")
+	}
+	if load(t, "rate_limit:\n  enabled: false\n").RateLimit.IsEnabled() {
+		t.Fatal("rate limit enabled with enabled: false")
+	}
+}
+
+func TestCORSCanBeDisabled(t *testing.T) {
+	if !load(t, "").CORS.IsEnabled() {
+		t.Fatal("CORS disabled by default")
+	}
+	if load(t, "cors:\n  enabled: false\n").CORS.IsEnabled() {
+		t.Fatal("CORS enabled with enabled: false")
+	}
+}
+
+func TestLegacyRoutesCanBeDisabled(t *testing.T) {
+	if !load(t, "").API.LegacyRoutesEnabled() {
+		t.Fatal("legacy routes disabled by default")
+	}
+	if load(t, "api:\n  legacy_routes: false\n").API.LegacyRoutesEnabled() {
+		t.Fatal("legacy routes enabled with legacy_routes: false")
+	}
+}
+
+func TestWebhooksCanBeDisabled(t *testing.T) {
+	if !load(t, "").Webhooks.IsEnabled() {
+		t.Fatal("webhooks disabled by default")
+	}
+	if load(t, "webhooks:\n  enabled: false\n").Webhooks.IsEnabled() {
+		t.Fatal("webhooks enabled with enabled: false")
+	}
+}
+
+func TestErasureCanBeDisabled(t *testing.T) {
+	if !load(t, "").Erasure.IsEnabled() {
+		t.Fatal("erasure disabled by default")
+	}
+	if load(t, "erasure:\n  enabled: false\n").Erasure.IsEnabled() {
+		t.Fatal("erasure enabled with enabled: false")
+	}
+}
+
+func TestTwoFactorCanBeDisabled(t *testing.T) {
+	cfg := load(t, "").TwoFactor
+	if !cfg.IsEnabled() || !cfg.AdminsRequired() {
+		t.Fatal("two-factor or admin requirement disabled by default")
+	}
+	cfg = load(t, "two_factor:\n  enabled: false\n  required_for_admins: false\n").TwoFactor
+	if cfg.IsEnabled() {
+		t.Fatal("two-factor enabled with enabled: false")
+	}
+	if cfg.AdminsRequired() {
+		t.Fatal("admin requirement enabled with required_for_admins: false")
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// DeletedPrefix начинает псевдоним, которым при удалении аккаунта заменяется
+// sub пользователя в журнале аудита.
+const DeletedPrefix = "deleted:"
+
+// Membership - приложение, в которое пользователь входил. Собирается из сессий.
+type Membership struct {
+	AppID       int64     `json:"app_id"`
+	AppName     string    `json:"app_name"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// Export - все, что сервис хранит о пользователе: профиль, привязанные учетные
+// записи, сессии, включая завершенные, приложения и события аудита, где он
+// actor или subject.
+type Export struct {
+	User        User         `json:"user"`
+	Roles       []string     `json:"roles"`
+	Identities  []Identity   `json:"identities"`
+	Sessions    []Session    `json:"sessions"`
+	Memberships []Membership `json:"memberships"`
+	AuditEvents []AuditEvent `json:"audit_events"`
+	ExportedAt  time.Time    `json:"exported_at"`
+}
+
+// Erasure - запланированное удаление аккаунта. До EraseAt его можно отменить.
+type Erasure struct {
+	EraseAt time.Time `json:"erase_at"`
+}
@@ -4,4 +4,8 @@ type App struct {
 	ID     int32
 	Name   string
 	Secret string
+	// AllowedOrigins - origin-ы мини-аппов, которым разрешены CORS-запросы от имени приложения.
+	AllowedOrigins []string
+	// Scopes - права, которые приложение может получить через client_credentials.
+	Scopes []string
 }
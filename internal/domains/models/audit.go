@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Типы событий журнала аудита.
+const (
+	AuditLogin             = "login"
+	AuditHandoffApproved   = "handoff_approved"
+	AuditRegister          = "register"
+	AuditClientCredentials = "client_credentials"
+	AuditTokenExchange     = "token_exchange"
+	AuditBan               = "ban"
+	AuditUnban             = "unban"
+	AuditPromote           = "promote"
+	AuditDemote            = "demote"
+	AuditWhois             = "whois"
+	AuditSessionRevoked    = "session_revoked"
+	AuditIdentityLinked    = "identity_linked"
+	AuditIdentityUnlinked  = "identity_unlinked"
+	AuditEmailVerified     = "email_verified"
+	AuditPasswordReset     = "password_reset"
+	AuditDataExport        = "data_export"
+	AuditDeletionRequested = "deletion_requested"
+	AuditDeletionCanceled  = "deletion_canceled"
+	AuditAccountErased     = "account_erased"
+	AuditTOTPEnrollStarted = "totp_enroll_started"
+	AuditTOTPEnrolled      = "totp_enrolled"
+	AuditTOTPDisabled      = "totp_disabled"
+	AuditRecoveryCodes     = "recovery_codes_regenerated"
+	AuditStepUp            = "step_up"
+)
+
+// Исход события аудита.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEvent - запись журнала аудита. Actor - кто действовал: sub пользователя
+// или "app:<id>" приложения; Subject - чей аккаунт затронут. Reason заполнен
+// у неудачных событий и совпадает с кодом ошибки /v1.
+type AuditEvent struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Actor     string    `json:"actor"`
+	Subject   string    `json:"subject"`
+	AppID     int64     `json:"app_id"`
+	IP        string    `json:"ip"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditFilter - условия выборки журнала. Пустые поля не ограничивают выборку.
+// User совпадает и с Actor, и с Subject. События отдаются по возрастанию ID,
+// AfterID - курсор для следующей страницы.
+type AuditFilter struct {
+	User    string
+	AppID   int64
+	Type    string
+	Since   time.Time
+	Until   time.Time
+	AfterID int64
+	Limit   int
+}
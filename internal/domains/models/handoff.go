@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Handoff - вход в браузере через мини-приложение. Браузер получает Code для
+// QR-кода и секретный device_code, которым опрашивает сервис; мини-приложение
+// подтверждает Code через initData. В хранилище лежит только хэш device_code.
+type Handoff struct {
+	Code       string
+	DeviceHash string
+	AppID      int64
+	// UserID - sub подтвердившего пользователя, пусто до подтверждения.
+	UserID     string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	ApprovedAt *time.Time
+	// IP, UserAgent и Platform - браузер, запросивший код. Их видит
+	// подтверждающий пользователь.
+	IP        string
+	UserAgent string
+	Platform  string
+}
+
+// HandoffInfo - ответ /v1/auth/handoff/info и /v1/auth/handoff/approve: какое
+// приложение и с какого устройства просит вход. Мини-приложение показывает его
+// пользователю, чтобы тот не подтвердил чужой код.
+type HandoffInfo struct {
+	AppID     int64     `json:"app_id"`
+	AppName   string    `json:"app_name"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Platform  string    `json:"platform"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// HandoffStart - ответ POST /v1/auth/handoff. Link - ссылка на мини-приложение
+// с кодом в startapp, ее показывают QR-кодом; пусто, если ссылка не настроена.
+type HandoffStart struct {
+	Code       string    `json:"code"`
+	DeviceCode string    `json:"device_code"`
+	Link       string    `json:"link,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type HandoffRequest struct {
+	ServiceId int64 `json:"serviceId"`
+}
+
+type HandoffApproveRequest struct {
+	InitData string `json:"initData"`
+	Code     string `json:"code"`
+}
+
+type HandoffTokenRequest struct {
+	DeviceCode string `json:"device_code"`
+	// Platform записывается в сессию браузера.
+	Platform string `json:"platform,omitempty"`
+}
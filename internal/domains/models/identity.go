@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Провайдеры внешних учетных записей. Telegram - основная учетная запись
+// пользователя (users.tgid), в identities он не хранится.
+const (
+	ProviderEmail  = "email"
+	ProviderGoogle = "google"
+	ProviderApple  = "apple"
+)
+
+// AccountPrefix начинает sub аккаунтов, созданных без Telegram (по email и
+// паролю). У остальных аккаунтов sub - зашифрованный Telegram ID.
+const AccountPrefix = "acct:"
+
+// Identity - внешняя учетная запись, привязанная к аккаунту. Subject -
+// идентификатор пользователя у провайдера (sub ID-токена, у email - сам адрес
+// в нижнем регистре), у аккаунта не больше одной учетной записи каждого провайдера.
+type Identity struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"-"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Поля входа по паролю, заполнены только у ProviderEmail. VerifiedAt -
+	// когда адрес подтвержден, до этого войти по паролю нельзя.
+	PasswordHash string     `json:"-"`
+	VerifiedAt   *time.Time `json:"verified_at,omitempty"`
+	FailedLogins int        `json:"-"`
+	LockedUntil  *time.Time `json:"-"`
+}
+
+// Назначения токенов из писем.
+const (
+	EmailTokenVerify = "verify"
+	EmailTokenReset  = "reset"
+)
+
+// EmailToken - одноразовый токен из письма. Хранится только его хэш.
+type EmailToken struct {
+	Hash       string
+	IdentityID int64
+	Purpose    string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// IdentityLinkRequest - для google и apple передается IDToken, для email -
+// Email и Password.
+type IdentityLinkRequest struct {
+	Provider string `json:"provider"`
+	IDToken  string `json:"id_token,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type IdentityLoginRequest struct {
+	Provider  string `json:"provider"`
+	IDToken   string `json:"id_token"`
+	ServiceId int64  `json:"serviceId"`
+	// Platform записывается в сессию.
+	Platform string `json:"platform,omitempty"`
+}
+
+type EmailRegisterRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	FirstName string `json:"first_name,omitempty"`
+	ServiceId int64  `json:"serviceId"`
+}
+
+type EmailLoginRequest struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	ServiceId int64  `json:"serviceId"`
+	// Platform записывается в сессию.
+	Platform string `json:"platform,omitempty"`
+}
+
+type EmailTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
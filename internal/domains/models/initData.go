@@ -3,6 +3,8 @@ package models
 type InitDataRequest struct {
 	InitData  string `json:"initData"`
 	ServiceId int64  `json:"serviceId"`
+	// Platform - Telegram.WebApp.platform, записывается в сессию.
+	Platform string `json:"platform,omitempty"`
 }
 type InitDataUnsafe struct {
 	User         TelegramUser `json:"user"`
@@ -2,4 +2,7 @@ package models
 
 type IsAdmin struct {
 	InitData string `json:"initData"`
+	// OTP - код TOTP или код восстановления. Обязателен для администраторов
+	// со вторым фактором.
+	OTP string `json:"otp,omitempty"`
 }
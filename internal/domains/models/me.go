@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Me - профиль владельца токена приложения.
+type Me struct {
+	User      UserResponse `json:"user"`
+	Roles     []string     `json:"roles"`
+	ServiceID int64        `json:"serviceId"`
+	// EraseAt - дата удаления аккаунта, если пользователь его запросил.
+	EraseAt *time.Time `json:"erase_at,omitempty"`
+	// StepUpRequired - пользователь администратор, но роль admin не выдана:
+	// сессия не подтверждала второй фактор в пределах two_factor.step_up_ttl.
+	StepUpRequired bool `json:"step_up_required,omitempty"`
+}
@@ -4,4 +4,6 @@ type RegisterRequest struct {
 	UserHash       string `json:"initData"`
 	UserNameLocale string `json:"userNameLocale"`
 	ServiceID      int64  `json:"serviceId"`
+	// Platform - Telegram.WebApp.platform, записывается в сессию.
+	Platform string `json:"platform,omitempty"`
 }
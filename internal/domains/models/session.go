@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Session - семейство токенов одного входа: токен из login/register и все токены,
+// полученные из него обменом, несут один claim sid.
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	AppID      int64      `json:"app_id"`
+	Platform   string     `json:"platform"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// MFAAt - когда в этой сессии последний раз подтвержден второй фактор.
+	MFAAt *time.Time `json:"mfa_at,omitempty"`
+	// Current отмечает сессию токена, которым сделан запрос.
+	Current bool `json:"current"`
+}
+
+// Active сообщает, что сессия не завершена.
+func (s Session) Active() bool {
+	return s.RevokedAt == nil
+}
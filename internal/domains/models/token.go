@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Тип субъекта токена, claim sub_type. Токены без sub_type выпущены до его
+// появления и считаются пользовательскими.
+const (
+	SubjectUser    = "user"
+	SubjectService = "service"
+)
+
+// Права сервисных токенов client_credentials.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopeAuditRead  = "audit:read"
+	ScopeWebhooks   = "webhooks:manage"
+	ScopeEventsRead = "events:read"
+)
+
+// ServiceToken - токен, выданный через /v1/oauth/token: client_credentials или обмен.
+type ServiceToken struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+	Scopes      []string
+}
+
+// Caller - приложение, предъявившее сервисный токен.
+type Caller struct {
+	AppID  int64
+	Scopes []string
+}
+
+// UserInfo - пользователь с ролями для admin API.
+type UserInfo struct {
+	User  UserResponse `json:"user"`
+	Roles []string     `json:"roles"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// TOTPFactor - второй фактор пользователя. Secret зашифрован crypto.Seal.
+// Пока ConfirmedAt пуст, фактор только заведен и не действует: пользователь
+// еще не ввел из аутентификатора ни одного кода.
+type TOTPFactor struct {
+	UserID    string
+	Secret    string
+	CreatedAt time.Time
+	// ConfirmedAt - когда фактор подтвержден первым кодом.
+	ConfirmedAt *time.Time
+	// LastStep - шаг последнего принятого кода, коды с шагом не больше него
+	// не принимаются повторно.
+	LastStep int64
+	// LastVerifiedAt - когда пользователь последний раз подтвердил второй
+	// фактор кодом TOTP или кодом восстановления.
+	LastVerifiedAt *time.Time
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
+
+// Confirmed сообщает, что фактор включен.
+func (f TOTPFactor) Confirmed() bool {
+	return f.ConfirmedAt != nil
+}
+
+// TwoFactorStatus - состояние второго фактора владельца токена.
+type TwoFactorStatus struct {
+	// Enabled - TOTP подтвержден и требуется для прав администратора.
+	Enabled bool `json:"enabled"`
+	// Required - фактор обязателен для пользователя, даже если он его еще не
+	// завел (two_factor.required_for_admins).
+	Required          bool       `json:"required"`
+	EnrolledAt        *time.Time `json:"enrolled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+	// StepUpUntil - до какого момента сессия токена считается подтвержденной.
+	StepUpUntil *time.Time `json:"step_up_until,omitempty"`
+}
+
+// TOTPEnrollment - секрет для аутентификатора: URI для QR-кода и тот же
+// секрет для ручного ввода.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes показываются пользователю один раз, хранятся только их хэши.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+type OTPRequest struct {
+	// Code - код из аутентификатора или код восстановления.
+	Code string `json:"code"`
+}
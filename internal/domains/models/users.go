@@ -13,6 +13,8 @@ type User struct {
 	PhotoURL       string    `json:"photo_url" sql:"photo_url"`
 	IsAdmin        bool      `json:"is_admin" sql:"is_admin"`
 	IsBanned       bool      `json:"is_banned" sql:"is_banned"`
+	// EraseAt - когда аккаунт будет удален по запросу пользователя, nil - удаление не запрошено.
+	EraseAt *time.Time `json:"erase_at,omitempty" sql:"erase_at"`
 }
 type UserResponse struct {
 	ID             string `json:"id" sql:"id"`
@@ -24,3 +26,23 @@ type UserResponse struct {
 	PhotoURL       string `json:"photo_url" sql:"photo_url"`
 	IsBanned       bool   `json:"is_banned" sql:"is_banned"`
 }
+
+func (u User) Response() UserResponse {
+	return UserResponse{
+		ID:             u.ID,
+		TgId:           u.TgId,
+		FirstName:      u.FirstName,
+		LastName:       u.LastName,
+		Username:       u.Username,
+		UserNameLocale: u.UserNameLocale,
+		PhotoURL:       u.PhotoURL,
+		IsBanned:       u.IsBanned,
+	}
+}
+
+func (u User) Roles() []string {
+	if u.IsAdmin {
+		return []string{RoleUser, RoleAdmin}
+	}
+	return []string{RoleUser}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы событий жизненного цикла пользователя для вебхуков.
+const (
+	EventUserRegistered = "user.registered"
+	EventUserUpdated    = "user.updated"
+	EventUserBanned     = "user.banned"
+	EventUserDeleted    = "user.deleted"
+	EventSessionRevoked = "session.revoked"
+)
+
+// EventTypes - все типы, на которые можно подписать вебхук.
+var EventTypes = []string{EventUserRegistered, EventUserUpdated, EventUserBanned, EventUserDeleted, EventSessionRevoked}
+
+// ProfileEventTypes - события, в данных которых есть профиль. При удалении
+// аккаунта от их данных остается только sub, замененный псевдонимом.
+var ProfileEventTypes = []string{EventUserRegistered, EventUserUpdated, EventUserBanned}
+
+// OutboxEvent - событие transactional outbox. Пишется в той же транзакции, что
+// и изменение, которое оно описывает. Subject - sub пользователя, AppID -
+// приложение, в котором произошло событие (0, если таких нет).
+type OutboxEvent struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Subject   string          `json:"-"`
+	AppID     int64           `json:"-"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// UserEvent - данные событий user.*. Для user.deleted заполнен только Sub.
+type UserEvent struct {
+	Sub            string   `json:"sub"`
+	FirstName      string   `json:"first_name,omitempty"`
+	LastName       string   `json:"last_name,omitempty"`
+	Username       string   `json:"user_name,omitempty"`
+	UserNameLocale string   `json:"user_name_locale,omitempty"`
+	PhotoURL       string   `json:"photo_url,omitempty"`
+	IsBanned       bool     `json:"is_banned"`
+	Roles          []string `json:"roles,omitempty"`
+}
+
+// SessionEvent - данные события session.revoked.
+type SessionEvent struct {
+	Sub       string `json:"sub"`
+	SessionID string `json:"session_id"`
+}
+
+// Webhook - подписка приложения на события.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	AppID     int64     `json:"app_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookRequest - тело POST /v1/webhooks.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// Статусы доставки вебхука. Dead - попытки исчерпаны, доставка ждет ручного повтора.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Delivery - доставка одного события в один вебхук.
+type Delivery struct {
+	ID             int64     `json:"id"`
+	WebhookID      int64     `json:"webhook_id"`
+	EventID        int64     `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	URL            string    `json:"url"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastError      string    `json:"last_error,omitempty"`
+	ResponseStatus int       `json:"response_status,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// Event и Secret заполняются только для отправки.
+	Event  OutboxEvent `json:"-"`
+	Secret string      `json:"-"`
+}
@@ -0,0 +1,154 @@
+// Package api содержит общие для /v1 конверты ответов и разбор ошибок.
+//
+// Успешный ответ: {"data": ...}.
+// Ошибка: {"error": {"code": "...", "message": "...", "request_id": "..."}}.
+package api
+
+import (
+	"auth-service/internal/grpc/middleware"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/storage"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// maxBody ограничивает размер JSON-тела запроса.
+const maxBody = 1 << 20
+
+// Коды ошибок, общие для всех ресурсов /v1.
+const (
+	CodeBadRequest   = "bad_request"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeInternal     = "internal"
+
+	CodeUserNotFound    = "user_not_found"
+	CodeUserBanned      = "user_banned"
+	CodeUserExists      = "user_exists"
+	CodeUnknownService  = "unknown_service"
+	CodeInvalidInitData = "invalid_init_data"
+	CodeInvalidToken    = "invalid_token"
+	CodeSessionNotFound = "session_not_found"
+	CodeHandoffNotFound = "handoff_not_found"
+
+	CodeIdentityNotFound = "identity_not_found"
+	CodeIdentityExists   = "identity_exists"
+	CodeUnknownProvider  = "unknown_provider"
+	CodeInvalidIDToken   = "invalid_id_token"
+
+	CodeInvalidEmail       = "invalid_email"
+	CodeWeakPassword       = "weak_password"
+	CodeEmailNotVerified   = "email_not_verified"
+	CodeAccountLocked      = "account_locked"
+	CodeLastIdentity       = "last_identity"
+	CodeEmailTokenNotFound = "email_token_not_found"
+
+	CodeStepUpRequired = "step_up_required"
+	CodeReauthRequired = "reauth_required"
+	CodeInvalidOTP     = "invalid_otp"
+	CodeTOTPNotFound   = "totp_not_found"
+	CodeTOTPExists     = "totp_exists"
+
+	CodeWebhookNotFound  = "webhook_not_found"
+	CodeDeliveryNotFound = "delivery_not_found"
+)
+
+// Error - ошибка, которую handler уже сопоставил с HTTP-статусом.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NewError(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+type errorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func WriteData(w http.ResponseWriter, status int, data any) {
+	writeJSON(w, status, map[string]any{"data": data})
+}
+
+// WriteError пишет конверт ошибки. Ошибки, не сопоставленные со статусом,
+// логируются и отдаются как 500 без подробностей.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := FromStorage(err)
+	if apiErr == nil {
+		sl.FromContext(r.Context(), slog.Default()).ErrorContext(r.Context(), "request failed", sl.Err(err))
+		apiErr = NewError(http.StatusInternalServerError, CodeInternal, "Внутренняя ошибка")
+	}
+
+	writeJSON(w, apiErr.Status, map[string]any{"error": errorBody{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		RequestID: middleware.RequestID(r.Context()),
+	}})
+}
+
+// FromStorage сопоставляет *Error и общие ошибки хранилища со статусами.
+func FromStorage(err error) *Error {
+	var apiErr *Error
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.Is(err, storage.ErrUserNotFound):
+		return NewError(http.StatusNotFound, CodeUserNotFound, "Пользователь не найден")
+	case errors.Is(err, storage.ErrUserBanned):
+		return NewError(http.StatusForbidden, CodeUserBanned, "Пользователь забанен")
+	case errors.Is(err, storage.ErrUserExist):
+		return NewError(http.StatusConflict, CodeUserExists, "Пользователь уже существует")
+	case errors.Is(err, storage.ErrAppNotFound):
+		return NewError(http.StatusBadRequest, CodeUnknownService, "Неизвестный сервис")
+	case errors.Is(err, storage.ErrSessionNotFound):
+		return NewError(http.StatusNotFound, CodeSessionNotFound, "Сессия не найдена")
+	case errors.Is(err, storage.ErrHandoffNotFound):
+		return NewError(http.StatusNotFound, CodeHandoffNotFound, "Код входа не найден или истек")
+	case errors.Is(err, storage.ErrIdentityNotFound):
+		return NewError(http.StatusNotFound, CodeIdentityNotFound, "Учетная запись не привязана")
+	case errors.Is(err, storage.ErrIdentityExists):
+		return NewError(http.StatusConflict, CodeIdentityExists, "Учетная запись уже привязана")
+	case errors.Is(err, storage.ErrEmailTokenNotFound):
+		return NewError(http.StatusNotFound, CodeEmailTokenNotFound, "Код из письма не найден или истек")
+	case errors.Is(err, storage.ErrTOTPNotFound):
+		return NewError(http.StatusNotFound, CodeTOTPNotFound, "Второй фактор не подключен")
+	case errors.Is(err, storage.ErrTOTPExists):
+		return NewError(http.StatusConflict, CodeTOTPExists, "Второй фактор уже подключен")
+	case errors.Is(err, storage.ErrWebhookNotFound):
+		return NewError(http.StatusNotFound, CodeWebhookNotFound, "Вебхук не найден")
+	case errors.Is(err, storage.ErrDeliveryNotFound):
+		return NewError(http.StatusNotFound, CodeDeliveryNotFound, "Доставка не найдена")
+	default:
+		return nil
+	}
+}
+
+// Decode читает JSON-тело в dst и отклоняет неизвестные поля.
+func Decode(r *http.Request, dst any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return NewError(http.StatusBadRequest, CodeBadRequest, "ошибка десериализации: "+err.Error())
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
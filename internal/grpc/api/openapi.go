@@ -0,0 +1,20 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// openapi.json описывает все маршруты /v1 и обновляется вместе с ними.
+//
+//go:embed openapi.json
+var openAPI []byte
+
+func RegisterDocs(r *mux.Router) {
+	r.HandleFunc("/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPI)
+	}).Methods("GET")
+}
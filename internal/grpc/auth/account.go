@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"auth-service/internal/grpc/api"
+	"net/http"
+)
+
+// Export отдает все данные владельца токена одним JSON-файлом.
+func (s *ServerApi) Export(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+	export, err := s.services.Export(r.Context(), token)
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	name := "sso-export-" + export.ExportedAt.Format("20060102T150405Z") + ".json"
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	api.WriteData(w, http.StatusOK, export)
+}
+
+// DeleteMe планирует удаление аккаунта владельца токена. Аккаунт стирается
+// не сразу, а после grace-периода, дата удаления возвращается в ответе.
+func (s *ServerApi) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+	erasure, err := s.services.RequestDeletion(r.Context(), token)
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	api.WriteData(w, http.StatusAccepted, erasure)
+}
+
+// CancelDeletion отменяет запланированное удаление аккаунта.
+func (s *ServerApi) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+	if err := s.services.CancelDeletion(r.Context(), token); err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type callerKey struct{}
+
+// registerAdmin добавляет /v1/admin/*. Эти маршруты принимают только сервисные
+// токены client_credentials с нужным scope - токен пользователя-админа не подходит.
+func (s *ServerApi) registerAdmin(r *mux.Router) {
+	admin := r.PathPrefix("/v1/admin").Subrouter()
+	admin.Handle("/users/{sub}", s.requireScope(models.ScopeUsersRead, s.AdminUser)).Methods("GET")
+	admin.Handle("/users/{sub}/ban", s.requireScope(models.ScopeUsersWrite, s.AdminBan(true))).Methods("POST")
+	admin.Handle("/users/{sub}/unban", s.requireScope(models.ScopeUsersWrite, s.AdminBan(false))).Methods("POST")
+	admin.Handle("/users/{sub}/sessions", s.requireScope(models.ScopeUsersRead, s.AdminSessions)).Methods("GET")
+	admin.Handle("/users/{sub}/sessions/{id}", s.requireScope(models.ScopeUsersWrite, s.AdminRevokeSession)).Methods("DELETE")
+	admin.Handle("/audit", s.requireScope(models.ScopeAuditRead, s.AuditEvents)).Methods("GET")
+	admin.Handle("/audit/export", s.requireScope(models.ScopeAuditRead, s.AuditExport)).Methods("GET")
+}
+
+// requireScope пропускает запрос только с сервисным токеном, у которого есть scope,
+// и кладет вызывающее приложение в контекст.
+func (s *ServerApi) requireScope(scope string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := cutBearer(r.Header.Get("Authorization"))
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			api.WriteError(w, r, api.NewError(http.StatusUnauthorized, api.CodeUnauthorized, "Нужен заголовок Authorization: Bearer"))
+			return
+		}
+		caller, err := s.services.Caller(r.Context(), token, scope)
+		if err != nil {
+			api.WriteError(w, r, fromService(err))
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, caller)))
+	})
+}
+
+func callerFrom(ctx context.Context) models.Caller {
+	caller, _ := ctx.Value(callerKey{}).(models.Caller)
+	return caller
+}
+
+func (s *ServerApi) AdminUser(w http.ResponseWriter, r *http.Request) {
+	info, err := s.services.UserInfo(r.Context(), mux.Vars(r)["sub"])
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, info)
+}
+
+func (s *ServerApi) AdminBan(banned bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub := mux.Vars(r)["sub"]
+		if err := s.services.SetBanned(r.Context(), callerFrom(r.Context()), sub, banned); err != nil {
+			api.WriteError(w, r, fromService(err))
+			return
+		}
+		info, err := s.services.UserInfo(r.Context(), sub)
+		if err != nil {
+			api.WriteError(w, r, fromService(err))
+			return
+		}
+		api.WriteData(w, http.StatusOK, info)
+	}
+}
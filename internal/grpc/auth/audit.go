@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"auth-service/internal/services/auth"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// defaultAuditPage - размер страницы /v1/admin/audit без параметра limit.
+const defaultAuditPage = 100
+
+var auditCSVHeader = []string{"id", "created_at", "type", "outcome", "reason", "actor", "subject", "app_id", "ip"}
+
+// AuditEvents отдает страницу журнала аудита. Следующая страница запрашивается
+// с after равным id последнего события.
+func (s *ServerApi) AuditEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := auditFilter(r.URL.Query())
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditPage
+	}
+	events, err := s.services.AuditEvents(r.Context(), filter)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, events)
+}
+
+// AuditExport выгружает все события под фильтром файлом CSV или JSON
+// (format=csv|json), limit не учитывается. Журнал читается страницами,
+// чтобы не держать его в памяти.
+func (s *ServerApi) AuditExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := auditFilter(query)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "format должен быть csv или json"))
+		return
+	}
+	filter.Limit = auth.MaxAuditPage
+
+	// Первая страница читается до заголовков, чтобы ошибка базы ушла обычным ответом.
+	events, err := s.services.AuditEvents(r.Context(), filter)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+
+	name := "audit-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+
+	var (
+		write  func(models.AuditEvent) error
+		finish func()
+	)
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		cw.Write(auditCSVHeader)
+		write = func(e models.AuditEvent) error { return cw.Write(auditCSVRow(e)) }
+		finish = cw.Flush
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		w.Write([]byte("["))
+		first := true
+		write = func(e models.AuditEvent) error {
+			if !first {
+				w.Write([]byte(","))
+			}
+			first = false
+			return enc.Encode(e)
+		}
+		finish = func() { w.Write([]byte("]\n")) }
+	}
+
+	for len(events) > 0 {
+		for _, e := range events {
+			if err := write(e); err != nil {
+				return
+			}
+		}
+		filter.AfterID = events[len(events)-1].ID
+		if events, err = s.services.AuditEvents(r.Context(), filter); err != nil {
+			// Заголовки уже отправлены: обрываем соединение, чтобы неполный файл
+			// не выглядел как полная выгрузка.
+			panic(http.ErrAbortHandler)
+		}
+	}
+	finish()
+}
+
+func auditCSVRow(e models.AuditEvent) []string {
+	return []string{
+		strconv.FormatInt(e.ID, 10),
+		e.CreatedAt.UTC().Format(time.RFC3339),
+		e.Type,
+		e.Outcome,
+		e.Reason,
+		e.Actor,
+		e.Subject,
+		strconv.FormatInt(e.AppID, 10),
+		e.IP,
+	}
+}
+
+// auditFilter разбирает параметры user, app, type, since, until (RFC 3339), after и limit.
+func auditFilter(query url.Values) (models.AuditFilter, error) {
+	filter := models.AuditFilter{User: query.Get("user"), Type: query.Get("type")}
+
+	ints := []struct {
+		name string
+		dst  *int64
+	}{{"app", &filter.AppID}, {"after", &filter.AfterID}}
+	for _, p := range ints {
+		if v := query.Get(p.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return models.AuditFilter{}, api.NewError(http.StatusBadRequest, api.CodeBadRequest, p.name+" должен быть неотрицательным числом")
+			}
+			*p.dst = n
+		}
+	}
+	times := []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}}
+	for _, p := range times {
+		if v := query.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return models.AuditFilter{}, api.NewError(http.StatusBadRequest, api.CodeBadRequest, p.name+" должен быть в формате RFC 3339")
+			}
+			*p.dst = t
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > auth.MaxAuditPage {
+			return models.AuditFilter{}, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "limit должен быть от 1 до "+strconv.Itoa(auth.MaxAuditPage))
+		}
+		filter.Limit = n
+	}
+	return filter, nil
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"auth-service/internal/services/auth"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultEventPage - размер страницы /v1/events без параметра limit.
+	defaultEventPage = 100
+	// maxEventsWait - наибольшее ожидание long-poll запроса.
+	maxEventsWait = 30 * time.Second
+	// sseHeartbeat - как часто поток SSE без событий шлет комментарий, чтобы
+	// прокси не закрыли соединение по простою.
+	sseHeartbeat = 15 * time.Second
+)
+
+// Events отдает события outbox, видимые вызывающему приложению, по возрастанию id.
+// С Accept: text/event-stream ответ - поток SSE, id каждого события - курсор,
+// который браузер вернет в Last-Event-ID при переподключении. Иначе это long-poll:
+// запрос ждет событий до wait секунд и отдает страницу, следующая - с after
+// равным id последнего события.
+func (s *ServerApi) Events(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	after := query.Get("after")
+	if after == "" {
+		after = r.Header.Get("Last-Event-ID")
+	}
+	var afterID int64
+	if after != "" {
+		n, err := strconv.ParseInt(after, 10, 64)
+		if err != nil || n < 0 {
+			api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "after должен быть неотрицательным числом"))
+			return
+		}
+		afterID = n
+	}
+	limit := defaultEventPage
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > auth.MaxEventPage {
+			api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "limit должен быть от 1 до "+strconv.Itoa(auth.MaxEventPage)))
+			return
+		}
+		limit = n
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamEvents(w, r, afterID, limit)
+		return
+	}
+
+	var wait time.Duration
+	if v := query.Get("wait"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || time.Duration(n)*time.Second > maxEventsWait {
+			api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest,
+				fmt.Sprintf("wait должен быть от 0 до %d секунд", int(maxEventsWait.Seconds()))))
+			return
+		}
+		wait = time.Duration(n) * time.Second
+	}
+	events, err := s.services.Events(r.Context(), callerFrom(r.Context()), afterID, limit, wait)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, events)
+}
+
+// streamEvents держит поток SSE, пока клиент не отключится.
+func (s *ServerApi) streamEvents(w http.ResponseWriter, r *http.Request, afterID int64, limit int) {
+	ctx := r.Context()
+	caller := callerFrom(ctx)
+
+	// Первая выборка до заголовков, чтобы ошибка базы ушла обычным ответом.
+	events, err := s.services.Events(ctx, caller, afterID, limit, 0)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		if len(events) == 0 {
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		for _, e := range events {
+			writeSSE(w, e)
+			afterID = e.ID
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		events, err = s.services.Events(ctx, caller, afterID, limit, sseHeartbeat)
+		if err != nil || ctx.Err() != nil {
+			// Заголовки уже отправлены: клиент переподключится с Last-Event-ID.
+			return
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, e models.OutboxEvent) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
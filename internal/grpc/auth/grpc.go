@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"auth-service/internal/lib/clientinfo"
+	"auth-service/internal/services/auth"
+	"auth-service/internal/storage"
+	ssov1 "auth-service/pkg/api/sso/v1"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrorDomain - домен ErrorInfo в деталях статуса, Reason совпадает с кодом ошибки /v1.
+const ErrorDomain = "sso"
+
+// eventsStreamWait - сколько стрим Events ждет новых событий за одну выборку.
+const eventsStreamWait = 30 * time.Second
+
+// GRPCServer реализует sso.v1.Auth из proto/sso/v1/auth.proto. JSON остается
+// только у HTTP /v1, по gRPC сообщения идут в protobuf.
+type GRPCServer struct {
+	ssov1.UnimplementedAuthServer
+	services auth.Auth
+}
+
+func RegisterGRPC(s *grpc.Server, authService auth.Auth) {
+	ssov1.RegisterAuthServer(s, &GRPCServer{services: authService})
+}
+
+// Login - аналог POST /v1/auth/login.
+func (s *GRPCServer) Login(ctx context.Context, req *ssov1.LoginRequest) (*ssov1.LoginResponse, error) {
+	if req.GetInitData() == "" || req.GetServiceId() == 0 {
+		return nil, withReason(codes.InvalidArgument, api.CodeBadRequest, "initData и serviceId обязательны")
+	}
+	user, token, err := s.services.ValidateUser(clientinfo.WithPlatform(ctx, req.GetPlatform()), req.GetInitData(), req.GetServiceId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &ssov1.LoginResponse{Token: token, User: userToProto(user)}, nil
+}
+
+// Register - аналог POST /v1/auth/register.
+func (s *GRPCServer) Register(ctx context.Context, req *ssov1.RegisterRequest) (*ssov1.RegisterResponse, error) {
+	if req.GetInitData() == "" || req.GetUserNameLocale() == "" || req.GetServiceId() == 0 {
+		return nil, withReason(codes.InvalidArgument, api.CodeBadRequest, "initData, userNameLocale и serviceId обязательны")
+	}
+	token, err := s.services.RegisterUser(clientinfo.WithPlatform(ctx, req.GetPlatform()), req.GetInitData(), req.GetUserNameLocale(), req.GetServiceId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &ssov1.RegisterResponse{Token: token}, nil
+}
+
+// AdminCheck - аналог POST /v1/auth/admin-check.
+func (s *GRPCServer) AdminCheck(ctx context.Context, req *ssov1.AdminCheckRequest) (*ssov1.AdminCheckResponse, error) {
+	if req.GetInitData() == "" {
+		return nil, withReason(codes.InvalidArgument, api.CodeBadRequest, "initData обязателен")
+	}
+	isAdmin, err := s.services.IsAdmin(ctx, req.GetInitData(), req.GetOtp())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &ssov1.AdminCheckResponse{IsAdmin: isAdmin}, nil
+}
+
+// Me - аналог GET /v1/me, токен передается в метаданных authorization: Bearer <token>.
+func (s *GRPCServer) Me(ctx context.Context, _ *ssov1.MeRequest) (*ssov1.MeResponse, error) {
+	token, ok := bearerFromMetadata(ctx)
+	if !ok {
+		return nil, withReason(codes.Unauthenticated, api.CodeUnauthorized, "Нужны метаданные authorization: Bearer")
+	}
+	me, err := s.services.Me(ctx, token)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &ssov1.MeResponse{
+		User:           userToProto(me.User),
+		Roles:          me.Roles,
+		ServiceId:      me.ServiceID,
+		StepUpRequired: me.StepUpRequired,
+	}
+	if me.EraseAt != nil {
+		resp.EraseAt = timestamppb.New(*me.EraseAt)
+	}
+	return resp, nil
+}
+
+// Events - аналог GET /v1/events в режиме SSE: стрим отдает события, видимые
+// приложению сервисного токена, пока клиент его не закроет.
+func (s *GRPCServer) Events(req *ssov1.EventsRequest, stream grpc.ServerStreamingServer[ssov1.Event]) error {
+	ctx := stream.Context()
+	token, ok := bearerFromMetadata(ctx)
+	if !ok {
+		return withReason(codes.Unauthenticated, api.CodeUnauthorized, "Нужны метаданные authorization: Bearer")
+	}
+	caller, err := s.services.Caller(ctx, token, models.ScopeEventsRead)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	after := req.GetAfter()
+	for {
+		events, err := s.services.Events(ctx, caller, after, auth.MaxEventPage, eventsStreamWait)
+		if err != nil {
+			return toStatus(err)
+		}
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		for _, e := range events {
+			if err := stream.Send(&ssov1.Event{
+				Id:        e.ID,
+				Type:      e.Type,
+				Data:      e.Data,
+				CreatedAt: timestamppb.New(e.CreatedAt),
+			}); err != nil {
+				return err
+			}
+			after = e.ID
+		}
+	}
+}
+
+func userToProto(u models.UserResponse) *ssov1.User {
+	return &ssov1.User{
+		Id:             u.ID,
+		Tgid:           u.TgId,
+		FirstName:      u.FirstName,
+		LastName:       u.LastName,
+		UserName:       u.Username,
+		UserNameLocale: u.UserNameLocale,
+		PhotoUrl:       u.PhotoURL,
+		IsBanned:       u.IsBanned,
+	}
+}
+
+func bearerFromMetadata(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, v := range md.Get("authorization") {
+		if token, ok := cutBearer(v); ok {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+func cutBearer(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// toStatus сопоставляет ошибки сервиса и хранилища с кодами gRPC. Код ошибки
+// /v1 кладется в ErrorInfo.Reason, чтобы клиент различал, например, плохой
+// токен и плохие initData при одинаковом Unauthenticated.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		return withReason(codes.Unauthenticated, api.CodeInvalidToken, "Токен недействителен")
+	case errors.Is(err, auth.ErrInvalidInitData):
+		return withReason(codes.Unauthenticated, api.CodeInvalidInitData, "initData не прошли проверку")
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return withReason(codes.Unauthenticated, api.CodeUnauthorized, "Неверные учетные данные")
+	case errors.Is(err, auth.ErrStepUpRequired):
+		return withReason(codes.PermissionDenied, api.CodeStepUpRequired, "Требуется код второго фактора")
+	case errors.Is(err, auth.ErrInvalidOTP):
+		return withReason(codes.Unauthenticated, api.CodeInvalidOTP, "Неверный или уже использованный код")
+	case errors.Is(err, auth.ErrAccountLocked):
+		return withReason(codes.ResourceExhausted, api.CodeAccountLocked, "Слишком много неудачных попыток, попробуйте позже")
+	case errors.Is(err, auth.ErrForbidden):
+		return withReason(codes.PermissionDenied, api.CodeForbidden, "Недостаточно прав")
+	case errors.Is(err, auth.ErrInvalidApp), errors.Is(err, storage.ErrAppNotFound):
+		return withReason(codes.InvalidArgument, api.CodeUnknownService, "Неизвестный сервис")
+	case errors.Is(err, storage.ErrUserNotFound):
+		return withReason(codes.NotFound, api.CodeUserNotFound, "Пользователь не найден")
+	case errors.Is(err, storage.ErrUserBanned):
+		return withReason(codes.PermissionDenied, api.CodeUserBanned, "Пользователь забанен")
+	case errors.Is(err, storage.ErrUserExist):
+		return withReason(codes.AlreadyExists, api.CodeUserExists, "Пользователь уже существует")
+	case errors.Is(err, storage.ErrSessionNotFound):
+		return withReason(codes.NotFound, api.CodeSessionNotFound, "Сессия не найдена")
+	case errors.Is(err, storage.ErrWebhookNotFound):
+		return withReason(codes.NotFound, api.CodeWebhookNotFound, "Вебхук не найден")
+	case errors.Is(err, storage.ErrDeliveryNotFound):
+		return withReason(codes.NotFound, api.CodeDeliveryNotFound, "Доставка не найдена")
+	}
+	if st, ok := status.FromError(err); ok {
+		return st.Err()
+	}
+	return withReason(codes.Internal, api.CodeInternal, "internal error")
+}
+
+func withReason(code codes.Code, reason, message string) error {
+	st := status.New(code, message)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
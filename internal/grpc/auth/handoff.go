@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"auth-service/internal/lib/clientinfo"
+	"auth-service/internal/services/auth"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type handoffPending struct {
+	Status string `json:"status"`
+}
+
+// StartHandoff выдает браузеру код входа через мини-приложение. Код (или link)
+// показывается QR-кодом, device_code браузер хранит у себя и опрашивает с ним
+// /v1/auth/handoff/token.
+func (s *ServerApi) StartHandoff(w http.ResponseWriter, r *http.Request) {
+	var req models.HandoffRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.ServiceId == 0 {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "serviceId обязателен"))
+		return
+	}
+
+	start, err := s.services.StartHandoff(r.Context(), req.ServiceId)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusCreated, start)
+}
+
+// HandoffInfo вызывает мини-приложение, открытое по ссылке с startapp=<code>,
+// чтобы показать пользователю приложение и браузер, которые просят вход.
+func (s *ServerApi) HandoffInfo(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeHandoffApprove(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := s.services.HandoffInfo(r.Context(), req.InitData, req.Code)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, info)
+}
+
+// ApproveHandoff вызывает мини-приложение, когда пользователь подтвердил вход.
+// В ответе - те же сведения, что у /v1/auth/handoff/info.
+func (s *ServerApi) ApproveHandoff(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeHandoffApprove(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := s.services.ApproveHandoff(r.Context(), req.InitData, req.Code)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, info)
+}
+
+func decodeHandoffApprove(w http.ResponseWriter, r *http.Request) (models.HandoffApproveRequest, bool) {
+	var req models.HandoffApproveRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return req, false
+	}
+	if req.InitData == "" || req.Code == "" {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "initData и code обязательны"))
+		return req, false
+	}
+	return req, true
+}
+
+// HandoffToken отдает браузеру токен после подтверждения кода. С Accept:
+// text/event-stream ответ - поток SSE, который закрывается событием token или
+// error. Иначе это long-poll: запрос ждет подтверждения до wait секунд и
+// отвечает 202 {"status": "pending"}, если его еще нет.
+func (s *ServerApi) HandoffToken(w http.ResponseWriter, r *http.Request) {
+	var req models.HandoffTokenRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.DeviceCode == "" {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "device_code обязателен"))
+		return
+	}
+	r = r.WithContext(clientinfo.WithPlatform(r.Context(), req.Platform))
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamHandoff(w, r, req.DeviceCode)
+		return
+	}
+
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || time.Duration(n)*time.Second > maxEventsWait {
+			api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest,
+				fmt.Sprintf("wait должен быть от 0 до %d секунд", int(maxEventsWait.Seconds()))))
+			return
+		}
+		wait = time.Duration(n) * time.Second
+	}
+	user, token, err := s.services.PollHandoff(r.Context(), req.DeviceCode, wait)
+	switch {
+	case errors.Is(err, auth.ErrHandoffPending):
+		api.WriteData(w, http.StatusAccepted, handoffPending{Status: "pending"})
+	case err != nil:
+		api.WriteError(w, r, fromService(err))
+	default:
+		api.WriteData(w, http.StatusOK, loginResponse{Token: token, User: user})
+	}
+}
+
+// streamHandoff держит поток SSE до выдачи токена, ошибки или истечения кода.
+func (s *ServerApi) streamHandoff(w http.ResponseWriter, r *http.Request, deviceCode string) {
+	ctx := r.Context()
+
+	// Первый опрос до заголовков, чтобы неизвестный код ушел обычным ответом.
+	user, token, err := s.services.PollHandoff(ctx, deviceCode, 0)
+	if err != nil && !errors.Is(err, auth.ErrHandoffPending) {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		switch {
+		case err == nil:
+			writeHandoffEvent(w, "token", loginResponse{Token: token, User: user})
+			rc.Flush()
+			return
+		case errors.Is(err, auth.ErrHandoffPending):
+			fmt.Fprint(w, ": pending\n\n")
+		default:
+			// Код истек или вход не удался: браузеру нужен новый код, переподключаться незачем.
+			apiErr := api.FromStorage(fromService(err))
+			if apiErr == nil {
+				apiErr = api.NewError(http.StatusInternalServerError, api.CodeInternal, "Внутренняя ошибка")
+			}
+			writeHandoffEvent(w, "error", map[string]string{"code": apiErr.Code, "message": apiErr.Message})
+			rc.Flush()
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		// Выпущенный токен пишется и после отключения: код уже погашен, и это
+		// единственная попытка его отдать.
+		user, token, err = s.services.PollHandoff(ctx, deviceCode, sseHeartbeat)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func writeHandoffEvent(w http.ResponseWriter, event string, body any) {
+	data, _ := json.Marshal(body)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
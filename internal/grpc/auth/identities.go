@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"auth-service/internal/lib/clientinfo"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Identities - учетные записи, привязанные к аккаунту владельца токена.
+func (s *ServerApi) Identities(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+	identities, err := s.services.Identities(r.Context(), token)
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	api.WriteData(w, http.StatusOK, identities)
+}
+
+// LinkIdentity привязывает к аккаунту учетную запись из ID-токена провайдера
+// или, для provider email, адрес с паролем.
+func (s *ServerApi) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+	var req models.IdentityLinkRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	var (
+		identity models.Identity
+		err      error
+	)
+	switch {
+	case req.Provider == models.ProviderEmail:
+		if req.Email == "" || req.Password == "" {
+			api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "email и password обязательны"))
+			return
+		}
+		identity, err = s.services.LinkEmail(r.Context(), token, req.Email, req.Password)
+	case req.Provider == "" || req.IDToken == "":
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "provider и id_token обязательны"))
+		return
+	default:
+		identity, err = s.services.LinkIdentity(r.Context(), token, req.Provider, req.IDToken)
+	}
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	api.WriteData(w, http.StatusCreated, identity)
+}
+
+func (s *ServerApi) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+	if err := s.services.UnlinkIdentity(r.Context(), token, mux.Vars(r)["provider"]); err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoginIdentity обменивает ID-токен привязанной учетной записи на токен приложения.
+func (s *ServerApi) LoginIdentity(w http.ResponseWriter, r *http.Request) {
+	var req models.IdentityLoginRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.Provider == "" || req.IDToken == "" || req.ServiceId == 0 {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "provider, id_token и serviceId обязательны"))
+		return
+	}
+
+	ctx := clientinfo.WithPlatform(r.Context(), req.Platform)
+	user, token, err := s.services.LoginWithIdentity(ctx, req.Provider, req.IDToken, req.ServiceId)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, loginResponse{Token: token, User: user})
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/services/auth"
+	"auth-service/internal/storage"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// tokenResponse и oauthError - форматы RFC 6749, а не конверт /v1: их ждут
+// стандартные OAuth2-клиенты (например, golang.org/x/oauth2/clientcredentials).
+type tokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	Scope           string `json:"scope,omitempty"`
+}
+
+const (
+	grantClientCredentials = "client_credentials"
+	grantTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	tokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	tokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// Token - POST /v1/oauth/token: client_credentials и обмен токенов. Приложение
+// передает client_id и client_secret через HTTP Basic или в теле формы.
+func (s *ServerApi) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "ошибка разбора формы")
+		return
+	}
+
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case grantClientCredentials:
+		s.clientCredentials(w, r)
+	case grantTokenExchange:
+		s.tokenExchange(w, r)
+	case "":
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "grant_type обязателен")
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", grantType)
+	}
+}
+
+// clientAuth достает client_id и client_secret из HTTP Basic или из формы.
+func clientAuth(r *http.Request) (id int64, secret string, basic bool, ok bool) {
+	clientID, secret, basic := r.BasicAuth()
+	if !basic {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	id, err := strconv.ParseInt(clientID, 10, 64)
+	if err != nil || secret == "" {
+		return 0, "", basic, false
+	}
+	return id, secret, basic, true
+}
+
+func (s *ServerApi) clientCredentials(w http.ResponseWriter, r *http.Request) {
+	id, secret, basic, ok := clientAuth(r)
+	if !ok {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "client_id и client_secret обязательны")
+		return
+	}
+
+	tok, err := s.services.ClientCredentials(r.Context(), id, secret, strings.Fields(r.PostForm.Get("scope")))
+	if err != nil {
+		writeGrantError(w, r, err, basic)
+		return
+	}
+
+	writeOAuth(w, http.StatusOK, tokenResponse{
+		AccessToken: tok.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(tok.ExpiresIn.Seconds()),
+		Scope:       strings.Join(tok.Scopes, " "),
+	})
+}
+
+// tokenExchange - RFC 8693: приложение меняет токен своего пользователя на токен
+// для приложения audience, если это разрешает политика доверия.
+func (s *ServerApi) tokenExchange(w http.ResponseWriter, r *http.Request) {
+	id, secret, basic, ok := clientAuth(r)
+	if !ok {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "client_id и client_secret обязательны")
+		return
+	}
+
+	form := r.PostForm
+	subjectToken := form.Get("subject_token")
+	if subjectToken == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "subject_token обязателен")
+		return
+	}
+	if t := form.Get("subject_token_type"); t != tokenTypeAccessToken && t != tokenTypeJWT {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "неподдерживаемый subject_token_type")
+		return
+	}
+	if t := form.Get("requested_token_type"); t != "" && t != tokenTypeAccessToken {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "неподдерживаемый requested_token_type")
+		return
+	}
+	audience, err := strconv.ParseInt(form.Get("audience"), 10, 64)
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "audience - id приложения")
+		return
+	}
+
+	tok, err := s.services.ExchangeToken(r.Context(), id, secret, subjectToken, audience, strings.Fields(form.Get("scope")))
+	if err != nil {
+		writeGrantError(w, r, err, basic)
+		return
+	}
+
+	writeOAuth(w, http.StatusOK, tokenResponse{
+		AccessToken:     tok.AccessToken,
+		IssuedTokenType: tokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(tok.ExpiresIn.Seconds()),
+		Scope:           strings.Join(tok.Scopes, " "),
+	})
+}
+
+// writeGrantError сопоставляет ошибки сервиса с кодами ошибок RFC 6749 и RFC 8693.
+func writeGrantError(w http.ResponseWriter, r *http.Request, err error, basic bool) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidClient):
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="sso"`)
+		}
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "")
+	case errors.Is(err, auth.ErrInvalidScope):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "")
+	case errors.Is(err, auth.ErrInvalidTarget):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_target", "")
+	case errors.Is(err, auth.ErrInvalidGrant), errors.Is(err, storage.ErrUserNotFound), errors.Is(err, storage.ErrUserBanned):
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "")
+	default:
+		sl.FromContext(r.Context(), slog.Default()).ErrorContext(r.Context(), "token request failed", sl.Err(err))
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+	}
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	writeOAuth(w, status, oauthError{Error: code, Description: description})
+}
+
+func writeOAuth(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// JWKS - GET /.well-known/jwks.json: открытые ключи, которыми сервисы
+// проверяют ES256-токены без секрета приложения (RFC 7517, без конверта /v1).
+// Набор меняется только при ротации ключей, поэтому его можно кэшировать.
+func (s *ServerApi) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(s.services.JWKS())
+}
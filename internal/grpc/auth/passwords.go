@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"auth-service/internal/lib/clientinfo"
+	"net/http"
+)
+
+// pendingResponse - ответ на запросы, результат которых придет письмом.
+type pendingResponse struct {
+	Status string `json:"status"`
+}
+
+// RegisterEmail создает аккаунт по email и паролю и отправляет письмо для
+// подтверждения адреса. Ответ одинаков для новых и уже занятых адресов.
+func (s *ServerApi) RegisterEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.EmailRegisterRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.Email == "" || req.Password == "" || req.ServiceId == 0 {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "email, password и serviceId обязательны"))
+		return
+	}
+
+	if err := s.services.RegisterEmail(r.Context(), req.Email, req.Password, req.FirstName, req.ServiceId); err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusAccepted, pendingResponse{Status: "pending_verification"})
+}
+
+func (s *ServerApi) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.EmailTokenRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.Token == "" {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "token обязателен"))
+		return
+	}
+
+	if err := s.services.VerifyEmail(r.Context(), req.Token); err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoginEmail обменивает email и пароль на токен приложения.
+func (s *ServerApi) LoginEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.EmailLoginRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.Email == "" || req.Password == "" || req.ServiceId == 0 {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "email, password и serviceId обязательны"))
+		return
+	}
+
+	ctx := clientinfo.WithPlatform(r.Context(), req.Platform)
+	user, token, err := s.services.LoginWithPassword(ctx, req.Email, req.Password, req.ServiceId)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, loginResponse{Token: token, User: user})
+}
+
+// RequestPasswordReset отправляет письмо для сброса пароля. Ответ не зависит от
+// того, зарегистрирован ли адрес.
+func (s *ServerApi) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordResetRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.Email == "" {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "email обязателен"))
+		return
+	}
+
+	if err := s.services.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusAccepted, pendingResponse{Status: "sent"})
+}
+
+// ConfirmPasswordReset задает новый пароль по токену из письма. Все сессии
+// аккаунта завершаются.
+func (s *ServerApi) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordResetConfirmRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "token и password обязательны"))
+		return
+	}
+
+	if err := s.services.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -22,25 +22,18 @@ type Auth interface {
 
 type ServerApi struct {
 	services auth.Auth
-	port     string
 }
 
-func Register(authService auth.Auth, port string) *http.Server {
+func Register(r *mux.Router, authService auth.Auth) {
 	api := ServerApi{
 		services: authService,
-		port:     port,
 	}
-	router := api.configureRouting()
-	return &http.Server{Addr: api.port, Handler: &router}
+	api.configureRouting(r)
 }
-func (s *ServerApi) configureRouting() mux.Router {
-	r := *mux.NewRouter()
-
+func (s *ServerApi) configureRouting(r *mux.Router) {
 	r.HandleFunc("/register", s.RegisterUser).Methods("POST")
 	r.HandleFunc("/validate", s.ValidateUser).Methods("GET")
 	r.HandleFunc("/isAdmin", s.IsAdmin).Methods("GET")
-
-	return r
 }
 func (s *ServerApi) ValidateUser(w http.ResponseWriter, r *http.Request) {
 	var req models.InitDataRequest
@@ -113,7 +106,7 @@ func (s *ServerApi) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	token, err := s.services.RegisterUser(ctx, req.UserHash, req.UserNameLocale, req.ServiceID)
 	if err != nil {
-		if errors.As(err, &storage.ErrUserExist) {
+		if errors.Is(err, storage.ErrUserExist) {
 			http.Error(w, "Ошибка", http.StatusConflict)
 			return
 		}
@@ -142,10 +135,19 @@ func (s *ServerApi) IsAdmin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	isAdmin, err := s.services.IsAdmin(ctx, req.InitData)
+	isAdmin, err := s.services.IsAdmin(ctx, req.InitData, req.OTP)
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidApp) {
 			http.Error(w, "Неизвестный сервис", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, auth.ErrStepUpRequired) || errors.Is(err, auth.ErrInvalidOTP) {
+			http.Error(w, "Требуется код второго фактора", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, auth.ErrAccountLocked) {
+			http.Error(w, "Слишком много неверных кодов, попробуйте позже", http.StatusTooManyRequests)
+			return
 		}
 		http.Error(w, "Ошибка", http.StatusInternalServerError)
 		return
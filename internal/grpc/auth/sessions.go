@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"auth-service/internal/grpc/api"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Sessions - активные сессии владельца токена.
+func (s *ServerApi) Sessions(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := s.services.Sessions(r.Context(), token)
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	api.WriteData(w, http.StatusOK, sessions)
+}
+
+// RevokeSession завершает одну из сессий владельца токена, в том числе текущую.
+func (s *ServerApi) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+	if err := s.services.RevokeSession(r.Context(), token, mux.Vars(r)["id"]); err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ServerApi) AdminSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.services.UserSessions(r.Context(), mux.Vars(r)["sub"])
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, sessions)
+}
+
+func (s *ServerApi) AdminRevokeSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := s.services.RevokeUserSession(r.Context(), callerFrom(r.Context()), vars["sub"], vars["id"]); err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
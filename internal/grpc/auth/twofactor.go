@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"net/http"
+)
+
+func (s *ServerApi) TwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+	status, err := s.services.TwoFactorStatus(r.Context(), token)
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	api.WriteData(w, http.StatusOK, status)
+}
+
+// EnrollTOTP выдает секрет и otpauth:// URI для QR-кода. Фактор включится
+// после /v1/me/2fa/totp/confirm.
+func (s *ServerApi) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+	enrollment, err := s.services.EnrollTOTP(r.Context(), token)
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	api.WriteData(w, http.StatusCreated, enrollment)
+}
+
+// ConfirmTOTP включает фактор и один раз отдает коды восстановления.
+func (s *ServerApi) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	token, code, ok := otpRequest(w, r)
+	if !ok {
+		return
+	}
+	codes, err := s.services.ConfirmTOTP(r.Context(), token, code)
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	api.WriteData(w, http.StatusOK, codes)
+}
+
+func (s *ServerApi) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	token, code, ok := otpRequest(w, r)
+	if !ok {
+		return
+	}
+	if err := s.services.DisableTOTP(r.Context(), token, code); err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StepUp подтверждает второй фактор в сессии токена: на two_factor.step_up_ttl
+// /v1/me снова отдает роль admin.
+func (s *ServerApi) StepUp(w http.ResponseWriter, r *http.Request) {
+	token, code, ok := otpRequest(w, r)
+	if !ok {
+		return
+	}
+	status, err := s.services.StepUp(r.Context(), token, code)
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	api.WriteData(w, http.StatusOK, status)
+}
+
+func (s *ServerApi) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	token, code, ok := otpRequest(w, r)
+	if !ok {
+		return
+	}
+	codes, err := s.services.RegenerateRecoveryCodes(r.Context(), token, code)
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	api.WriteData(w, http.StatusOK, codes)
+}
+
+// otpRequest читает токен и тело models.OTPRequest. Если чего-то нет, ответ
+// уже записан.
+func otpRequest(w http.ResponseWriter, r *http.Request) (token, code string, ok bool) {
+	if token, ok = bearerToken(w, r); !ok {
+		return "", "", false
+	}
+	var req models.OTPRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return "", "", false
+	}
+	if req.Code == "" {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "code обязателен"))
+		return "", "", false
+	}
+	return token, req.Code, true
+}
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"auth-service/internal/lib/clientinfo"
+	"auth-service/internal/services/auth"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterV1 добавляет версионированные маршруты /v1/auth/*. Обмен initData на
+// токен идет только через POST, чтобы тело не терялось на прокси.
+func RegisterV1(r *mux.Router, authService auth.Auth) {
+	handlers := ServerApi{
+		services: authService,
+	}
+
+	v1 := r.PathPrefix("/v1/auth").Subrouter()
+	v1.HandleFunc("/login", handlers.LoginV1).Methods("POST")
+	v1.HandleFunc("/register", handlers.RegisterV1).Methods("POST")
+	v1.HandleFunc("/admin-check", handlers.AdminCheckV1).Methods("POST")
+	v1.HandleFunc("/handoff", handlers.StartHandoff).Methods("POST")
+	v1.HandleFunc("/handoff/info", handlers.HandoffInfo).Methods("POST")
+	v1.HandleFunc("/handoff/approve", handlers.ApproveHandoff).Methods("POST")
+	v1.HandleFunc("/handoff/token", handlers.HandoffToken).Methods("POST")
+	v1.HandleFunc("/login/identity", handlers.LoginIdentity).Methods("POST")
+	v1.HandleFunc("/email/register", handlers.RegisterEmail).Methods("POST")
+	v1.HandleFunc("/email/verify", handlers.VerifyEmail).Methods("POST")
+	v1.HandleFunc("/email/login", handlers.LoginEmail).Methods("POST")
+	v1.HandleFunc("/email/reset", handlers.RequestPasswordReset).Methods("POST")
+	v1.HandleFunc("/email/reset/confirm", handlers.ConfirmPasswordReset).Methods("POST")
+
+	r.HandleFunc("/v1/me", handlers.MeV1).Methods("GET")
+	r.HandleFunc("/v1/me", handlers.DeleteMe).Methods("DELETE")
+	r.HandleFunc("/v1/me/export", handlers.Export).Methods("GET")
+	r.HandleFunc("/v1/me/deletion", handlers.CancelDeletion).Methods("DELETE")
+	r.HandleFunc("/v1/me/identities", handlers.Identities).Methods("GET")
+	r.HandleFunc("/v1/me/identities", handlers.LinkIdentity).Methods("POST")
+	r.HandleFunc("/v1/me/identities/{provider}", handlers.UnlinkIdentity).Methods("DELETE")
+	r.HandleFunc("/v1/me/sessions", handlers.Sessions).Methods("GET")
+	r.HandleFunc("/v1/me/sessions/{id}", handlers.RevokeSession).Methods("DELETE")
+	r.HandleFunc("/v1/me/2fa", handlers.TwoFactorStatus).Methods("GET")
+	r.HandleFunc("/v1/me/2fa/totp", handlers.EnrollTOTP).Methods("POST")
+	r.HandleFunc("/v1/me/2fa/totp", handlers.DisableTOTP).Methods("DELETE")
+	r.HandleFunc("/v1/me/2fa/totp/confirm", handlers.ConfirmTOTP).Methods("POST")
+	r.HandleFunc("/v1/me/2fa/step-up", handlers.StepUp).Methods("POST")
+	r.HandleFunc("/v1/me/2fa/recovery-codes", handlers.RegenerateRecoveryCodes).Methods("POST")
+	r.HandleFunc("/v1/oauth/token", handlers.Token).Methods("POST")
+	r.HandleFunc("/.well-known/jwks.json", handlers.JWKS).Methods("GET")
+	r.Handle("/v1/events", handlers.requireScope(models.ScopeEventsRead, handlers.Events)).Methods("GET")
+	handlers.registerAdmin(r)
+	handlers.registerWebhooks(r)
+}
+
+type loginResponse struct {
+	Token string              `json:"token"`
+	User  models.UserResponse `json:"user"`
+}
+
+type registerResponse struct {
+	Token string `json:"token"`
+}
+
+type adminCheckResponse struct {
+	IsAdmin bool `json:"isAdmin"`
+}
+
+func (s *ServerApi) LoginV1(w http.ResponseWriter, r *http.Request) {
+	var req models.InitDataRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.InitData == "" || req.ServiceId == 0 {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "initData и serviceId обязательны"))
+		return
+	}
+
+	ctx := clientinfo.WithPlatform(r.Context(), req.Platform)
+	user, token, err := s.services.ValidateUser(ctx, req.InitData, req.ServiceId)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, loginResponse{Token: token, User: user})
+}
+
+func (s *ServerApi) RegisterV1(w http.ResponseWriter, r *http.Request) {
+	var req models.RegisterRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.UserHash == "" || req.UserNameLocale == "" || req.ServiceID == 0 {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "initData, userNameLocale и serviceId обязательны"))
+		return
+	}
+
+	ctx := clientinfo.WithPlatform(r.Context(), req.Platform)
+	token, err := s.services.RegisterUser(ctx, req.UserHash, req.UserNameLocale, req.ServiceID)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusCreated, registerResponse{Token: token})
+}
+
+func (s *ServerApi) AdminCheckV1(w http.ResponseWriter, r *http.Request) {
+	var req models.IsAdmin
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if req.InitData == "" {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "initData обязателен"))
+		return
+	}
+
+	isAdmin, err := s.services.IsAdmin(r.Context(), req.InitData, req.OTP)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, adminCheckResponse{IsAdmin: isAdmin})
+}
+
+// MeV1 отдает профиль владельца токена из Authorization: Bearer <token>.
+func (s *ServerApi) MeV1(w http.ResponseWriter, r *http.Request) {
+	token, ok := bearerToken(w, r)
+	if !ok {
+		return
+	}
+
+	me, err := s.services.Me(r.Context(), token)
+	if err != nil {
+		writeTokenError(w, r, err)
+		return
+	}
+	api.WriteData(w, http.StatusOK, me)
+}
+
+// bearerToken достает токен из Authorization: Bearer или сам отвечает 401.
+func bearerToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token, ok := cutBearer(r.Header.Get("Authorization"))
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		api.WriteError(w, r, api.NewError(http.StatusUnauthorized, api.CodeUnauthorized, "Нужен заголовок Authorization: Bearer"))
+	}
+	return token, ok
+}
+
+// writeTokenError пишет ошибку запроса с пользовательским токеном, добавляя
+// WWW-Authenticate для недействительного токена.
+func writeTokenError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, auth.ErrInvalidToken) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	api.WriteError(w, r, fromService(err))
+}
+
+// fromService сопоставляет ошибки auth.Auth с ответами /v1.
+func fromService(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidInitData):
+		return api.NewError(http.StatusUnauthorized, api.CodeInvalidInitData, "initData не прошли проверку")
+	case errors.Is(err, auth.ErrInvalidApp):
+		return api.NewError(http.StatusBadRequest, api.CodeUnknownService, "Неизвестный сервис")
+	case errors.Is(err, auth.ErrInvalidToken):
+		return api.NewError(http.StatusUnauthorized, api.CodeInvalidToken, "Токен недействителен")
+	case errors.Is(err, auth.ErrStepUpRequired):
+		return api.NewError(http.StatusForbidden, api.CodeStepUpRequired, "Требуется код второго фактора")
+	case errors.Is(err, auth.ErrReauthRequired):
+		return api.NewError(http.StatusForbidden, api.CodeReauthRequired, "Войдите заново, чтобы продолжить")
+	case errors.Is(err, auth.ErrInvalidOTP):
+		return api.NewError(http.StatusUnauthorized, api.CodeInvalidOTP, "Неверный или уже использованный код")
+	case errors.Is(err, auth.ErrForbidden):
+		return api.NewError(http.StatusForbidden, api.CodeForbidden, "Недостаточно прав")
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return api.NewError(http.StatusUnauthorized, api.CodeUnauthorized, "Неверные учетные данные")
+	case errors.Is(err, auth.ErrUnknownProvider):
+		return api.NewError(http.StatusBadRequest, api.CodeUnknownProvider, "Неизвестный провайдер")
+	case errors.Is(err, auth.ErrInvalidIDToken):
+		return api.NewError(http.StatusUnauthorized, api.CodeInvalidIDToken, "ID-токен не прошел проверку")
+	case errors.Is(err, auth.ErrInvalidEmail):
+		return api.NewError(http.StatusBadRequest, api.CodeInvalidEmail, "Некорректный email")
+	case errors.Is(err, auth.ErrWeakPassword):
+		return api.NewError(http.StatusBadRequest, api.CodeWeakPassword, "Пароль слишком простой: нужно от 8 до 128 символов")
+	case errors.Is(err, auth.ErrEmailNotVerified):
+		return api.NewError(http.StatusForbidden, api.CodeEmailNotVerified, "Email не подтвержден")
+	case errors.Is(err, auth.ErrAccountLocked):
+		return api.NewError(http.StatusTooManyRequests, api.CodeAccountLocked, "Слишком много неудачных попыток входа, попробуйте позже")
+	case errors.Is(err, auth.ErrLastIdentity):
+		return api.NewError(http.StatusConflict, api.CodeLastIdentity, "Нельзя отвязать единственный способ входа")
+	case errors.Is(err, auth.ErrInvalidWebhook):
+		_, detail, _ := strings.Cut(err.Error(), auth.ErrInvalidWebhook.Error()+": ")
+		return api.NewError(http.StatusBadRequest, api.CodeBadRequest, "Некорректный вебхук: "+detail)
+	default:
+		return err
+	}
+}
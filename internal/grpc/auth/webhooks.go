@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"auth-service/internal/services/auth"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultDeliveryPage - размер страницы /v1/webhooks/deliveries без параметра limit.
+const defaultDeliveryPage = 100
+
+// registerWebhooks добавляет /v1/webhooks/*. Приложение управляет только своими
+// подписками и доставками: оно определяется по сервисному токену.
+func (s *ServerApi) registerWebhooks(r *mux.Router) {
+	hooks := r.PathPrefix("/v1/webhooks").Subrouter()
+	hooks.Handle("", s.requireScope(models.ScopeWebhooks, s.CreateWebhook)).Methods("POST")
+	hooks.Handle("", s.requireScope(models.ScopeWebhooks, s.Webhooks)).Methods("GET")
+	hooks.Handle("/deliveries", s.requireScope(models.ScopeWebhooks, s.Deliveries)).Methods("GET")
+	hooks.Handle("/deliveries/{id:[0-9]+}/redeliver", s.requireScope(models.ScopeWebhooks, s.Redeliver)).Methods("POST")
+	hooks.Handle("/{id:[0-9]+}", s.requireScope(models.ScopeWebhooks, s.DeleteWebhook)).Methods("DELETE")
+}
+
+func (s *ServerApi) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.WebhookRequest
+	if err := api.Decode(r, &req); err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	webhook, err := s.services.CreateWebhook(r.Context(), callerFrom(r.Context()), req.URL, req.Events)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusCreated, webhook)
+}
+
+func (s *ServerApi) Webhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := s.services.Webhooks(r.Context(), callerFrom(r.Context()))
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, webhooks)
+}
+
+func (s *ServerApi) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if err := s.services.DeleteWebhook(r.Context(), callerFrom(r.Context()), id); err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries отдает доставки со статусом status (по умолчанию dead - очередь
+// на ручной повтор). Следующая страница запрашивается с after равным id последней доставки.
+func (s *ServerApi) Deliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	switch status {
+	case "":
+		status = models.DeliveryDead
+	case models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "status должен быть pending, delivered или dead"))
+		return
+	}
+
+	var afterID int64
+	if v := query.Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "after должен быть неотрицательным числом"))
+			return
+		}
+		afterID = n
+	}
+	limit := defaultDeliveryPage
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > auth.MaxDeliveryPage {
+			api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "limit должен быть от 1 до "+strconv.Itoa(auth.MaxDeliveryPage)))
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := s.services.Deliveries(r.Context(), callerFrom(r.Context()), status, afterID, limit)
+	if err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	api.WriteData(w, http.StatusOK, deliveries)
+}
+
+// Redeliver ставит доставку в очередь заново. Ее получатель должен быть готов
+// к повтору: X-SSO-Delivery и id события не меняются.
+func (s *ServerApi) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		api.WriteError(w, r, err)
+		return
+	}
+	if err := s.services.RedeliverDelivery(r.Context(), callerFrom(r.Context()), id); err != nil {
+		api.WriteError(w, r, fromService(err))
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func pathID(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		return 0, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "id должен быть числом")
+	}
+	return id, nil
+}
@@ -0,0 +1,170 @@
+// Package bot принимает апдейты Telegram-бота на вебхуке и выполняет команды
+// модераторов: /ban, /unban, /promote, /demote и /whois. Пользователя можно
+// указать аргументом (@username, Telegram ID или sub) или ответить командой
+// на его сообщение.
+package bot
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/telegram"
+	"auth-service/internal/services/auth"
+	"auth-service/internal/storage"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxUpdateSize ограничивает тело апдейта: команды бота короткие.
+const maxUpdateSize = 1 << 20
+
+type Handler struct {
+	log      *slog.Logger
+	services auth.Auth
+	client   *telegram.Client
+	secret   string
+}
+
+// Register добавляет POST /v1/telegram/webhook. secret должен совпадать с
+// secret_token, переданным в setWebhook.
+func Register(r *mux.Router, log *slog.Logger, authService auth.Auth, client *telegram.Client, secret string) {
+	h := &Handler{
+		log:      log.With(slog.String("op", "bot.Webhook")),
+		services: authService,
+		client:   client,
+		secret:   secret,
+	}
+	r.HandleFunc("/v1/telegram/webhook", h.Webhook).Methods("POST")
+}
+
+// Webhook обрабатывает апдейт и отвечает 200 даже на неудачную команду:
+// иначе Telegram будет повторять апдейт и выполнит команду еще раз.
+func (h *Handler) Webhook(w http.ResponseWriter, r *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(telegram.HeaderSecretToken)), []byte(h.secret)) != 1 {
+		api.WriteError(w, r, api.NewError(http.StatusUnauthorized, api.CodeUnauthorized, "Неверный секрет вебхука"))
+		return
+	}
+	var update telegram.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpdateSize)).Decode(&update); err != nil {
+		api.WriteError(w, r, api.NewError(http.StatusBadRequest, api.CodeBadRequest, "Некорректный апдейт"))
+		return
+	}
+
+	if msg := update.Message; msg != nil && msg.From != nil && !msg.From.IsBot {
+		if reply, ok := h.handle(r.Context(), msg); ok {
+			if err := h.client.SendMessage(r.Context(), msg.Chat.ID, msg.MessageID, reply); err != nil {
+				sl.FromContext(r.Context(), h.log).ErrorContext(r.Context(), "failed to send bot reply", sl.Err(err))
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handle выполняет команду из сообщения и возвращает текст ответа. ok = false
+// для сообщений, которые не являются командами бота.
+func (h *Handler) handle(ctx context.Context, msg *telegram.Message) (reply string, ok bool) {
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", false
+	}
+	// В группах команда приходит как /ban@bot_name.
+	command, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "/"), "@")
+
+	var target string
+	switch {
+	case len(fields) > 1:
+		target = fields[1]
+	case msg.ReplyToMessage != nil && msg.ReplyToMessage.From != nil:
+		target = strconv.FormatInt(msg.ReplyToMessage.From.ID, 10)
+	}
+
+	switch command {
+	case "ban", "unban", "promote", "demote", "whois":
+	default:
+		return "", false
+	}
+	if target == "" {
+		return fmt.Sprintf("Укажите пользователя: /%s @username, /%s <Telegram ID> или ответьте командой на его сообщение.", command, command), true
+	}
+
+	var (
+		user models.User
+		err  error
+		done string
+	)
+	moderator := msg.From.ID
+	switch command {
+	case "ban":
+		user, err = h.services.BotSetBanned(ctx, moderator, target, true)
+		done = "Пользователь забанен."
+	case "unban":
+		user, err = h.services.BotSetBanned(ctx, moderator, target, false)
+		done = "Пользователь разбанен."
+	case "promote":
+		user, err = h.services.BotSetAdmin(ctx, moderator, target, true)
+		done = "Пользователь назначен администратором."
+	case "demote":
+		user, err = h.services.BotSetAdmin(ctx, moderator, target, false)
+		done = "Пользователь больше не администратор."
+	case "whois":
+		user, err = h.services.BotWhois(ctx, moderator, target)
+	}
+	if err != nil {
+		return h.errorReply(ctx, err), true
+	}
+	if done != "" {
+		return done + "\n" + whois(user), true
+	}
+	return whois(user), true
+}
+
+func (h *Handler) errorReply(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(err, auth.ErrSelfModeration):
+		return "Эту команду нельзя применить к себе."
+	case errors.Is(err, auth.ErrStepUpRequired):
+		return "Подтвердите вход кодом из аутентификатора в приложении и повторите команду."
+	case errors.Is(err, auth.ErrForbidden):
+		return "Команда доступна только администраторам."
+	case errors.Is(err, storage.ErrUserNotFound):
+		return "Пользователь не найден."
+	default:
+		sl.FromContext(ctx, h.log).ErrorContext(ctx, "bot command failed", sl.Err(err))
+		return "Не удалось выполнить команду, попробуйте позже."
+	}
+}
+
+// whois описывает пользователя для ответа бота.
+func whois(user models.User) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "sub: %s\n", user.TgId)
+	if user.Username != "" {
+		fmt.Fprintf(&b, "username: @%s\n", user.Username)
+	}
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		fmt.Fprintf(&b, "имя: %s\n", name)
+	}
+	fmt.Fprintf(&b, "роли: %s\n", strings.Join(user.Roles(), ", "))
+	if user.IsBanned {
+		b.WriteString("забанен: да\n")
+	} else {
+		b.WriteString("забанен: нет\n")
+	}
+	if !user.LastLogin.IsZero() {
+		fmt.Fprintf(&b, "последний вход: %s\n", user.LastLogin.UTC().Format(time.DateTime))
+	}
+	if user.EraseAt != nil {
+		fmt.Fprintf(&b, "удаление аккаунта: %s\n", user.EraseAt.UTC().Format(time.DateTime))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
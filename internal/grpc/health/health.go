@@ -0,0 +1,123 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const checkTimeout = 2 * time.Second
+
+// Check проверяет одну зависимость сервиса и возвращает ошибку, если она не готова.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Health отвечает на пробы оркестратора по HTTP и через grpc.health.v1.
+// После SetDraining сервис продолжает жить, но перестает быть готовым,
+// чтобы балансировщик успел убрать его до остановки.
+type Health struct {
+	mu       sync.RWMutex
+	checks   []namedCheck
+	draining atomic.Bool
+	grpc     *grpchealth.Server
+}
+
+func New() *Health {
+	return &Health{grpc: grpchealth.NewServer()}
+}
+
+// GRPC возвращает сервер grpc.health.v1 для регистрации в grpc.Server.
+func (h *Health) GRPC() *grpchealth.Server {
+	return h.grpc
+}
+
+func (h *Health) AddCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+func (h *Health) Register(r *mux.Router) {
+	r.HandleFunc("/healthz", h.Liveness).Methods("GET")
+	r.HandleFunc("/readyz", h.Readiness).Methods("GET")
+}
+
+// SetServing переводит gRPC health в SERVING, вызывается после старта серверов.
+func (h *Health) SetServing() {
+	h.grpc.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+}
+
+// SetDraining помечает сервис как останавливающийся.
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+	h.grpc.Shutdown()
+}
+
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+func (h *Health) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, map[string]interface{}{
+		"status": "ok",
+	})
+}
+
+func (h *Health) Readiness(w http.ResponseWriter, r *http.Request) {
+	if h.Draining() {
+		writeStatus(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status": "draining",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	results, ok := h.run(ctx)
+
+	status, code := "ok", http.StatusOK
+	if !ok {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	writeStatus(w, code, map[string]interface{}{
+		"status": status,
+		"checks": results,
+	})
+}
+
+func (h *Health) run(ctx context.Context) (map[string]string, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	results := make(map[string]string, len(h.checks))
+	ok := true
+	for _, c := range h.checks {
+		if err := c.check(ctx); err != nil {
+			results[c.name] = err.Error()
+			ok = false
+			continue
+		}
+		results[c.name] = "ok"
+	}
+	return results, ok
+}
+
+func writeStatus(w http.ResponseWriter, code int, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
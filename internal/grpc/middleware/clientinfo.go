@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"auth-service/internal/lib/clientinfo"
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ClientInfo кладет в контекст IP и User-Agent клиента для записи в сессию.
+// IP определяется так же, как для лимитов.
+func ClientInfo(proxyHops int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := clientinfo.With(r.Context(), clientinfo.Info{
+				IP:        clientIP(r, proxyHops),
+				UserAgent: r.UserAgent(),
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// UnaryClientInfo - то же для gRPC: адрес пира и метаданные user-agent.
+func UnaryClientInfo() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withGRPCClientInfo(ctx), req)
+	}
+}
+
+// StreamClientInfo - UnaryClientInfo для стримов.
+func StreamClientInfo() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withGRPCClientInfo(ss.Context())})
+	}
+}
+
+func withGRPCClientInfo(ctx context.Context) context.Context {
+	client := clientinfo.Info{IP: peerIP(ctx)}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("user-agent"); len(v) > 0 {
+			client.UserAgent = v[0]
+		}
+	}
+	return clientinfo.With(ctx, client)
+}
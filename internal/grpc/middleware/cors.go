@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/logger/sl"
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	corsAllowMethods  = "GET, POST, DELETE, OPTIONS"
+	corsAllowHeaders  = "Authorization, Content-Type, X-Request-ID"
+	corsExposeHeaders = "X-Request-ID, Retry-After"
+	corsMaxAge        = "600"
+)
+
+// AppLister отдает реестр приложений с их разрешенными origin-ами.
+type AppLister interface {
+	Apps(ctx context.Context) ([]models.App, error)
+}
+
+// Origins кэширует allowed_origins всех приложений, чтобы не ходить в базу на каждый запрос.
+type Origins struct {
+	log   *slog.Logger
+	apps  AppLister
+	ttl   time.Duration
+	mu    sync.Mutex
+	byApp map[int64]map[string]struct{}
+	any   map[string]struct{}
+	until time.Time
+}
+
+func NewOrigins(log *slog.Logger, apps AppLister, ttl time.Duration) *Origins {
+	return &Origins{log: log, apps: apps, ttl: ttl}
+}
+
+// Allowed сообщает, разрешен ли origin для приложения serviceId.
+// При serviceId == 0 достаточно, чтобы origin принадлежал любому приложению.
+func (o *Origins) Allowed(ctx context.Context, origin string, serviceId int64) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if time.Now().After(o.until) {
+		o.reload(ctx)
+	}
+
+	origin = normalizeOrigin(origin)
+	if serviceId == 0 {
+		_, ok := o.any[origin]
+		return ok
+	}
+	_, ok := o.byApp[serviceId][origin]
+	return ok
+}
+
+func (o *Origins) reload(ctx context.Context) {
+	apps, err := o.apps.Apps(ctx)
+	if err != nil {
+		// Оставляем прошлый снимок и пробуем снова на следующем запросе.
+		sl.FromContext(ctx, o.log).WarnContext(ctx, "failed to load app origins", sl.Err(err))
+		return
+	}
+
+	byApp := make(map[int64]map[string]struct{}, len(apps))
+	all := make(map[string]struct{})
+	for _, app := range apps {
+		set := make(map[string]struct{}, len(app.AllowedOrigins))
+		for _, origin := range app.AllowedOrigins {
+			origin = normalizeOrigin(origin)
+			set[origin] = struct{}{}
+			all[origin] = struct{}{}
+		}
+		byApp[int64(app.ID)] = set
+	}
+
+	o.byApp, o.any = byApp, all
+	o.until = time.Now().Add(o.ttl)
+}
+
+func normalizeOrigin(origin string) string {
+	return strings.ToLower(strings.TrimRight(origin, "/"))
+}
+
+// CORS оборачивает весь роутер, чтобы preflight доходил до ответа даже для
+// маршрутов, зарегистрированных только на POST/GET. Preflight не несет ни тела,
+// ни токена, поэтому для него достаточно origin любого приложения. Сам запрос
+// проверяется по приложению из Bearer-токена и serviceId тела: origin должен
+// быть разрешен у каждого из них, иначе 403. Так страница приложения A не
+// прочитает ответы на запросы с токенами приложения B. Запрос без токена и
+// serviceId не несет учетных данных и проверяется как preflight.
+// Запросы без Origin (сервер-сервер) проходят без изменений.
+func CORS(origins *Origins) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				if !origins.Allowed(r.Context(), origin, 0) {
+					http.Error(w, "Origin не разрешен", http.StatusForbidden)
+					return
+				}
+				h := w.Header()
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+				h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+				h.Set("Access-Control-Max-Age", corsMaxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if !allowedForRequest(r, origins, origin) {
+				http.Error(w, "Origin не разрешен для этого сервиса", http.StatusForbidden)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func allowedForRequest(r *http.Request, origins *Origins, origin string) bool {
+	var apps []int64
+	if clientID, _, ok := r.BasicAuth(); ok {
+		// client_secret_basic в /v1/oauth/token: приложение - client_id.
+		serviceId, err := strconv.ParseInt(clientID, 10, 64)
+		if err != nil {
+			return false
+		}
+		apps = append(apps, serviceId)
+	} else if header := r.Header.Get("Authorization"); header != "" {
+		// Подпись здесь не проверяется: подделанный serviceID не пройдет
+		// проверку токена в обработчике, а origin сверяется с тем, что в нем указано.
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return false
+		}
+		serviceId, err := jwt.ServiceID(strings.TrimSpace(token))
+		if err != nil {
+			return false
+		}
+		apps = append(apps, serviceId)
+	}
+	if peek, ok := peekBody(r); ok && peek.ServiceID != 0 {
+		apps = append(apps, peek.ServiceID)
+	}
+
+	if len(apps) == 0 {
+		return origins.Allowed(r.Context(), origin, 0)
+	}
+	for _, serviceId := range apps {
+		if !origins.Allowed(r.Context(), origin, serviceId) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/jwt"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type staticApps []models.App
+
+func (a staticApps) Apps(context.Context) ([]models.App, error) { return a, nil }
+
+func TestCORS(t *testing.T) {
+	apps := staticApps{
+		{ID: 1, Secret: "a-secret", AllowedOrigins: []string{"https://a.example"}},
+		{ID: 2, Secret: "b-secret", AllowedOrigins: []string{"https://b.example"}},
+	}
+	tokenA, _ := jwt.NewToken(nil, "user-1", apps[0], "", time.Hour)
+	tokenB, _ := jwt.NewToken(nil, "user-1", apps[1], "", time.Hour)
+
+	handler := CORS(NewOrigins(slog.New(slog.DiscardHandler), apps, time.Minute))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	tests := []struct {
+		name   string
+		method string
+		origin string
+		auth   string
+		body   string
+		want   int
+	}{
+		{name: "own token", method: "GET", origin: "https://a.example", auth: "Bearer " + tokenA, want: http.StatusOK},
+		{name: "token of another app", method: "GET", origin: "https://a.example", auth: "Bearer " + tokenB, want: http.StatusForbidden},
+		{name: "token of another app on delete", method: "DELETE", origin: "https://a.example", auth: "Bearer " + tokenB, want: http.StatusForbidden},
+		{name: "unparsable token", method: "GET", origin: "https://a.example", auth: "Bearer garbage", want: http.StatusForbidden},
+		{name: "other scheme", method: "GET", origin: "https://a.example", auth: "Token " + tokenA, want: http.StatusForbidden},
+		{name: "own serviceId", method: "POST", origin: "https://b.example", body: `{"serviceId":2}`, want: http.StatusOK},
+		{name: "foreign serviceId", method: "POST", origin: "https://a.example", body: `{"serviceId":2}`, want: http.StatusForbidden},
+		{name: "token and body disagree", method: "POST", origin: "https://a.example", auth: "Bearer " + tokenA, body: `{"serviceId":2}`, want: http.StatusForbidden},
+		{name: "basic client id", method: "POST", origin: "https://b.example", auth: "basic:2", want: http.StatusOK},
+		{name: "foreign basic client id", method: "POST", origin: "https://a.example", auth: "basic:2", want: http.StatusForbidden},
+		{name: "no credentials", method: "POST", origin: "https://b.example", body: `{"code":"x"}`, want: http.StatusOK},
+		{name: "unknown origin", method: "POST", origin: "https://evil.example", want: http.StatusForbidden},
+		{name: "no origin", method: "GET", auth: "Bearer " + tokenB, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/v1/me", strings.NewReader(tt.body))
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if clientID, ok := strings.CutPrefix(tt.auth, "basic:"); ok {
+				r.SetBasicAuth(clientID, "secret")
+			} else if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if allow := w.Header().Get("Access-Control-Allow-Origin"); tt.want == http.StatusOK && allow != tt.origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", allow, tt.origin)
+			}
+		})
+	}
+}
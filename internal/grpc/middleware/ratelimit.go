@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"auth-service/internal/lib/ratelimit"
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UserResolver возвращает псевдоним пользователя, только если подпись initData верна.
+// Иначе любой мог бы исчерпать чужой лимит, подставив чужой telegram id.
+type UserResolver func(initData string) (string, bool)
+
+// exemptRoutes не ограничиваются: пробы оркестратора и сбор метрик.
+var exemptRoutes = map[string]struct{}{
+	"/healthz": {},
+	"/readyz":  {},
+	"/metrics": {},
+}
+
+// RateLimit отклоняет запрос с 429 и Retry-After, если исчерпан лимит по IP,
+// пользователю или приложению из тела запроса. proxyHops - как в clientIP.
+func RateLimit(limiter *ratelimit.Limiter, resolveUser UserResolver, proxyHops int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := exemptRoutes[routeTemplate(r)]; ok || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			keys := ratelimit.Keys{IP: clientIP(r, proxyHops)}
+			if peek, ok := peekBody(r); ok {
+				keys.ServiceID = peek.ServiceID
+				if user, ok := resolveUser(peek.InitData); ok {
+					keys.User = user
+				}
+			}
+
+			if ok, retryAfter := limiter.Allow(r.Context(), keys); !ok {
+				w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+				http.Error(w, "Слишком много запросов", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Геттеры сгенерированных gRPC-сообщений, по которым считаются лимиты на
+// пользователя и приложение.
+type (
+	initDataRequest interface {
+		GetInitData() string
+	}
+	serviceRequest interface {
+		GetServiceId() int64
+	}
+)
+
+// UnaryRateLimit - те же лимиты для gRPC, отказ возвращается как ResourceExhausted
+// с заголовком retry-after в секундах.
+func UnaryRateLimit(limiter *ratelimit.Limiter, resolveUser UserResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if isHealthMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		keys := ratelimit.Keys{IP: peerIP(ctx)}
+		if r, ok := req.(serviceRequest); ok {
+			keys.ServiceID = r.GetServiceId()
+		}
+		if r, ok := req.(initDataRequest); ok {
+			if user, ok := resolveUser(r.GetInitData()); ok {
+				keys.User = user
+			}
+		}
+
+		if err := allowGRPC(ctx, limiter, keys); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamRateLimit ограничивает открытие стримов по IP клиента.
+func StreamRateLimit(limiter *ratelimit.Limiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isHealthMethod(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		if err := allowGRPC(ss.Context(), limiter, ratelimit.Keys{IP: peerIP(ss.Context())}); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func allowGRPC(ctx context.Context, limiter *ratelimit.Limiter, keys ratelimit.Keys) error {
+	ok, retryAfter := limiter.Allow(ctx, keys)
+	if ok {
+		return nil
+	}
+	grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfterSeconds(retryAfter)))
+	return status.Errorf(codes.ResourceExhausted, "Слишком много запросов, повторите через %s", retryAfterSeconds(retryAfter))
+}
+
+func isHealthMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/")
+}
+
+// clientIP возвращает адрес клиента. proxyHops - сколько доверенных прокси
+// стоит перед сервисом, 0 - заголовкам не верить. Каждый прокси дописывает
+// адрес своего собеседника в конец X-Forwarded-For, поэтому клиент - запись
+// proxyHops-я с конца: все, что левее, мог подставить сам клиент.
+func clientIP(r *http.Request, proxyHops int) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if proxyHops <= 0 {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	if len(hops) > 0 {
+		// Цепочка короче ожидаемой - значит, клиент ничего не добавлял и
+		// самая левая запись записана прокси.
+		hop := hops[max(len(hops)-proxyHops, 0)]
+		if net.ParseIP(hop) != nil {
+			return hop
+		}
+		return remote
+	}
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(real) != nil {
+		return real
+	}
+	return remote
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(d.Seconds()))))
+}
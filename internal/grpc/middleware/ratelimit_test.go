@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxyHops int
+		xff       []string
+		realIP    string
+		want      string
+	}{
+		{name: "no proxy ignores headers", xff: []string{"1.1.1.1"}, realIP: "2.2.2.2", want: "10.0.0.9"},
+		{name: "single proxy", proxyHops: 1, xff: []string{"203.0.113.5"}, want: "203.0.113.5"},
+		{name: "spoofed entry before proxy", proxyHops: 1, xff: []string{"1.1.1.1, 203.0.113.5"}, want: "203.0.113.5"},
+		{name: "spoofed header line", proxyHops: 1, xff: []string{"1.1.1.1", "203.0.113.5"}, want: "203.0.113.5"},
+		{name: "two proxies", proxyHops: 2, xff: []string{"1.1.1.1, 203.0.113.5, 10.0.0.2"}, want: "203.0.113.5"},
+		{name: "chain shorter than hops", proxyHops: 2, xff: []string{"203.0.113.5"}, want: "203.0.113.5"},
+		{name: "garbage in trusted slot", proxyHops: 1, xff: []string{"1.1.1.1, not-an-ip"}, want: "10.0.0.9"},
+		{name: "ipv6", proxyHops: 1, xff: []string{"1.1.1.1, 2001:db8::1"}, want: "2001:db8::1"},
+		{name: "x-real-ip without xff", proxyHops: 1, realIP: "203.0.113.5", want: "203.0.113.5"},
+		{name: "xff wins over x-real-ip", proxyHops: 1, xff: []string{"203.0.113.5"}, realIP: "1.1.1.1", want: "203.0.113.5"},
+		{name: "bad x-real-ip", proxyHops: 1, realIP: "evil", want: "10.0.0.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/me", nil)
+			r.RemoteAddr = "10.0.0.9:41000"
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := clientIP(r, tt.proxyHops); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"auth-service/internal/lib/crypto"
+	"auth-service/internal/lib/logger/sl"
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+
+	// maxPeekBody ограничивает, сколько тела запроса читается ради serviceId и пользователя.
+	maxPeekBody = 64 << 10
+)
+
+type requestIDKey struct{}
+
+// RequestID возвращает идентификатор текущего запроса.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestLogger кладет в контекст логгер с request_id, route, serviceId и
+// псевдонимом пользователя, а по завершении пишет строку access-лога.
+// Сами initData и токены в логгер не попадают.
+func RequestLogger(log *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(RequestIDHeader)
+			if requestID == "" || len(requestID) > 64 {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			attrs := []any{
+				slog.String("request_id", requestID),
+				slog.String("method", r.Method),
+				slog.String("route", routeTemplate(r)),
+			}
+			if peek, ok := peekBody(r); ok {
+				if peek.ServiceID != 0 {
+					attrs = append(attrs, slog.Int64("serviceId", peek.ServiceID))
+				}
+				if user := userPseudonym(peek.InitData); user != "" {
+					attrs = append(attrs, slog.String("user", user))
+				}
+			}
+
+			reqLog := log.With(attrs...)
+			ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+			ctx = sl.WithLogger(ctx, reqLog)
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			reqLog.InfoContext(ctx, "request completed",
+				slog.Int("status", rec.status),
+				slog.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
+
+// UnaryRequestLogger - то же самое для gRPC, request id берется из метаданных x-request-id.
+func UnaryRequestLogger(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, reqLog := grpcRequestLogger(ctx, log, info.FullMethod)
+
+		resp, err := handler(ctx, req)
+
+		logCompleted(ctx, reqLog, start, err)
+		return resp, err
+	}
+}
+
+// StreamRequestLogger - UnaryRequestLogger для стримов: строка access-лога
+// пишется, когда стрим закрыт.
+func StreamRequestLogger(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, reqLog := grpcRequestLogger(ss.Context(), log, info.FullMethod)
+
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+
+		logCompleted(ctx, reqLog, start, err)
+		return err
+	}
+}
+
+func grpcRequestLogger(ctx context.Context, log *slog.Logger, fullMethod string) (context.Context, *slog.Logger) {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-request-id"); len(v) > 0 && len(v[0]) <= 64 {
+			requestID = v[0]
+		}
+	}
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+
+	reqLog := log.With(
+		slog.String("request_id", requestID),
+		slog.String("route", fullMethod),
+	)
+	ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+	return sl.WithLogger(ctx, reqLog), reqLog
+}
+
+func logCompleted(ctx context.Context, reqLog *slog.Logger, start time.Time, err error) {
+	attrs := []any{slog.Duration("duration", time.Since(start))}
+	if err != nil {
+		attrs = append(attrs, sl.Err(err))
+	}
+	reqLog.InfoContext(ctx, "request completed", attrs...)
+}
+
+// contextStream подменяет контекст стрима, чтобы обработчик видел значения,
+// которые положили интерсепторы.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+type bodyPeek struct {
+	InitData  string `json:"initData"`
+	ServiceID int64  `json:"serviceId"`
+}
+
+// peekBody читает начало JSON-тела и возвращает его обратно в запрос.
+func peekBody(r *http.Request) (bodyPeek, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return bodyPeek{}, false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBody))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	if err != nil {
+		return bodyPeek{}, false
+	}
+
+	var peek bodyPeek
+	if err := json.Unmarshal(data, &peek); err != nil {
+		return bodyPeek{}, false
+	}
+	return peek, true
+}
+
+// userPseudonym извлекает telegram id без проверки подписи - значение идет только в логи.
+func userPseudonym(rawInitData string) string {
+	if rawInitData == "" || !crypto.Initialized() {
+		return ""
+	}
+	data, err := initdata.Parse(rawInitData)
+	if err != nil || data.User.ID == 0 {
+		return ""
+	}
+	return crypto.Pseudonym(data.User.ID)
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
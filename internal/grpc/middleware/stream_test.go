@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"auth-service/internal/lib/clientinfo"
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeStream) Context() context.Context { return s.ctx }
+
+func TestStreamInterceptors(t *testing.T) {
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-request-id", "req-1",
+		"user-agent", "grpc-go/1.73.0",
+	))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.7"), Port: 5000}})
+	info := &grpc.StreamServerInfo{FullMethod: "/sso.v1.Auth/Events"}
+
+	var got clientinfo.Info
+	var requestID string
+	handler := func(srv any, ss grpc.ServerStream) error {
+		got = clientinfo.From(ss.Context())
+		requestID = RequestID(ss.Context())
+		return nil
+	}
+	chained := func(srv any, ss grpc.ServerStream) error {
+		return StreamClientInfo()(srv, ss, info, handler)
+	}
+	if err := StreamRequestLogger(log)(nil, fakeStream{ctx: ctx}, info, chained); err != nil {
+		t.Fatalf("interceptor: %v", err)
+	}
+
+	if requestID != "req-1" {
+		t.Errorf("RequestID = %q, want req-1", requestID)
+	}
+	if got.IP != "10.0.0.7" || got.UserAgent != "grpc-go/1.73.0" {
+		t.Errorf("client info = %+v", got)
+	}
+	if line := logs.String(); !strings.Contains(line, `"request_id":"req-1"`) || !strings.Contains(line, `"route":"/sso.v1.Auth/Events"`) {
+		t.Errorf("access log = %s", line)
+	}
+}
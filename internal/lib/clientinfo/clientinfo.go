@@ -0,0 +1,86 @@
+// Package clientinfo переносит сведения о клиенте запроса от транспорта до
+// сервиса, который записывает их в сессию.
+package clientinfo
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxUserAgent и maxPlatform ограничивают в байтах User-Agent и платформу,
+// сохраняемые в сессии: оба значения присылает клиент.
+const (
+	maxUserAgent = 512
+	maxPlatform  = 32
+)
+
+// Info - адрес и устройство клиента. Platform присылает сам мини-апп
+// (Telegram.WebApp.platform), при его отсутствии она угадывается по User-Agent.
+type Info struct {
+	IP        string
+	UserAgent string
+	Platform  string
+}
+
+type ctxKey struct{}
+
+// With кладет сведения о клиенте в контекст.
+func With(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, ctxKey{}, info)
+}
+
+// WithPlatform дополняет сведения в контексте платформой из тела запроса.
+func WithPlatform(ctx context.Context, platform string) context.Context {
+	if platform == "" {
+		return ctx
+	}
+	info := From(ctx)
+	info.Platform = platform
+	return With(ctx, info)
+}
+
+// From достает сведения о клиенте. Platform всегда заполнена, если ее можно угадать.
+func From(ctx context.Context) Info {
+	info, _ := ctx.Value(ctxKey{}).(Info)
+	info.UserAgent = truncate(info.UserAgent, maxUserAgent)
+	info.Platform = truncate(info.Platform, maxPlatform)
+	if info.Platform == "" {
+		info.Platform = platformFromUserAgent(info.UserAgent)
+	}
+	return info
+}
+
+// truncate обрезает s до n байт по границе руны, чтобы в сессию не попал
+// разрезанный UTF-8. Невалидные последовательности заменяются на U+FFFD.
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// platformFromUserAgent грубо определяет платформу в терминах Telegram.WebApp.platform.
+func platformFromUserAgent(ua string) string {
+	ua = strings.ToLower(ua)
+	switch {
+	case ua == "":
+		return ""
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		return "ios"
+	case strings.Contains(ua, "android"):
+		return "android"
+	case strings.Contains(ua, "qtwebengine"):
+		return "tdesktop"
+	case strings.Contains(ua, "macintosh"):
+		return "macos"
+	case strings.Contains(ua, "mozilla"):
+		return "web"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,57 @@
+package clientinfo
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFromTruncatesOnRuneBoundary(t *testing.T) {
+	// "я" занимает два байта, так что граница maxUserAgent приходится на середину руны.
+	ua := "x" + strings.Repeat("я", maxUserAgent)
+	info := From(With(context.Background(), Info{UserAgent: ua}))
+
+	if !utf8.ValidString(info.UserAgent) {
+		t.Fatalf("UserAgent is not valid UTF-8: %q", info.UserAgent[len(info.UserAgent)-4:])
+	}
+	if len(info.UserAgent) != maxUserAgent-1 {
+		t.Fatalf("len(UserAgent) = %d, want %d", len(info.UserAgent), maxUserAgent-1)
+	}
+}
+
+func TestFromCapsPlatform(t *testing.T) {
+	ctx := WithPlatform(context.Background(), strings.Repeat("ü", maxPlatform))
+	info := From(ctx)
+
+	if len(info.Platform) > maxPlatform || !utf8.ValidString(info.Platform) {
+		t.Fatalf("Platform = %q (%d bytes), want valid UTF-8 up to %d bytes", info.Platform, len(info.Platform), maxPlatform)
+	}
+	if got := From(WithPlatform(context.Background(), "ios")).Platform; got != "ios" {
+		t.Fatalf("Platform = %q, want ios", got)
+	}
+}
+
+func TestFromReplacesInvalidUTF8(t *testing.T) {
+	info := From(With(context.Background(), Info{UserAgent: "bad\xffagent"}))
+	if info.UserAgent != "bad�agent" {
+		t.Fatalf("UserAgent = %q", info.UserAgent)
+	}
+}
+
+func TestPlatformFromUserAgent(t *testing.T) {
+	tests := map[string]string{
+		"":                                    "",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS)": "ios",
+		"Mozilla/5.0 (Linux; Android 14)":     "android",
+		"Mozilla/5.0 QtWebEngine/6.5":         "tdesktop",
+		"Mozilla/5.0 (Macintosh; Intel Mac)":  "macos",
+		"Mozilla/5.0 (X11; Linux x86_64)":     "web",
+		"curl/8.5.0":                          "unknown",
+	}
+	for ua, want := range tests {
+		if got := From(With(context.Background(), Info{UserAgent: ua})).Platform; got != want {
+			t.Errorf("platform(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -21,6 +22,19 @@ func InitCrypto(secret string) {
 	key = hash[:]
 }
 
+// Initialized сообщает, был ли вызван InitCrypto.
+func Initialized() bool {
+	return len(key) == sha256.Size
+}
+
+// Pseudonym возвращает необратимый короткий идентификатор пользователя для логов
+// и ключей лимитов. В отличие от HashTgID, его нельзя расшифровать обратно.
+func Pseudonym(tgID int64) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("pseudonym:" + strconv.FormatInt(tgID, 10)))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
 func pad(src []byte) []byte {
 	padding := aes.BlockSize - len(src)%aes.BlockSize
 	padText := bytes.Repeat([]byte{byte(padding)}, padding)
@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrMalformedSealed - строка не похожа на результат Seal или ключ не тот.
+var ErrMalformedSealed = errors.New("malformed sealed value")
+
+// Seal шифрует секрет для хранения в базе (AES-GCM). Ключ выводится из ключа
+// InitCrypto и purpose, поэтому шифротекст одного назначения нельзя
+// расшифровать как другое.
+func Seal(purpose string, plain []byte) (string, error) {
+	aead, err := sealer(purpose)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, []byte(purpose))), nil
+}
+
+// Open расшифровывает строку, полученную от Seal с тем же purpose.
+func Open(purpose, sealed string) ([]byte, error) {
+	aead, err := sealer(purpose)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return nil, ErrMalformedSealed
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], []byte(purpose))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedSealed, err)
+	}
+	return plain, nil
+}
+
+func sealer(purpose string) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("seal:" + purpose))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
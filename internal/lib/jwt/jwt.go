@@ -5,39 +5,161 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"strconv"
+	"strings"
 	"time"
 )
 
-func NewToken(userID string, app models.App, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims - поля, которые NewToken и NewServiceToken кладут в токен.
+type Claims struct {
+	Subject     string
+	SubjectType string
+	ServiceID   int64
+	Scopes      []string
+	ExpiresAt   time.Time
+	// Actor - claim act токенов, полученных обменом (RFC 8693).
+	Actor *Actor
+	// SessionID - claim sid, общий для всех токенов одного входа.
+	SessionID string
+}
+
+// Actor - кто действует от имени субъекта. Вложенный Actor - предыдущее звено
+// цепочки обменов.
+type Actor struct {
+	Subject string `json:"sub"`
+	Actor   *Actor `json:"act,omitempty"`
+}
+
+// NewToken выпускает токен пользователя userID для app. Токен подписывается
+// ключом keys (ES256 с kid) или, если keys == nil, HS256 секретом app.
+func NewToken(keys *Keys, userID string, app models.App, sessionID string, duration time.Duration) (string, error) {
+	claims := jwt.MapClaims{}
 	claims["sub"] = userID
+	claims["sub_type"] = models.SubjectUser
 	claims["serviceID"] = app.ID
+	claims["sid"] = sessionID
 	claims["exp"] = time.Now().Add(duration).Unix()
 
-	tokenString, err := token.SignedString([]byte(app.Secret))
-	if err != nil {
-		return "", err
+	return sign(keys, app, claims)
+}
+
+func sign(keys *Keys, app models.App, claims jwt.MapClaims) (string, error) {
+	if keys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(app.Secret))
 	}
-	return tokenString, nil
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = keys.kid
+	return token.SignedString(keys.signing)
 }
-func ValidateToken(tokenString string, app models.App) (int64, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return app.Secret, nil
-	})
-	if err != nil || !token.Valid {
-		return 0, errors.New("invalid token")
+
+// AppSubject - sub приложения в сервисных токенах и claim act.
+func AppSubject(appID int64) string {
+	return "app:" + strconv.FormatInt(appID, 10)
+}
+
+// NewServiceToken выпускает токен самого приложения: sub - "app:<id>",
+// права перечислены через пробел в claim scope, как в RFC 9068.
+func NewServiceToken(keys *Keys, app models.App, scopes []string, duration time.Duration) (string, error) {
+	claims := jwt.MapClaims{}
+	claims["sub"] = AppSubject(int64(app.ID))
+	claims["sub_type"] = models.SubjectService
+	claims["serviceID"] = app.ID
+	claims["scope"] = strings.Join(scopes, " ")
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Add(duration).Unix()
+
+	return sign(keys, app, claims)
+}
+
+// NewExchangedToken выпускает токен пользователя sub для app по обмену: claim act
+// фиксирует приложение, которое выполнило обмен, scope - права из политики доверия.
+// sid переносится из исходного токена, чтобы завершение сессии отзывало всю цепочку.
+func NewExchangedToken(keys *Keys, sub string, app models.App, scopes []string, actor *Actor, sessionID string, duration time.Duration) (string, error) {
+	claims := jwt.MapClaims{}
+	claims["sub"] = sub
+	claims["sub_type"] = models.SubjectUser
+	claims["serviceID"] = app.ID
+	claims["act"] = actor
+	if sessionID != "" {
+		claims["sid"] = sessionID
 	}
-	claims, ok := token.Claims.(jwt.MapClaims)
+	if len(scopes) > 0 {
+		claims["scope"] = strings.Join(scopes, " ")
+	}
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = time.Now().Add(duration).Unix()
+
+	return sign(keys, app, claims)
+}
+
+// ServiceID читает serviceID без проверки подписи - только чтобы найти приложение,
+// для которого токен затем проверяется в ValidateToken.
+func ServiceID(tokenString string) (int64, error) {
+	var claims jwt.MapClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &claims); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	serviceID, ok := claims["serviceID"].(float64)
 	if !ok {
-		return 0, errors.New("invalid claims")
+		return 0, fmt.Errorf("%w: serviceID missing", ErrInvalidToken)
+	}
+	return int64(serviceID), nil
+}
+
+// ValidateToken проверяет подпись, срок действия и то, что токен выпущен
+// именно для app. ES256-токены проверяются ключом keys по kid, HS256 - секретом
+// app: так остаются действительными токены, выданные до включения ключей.
+func ValidateToken(tokenString string, app models.App, keys *Keys) (Claims, error) {
+	var claims jwt.MapClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method == jwt.SigningMethodHS256 {
+			return []byte(app.Secret), nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		return keys.publicKey(kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodES256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
 	}
-	uid, ok := claims["user_id"].(float64)
+
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return Claims{}, fmt.Errorf("%w: sub missing", ErrInvalidToken)
+	}
+	serviceID, ok := claims["serviceID"].(float64)
+	if !ok || int64(serviceID) != int64(app.ID) {
+		return Claims{}, fmt.Errorf("%w: token issued for another app", ErrInvalidToken)
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	subType, _ := claims["sub_type"].(string)
+	if subType == "" {
+		subType = models.SubjectUser
+	}
+	scope, _ := claims["scope"].(string)
+	sid, _ := claims["sid"].(string)
+
+	return Claims{
+		Subject:     sub,
+		SubjectType: subType,
+		ServiceID:   int64(serviceID),
+		Scopes:      strings.Fields(scope),
+		ExpiresAt:   exp.Time,
+		Actor:       parseActor(claims["act"]),
+		SessionID:   sid,
+	}, nil
+}
+
+func parseActor(v any) *Actor {
+	act, ok := v.(map[string]any)
 	if !ok {
-		return 0, errors.New("user_id missing")
+		return nil
 	}
-	return int64(uid), nil
+	sub, _ := act["sub"].(string)
+	return &Actor{Subject: sub, Actor: parseActor(act["act"])}
 }
@@ -0,0 +1,121 @@
+package jwt
+
+import (
+	"auth-service/internal/domains/models"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	app   = models.App{ID: 1, Name: "test", Secret: "test-secret"}
+	other = models.App{ID: 2, Name: "other", Secret: "other-secret"}
+)
+
+func TestNewToken(t *testing.T) {
+	token, err := NewToken(nil, "user-1", app, "sid-1", time.Hour)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	if id, err := ServiceID(token); err != nil || id != 1 {
+		t.Errorf("ServiceID = %d, %v, want 1", id, err)
+	}
+
+	claims, err := ValidateToken(token, app, nil)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.SubjectType != models.SubjectUser || claims.ServiceID != 1 ||
+		claims.SessionID != "sid-1" || claims.Actor != nil || len(claims.Scopes) != 0 {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if d := time.Until(claims.ExpiresAt); d <= 59*time.Minute || d > time.Hour {
+		t.Errorf("ExpiresAt in %v, want about an hour", d)
+	}
+}
+
+func TestNewServiceToken(t *testing.T) {
+	token, err := NewServiceToken(nil, app, []string{"users:read", "users:ban"}, time.Minute)
+	if err != nil {
+		t.Fatalf("NewServiceToken: %v", err)
+	}
+	claims, err := ValidateToken(token, app, nil)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.Subject != "app:1" || claims.SubjectType != models.SubjectService || claims.SessionID != "" ||
+		!slices.Equal(claims.Scopes, []string{"users:read", "users:ban"}) {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+func TestNewExchangedToken(t *testing.T) {
+	actor := &Actor{Subject: AppSubject(1), Actor: &Actor{Subject: AppSubject(3)}}
+	token, err := NewExchangedToken(nil, "user-1", other, []string{"profile"}, actor, "sid-1", time.Minute)
+	if err != nil {
+		t.Fatalf("NewExchangedToken: %v", err)
+	}
+	claims, err := ValidateToken(token, other, nil)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.ServiceID != 2 || claims.SessionID != "sid-1" ||
+		!slices.Equal(claims.Scopes, []string{"profile"}) {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if claims.Actor == nil || claims.Actor.Subject != "app:1" || claims.Actor.Actor == nil ||
+		claims.Actor.Actor.Subject != "app:3" || claims.Actor.Actor.Actor != nil {
+		t.Errorf("unexpected actor chain %+v", claims.Actor)
+	}
+}
+
+func TestValidateTokenRejects(t *testing.T) {
+	sign := func(method gojwt.SigningMethod, key any, claims gojwt.MapClaims) string {
+		token, err := gojwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return token
+	}
+	valid := func() gojwt.MapClaims {
+		return gojwt.MapClaims{"sub": "user-1", "serviceID": 1, "exp": time.Now().Add(time.Hour).Unix()}
+	}
+	without := func(key string) gojwt.MapClaims {
+		claims := valid()
+		delete(claims, key)
+		return claims
+	}
+	expired := valid()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	foreign := valid()
+	foreign["serviceID"] = 2
+
+	tests := map[string]string{
+		"wrong secret":   sign(gojwt.SigningMethodHS256, []byte(other.Secret), valid()),
+		"other alg":      sign(gojwt.SigningMethodHS512, []byte(app.Secret), valid()),
+		"none alg":       sign(gojwt.SigningMethodNone, gojwt.UnsafeAllowNoneSignatureType, valid()),
+		"expired":        sign(gojwt.SigningMethodHS256, []byte(app.Secret), expired),
+		"no exp":         sign(gojwt.SigningMethodHS256, []byte(app.Secret), without("exp")),
+		"no sub":         sign(gojwt.SigningMethodHS256, []byte(app.Secret), without("sub")),
+		"no serviceID":   sign(gojwt.SigningMethodHS256, []byte(app.Secret), without("serviceID")),
+		"another app id": sign(gojwt.SigningMethodHS256, []byte(app.Secret), foreign),
+		"garbage":        "not-a-token",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ValidateToken(token, app, nil); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("ValidateToken error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestServiceIDMissing(t *testing.T) {
+	token, _ := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{"sub": "user-1"}).SignedString([]byte("x"))
+	if _, err := ServiceID(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ServiceID error = %v, want %v", err, ErrInvalidToken)
+	}
+}
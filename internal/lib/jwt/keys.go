@@ -0,0 +1,159 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Keys - ключи ES256 (P-256), которыми SSO подписывает токены. Первый ключ
+// подписывает новые токены, остальные - выведенные из ротации: ими только
+// проверяются уже выданные токены, и они публикуются в JWKS, пока те не истекут.
+// nil вместо Keys означает прежнюю подпись HS256 секретом приложения.
+type Keys struct {
+	signing *ecdsa.PrivateKey
+	kid     string
+	public  map[string]*ecdsa.PublicKey
+	jwks    JWKS
+}
+
+// JWK - открытый ключ в формате RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+// JWKS - ответ /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeys собирает набор из ключей P-256, первый из них подписывает токены.
+func NewKeys(keys ...*ecdsa.PrivateKey) (*Keys, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("jwt: no signing keys")
+	}
+	set := &Keys{public: make(map[string]*ecdsa.PublicKey, len(keys))}
+	for i, key := range keys {
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("jwt: key %d is not P-256", i)
+		}
+		jwk, err := publicJWK(&key.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %d: %w", i, err)
+		}
+		if _, ok := set.public[jwk.Kid]; ok {
+			return nil, fmt.Errorf("jwt: key %d is listed twice", i)
+		}
+		if i == 0 {
+			set.signing, set.kid = key, jwk.Kid
+		}
+		set.public[jwk.Kid] = &key.PublicKey
+		set.jwks.Keys = append(set.jwks.Keys, jwk)
+	}
+	return set, nil
+}
+
+// LoadKeys читает закрытые ключи P-256 из PEM-файлов (PKCS#8 или SEC 1),
+// первый файл - ключ подписи.
+func LoadKeys(files ...string) (*Keys, error) {
+	keys := make([]*ecdsa.PrivateKey, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: %w", err)
+		}
+		key, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: %s: %w", file, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewKeys(keys...)
+}
+
+func parsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		ec, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("not an ECDSA key")
+		}
+		return ec, nil
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+}
+
+// JWKS возвращает открытые ключи набора для /.well-known/jwks.json.
+func (k *Keys) JWKS() JWKS {
+	if k == nil {
+		return JWKS{Keys: []JWK{}}
+	}
+	return k.jwks
+}
+
+func (k *Keys) publicKey(kid string) (*ecdsa.PublicKey, error) {
+	if k == nil {
+		return nil, errors.New("asymmetric tokens are not enabled")
+	}
+	key, ok := k.public[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+// publicJWK описывает ключ как JWK, kid - отпечаток ключа по RFC 7638.
+func publicJWK(key *ecdsa.PublicKey) (JWK, error) {
+	ecdhKey, err := key.ECDH()
+	if err != nil {
+		return JWK{}, err
+	}
+	// Несжатая точка: 0x04 || X || Y, координаты по 32 байта.
+	point := ecdhKey.Bytes()
+	x := base64.RawURLEncoding.EncodeToString(point[1:33])
+	y := base64.RawURLEncoding.EncodeToString(point[33:])
+
+	thumbprint, err := json.Marshal(struct {
+		Crv string `json:"crv"`
+		Kty string `json:"kty"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}{"P-256", "EC", x, y})
+	if err != nil {
+		return JWK{}, err
+	}
+	sum := sha256.Sum256(thumbprint)
+	return JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   x,
+		Y:   y,
+		Kid: base64.RawURLEncoding.EncodeToString(sum[:]),
+		Use: "sig",
+		Alg: "ES256",
+	}, nil
+}
@@ -0,0 +1,128 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKeysSignES256(t *testing.T) {
+	current, retired := newKey(t), newKey(t)
+	oldKeys, err := NewKeys(retired)
+	if err != nil {
+		t.Fatalf("NewKeys: %v", err)
+	}
+	keys, err := NewKeys(current, retired)
+	if err != nil {
+		t.Fatalf("NewKeys: %v", err)
+	}
+
+	token, err := NewToken(keys, "user-1", app, "sid-1", time.Hour)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	parsed, _, err := gojwt.NewParser().ParseUnverified(token, gojwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Method != gojwt.SigningMethodES256 || parsed.Header["kid"] != keys.JWKS().Keys[0].Kid {
+		t.Fatalf("header = %v, want ES256 with kid of the first key", parsed.Header)
+	}
+	if claims, err := ValidateToken(token, app, keys); err != nil || claims.Subject != "user-1" {
+		t.Fatalf("ValidateToken = %+v, %v", claims, err)
+	}
+
+	// Токен, подписанный выведенным из ротации ключом, еще действителен.
+	old, _ := NewToken(oldKeys, "user-1", app, "sid-1", time.Hour)
+	if _, err := ValidateToken(old, app, keys); err != nil {
+		t.Fatalf("token of the retired key: %v", err)
+	}
+	// HS256-токены, выданные до включения ключей, тоже.
+	legacy, _ := NewToken(nil, "user-1", app, "sid-1", time.Hour)
+	if _, err := ValidateToken(legacy, app, keys); err != nil {
+		t.Fatalf("HS256 token: %v", err)
+	}
+
+	foreign, _ := NewKeys(newKey(t))
+	unknown, _ := NewToken(foreign, "user-1", app, "sid-1", time.Hour)
+	if _, err := ValidateToken(unknown, app, keys); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token of an unknown key: %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := ValidateToken(token, app, nil); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("ES256 token without keys: %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestKeysJWKS(t *testing.T) {
+	keys, err := NewKeys(newKey(t), newKey(t))
+	if err != nil {
+		t.Fatalf("NewKeys: %v", err)
+	}
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid == jwks.Keys[1].Kid {
+		t.Fatalf("JWKS = %+v, want two keys with distinct kids", jwks)
+	}
+	for _, k := range jwks.Keys {
+		if k.Kty != "EC" || k.Crv != "P-256" || k.Alg != "ES256" || k.Use != "sig" || len(k.X) != 43 || len(k.Y) != 43 {
+			t.Errorf("unexpected JWK %+v", k)
+		}
+	}
+	if got := (*Keys)(nil).JWKS(); got.Keys == nil || len(got.Keys) != 0 {
+		t.Errorf("nil JWKS = %+v, want an empty key list", got)
+	}
+
+	if _, err := NewKeys(); err == nil {
+		t.Error("NewKeys without keys succeeded")
+	}
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if _, err := NewKeys(p384); err == nil {
+		t.Error("NewKeys accepted a P-384 key")
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	key := newKey(t)
+	dir := t.TempDir()
+
+	sec1, _ := x509.MarshalECPrivateKey(key)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(newKey(t))
+	files := map[string]*pem.Block{
+		"sec1.pem":  {Type: "EC PRIVATE KEY", Bytes: sec1},
+		"pkcs8.pem": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	}
+	for name, block := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := LoadKeys(filepath.Join(dir, "sec1.pem"), filepath.Join(dir, "pkcs8.pem"))
+	if err != nil {
+		t.Fatalf("LoadKeys: %v", err)
+	}
+	want, _ := NewKeys(key)
+	if keys.JWKS().Keys[0] != want.JWKS().Keys[0] {
+		t.Errorf("signing key = %+v, want %+v", keys.JWKS().Keys[0], want.JWKS().Keys[0])
+	}
+	if _, err := LoadKeys(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("LoadKeys of a missing file succeeded")
+	}
+}
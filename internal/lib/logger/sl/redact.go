@@ -0,0 +1,54 @@
+package sl
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys - ключи атрибутов, значения которых никогда не попадают в лог.
+var sensitiveKeys = map[string]struct{}{
+	"initdata":      {},
+	"init_data":     {},
+	"userhash":      {},
+	"token":         {},
+	"access_token":  {},
+	"refresh_token": {},
+	"authorization": {},
+	"secret":        {},
+	"password":      {},
+	"hash":          {},
+	"signature":     {},
+}
+
+var (
+	jwtPattern      = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	initDataPattern = regexp.MustCompile(`[^\s"]*(?:auth_date|hash)=[^\s"]*`)
+)
+
+// Redact используется как slog.HandlerOptions.ReplaceAttr. Он скрывает значения
+// чувствительных ключей и вычищает JWT и initData из произвольных строк, например из текста ошибок.
+func Redact(groups []string, a slog.Attr) slog.Attr {
+	if isSensitive(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	if a.Value.Kind() == slog.KindString {
+		v := a.Value.String()
+		if strings.Contains(v, "eyJ") || strings.Contains(v, "hash=") || strings.Contains(v, "auth_date=") {
+			v = jwtPattern.ReplaceAllString(v, redacted)
+			v = initDataPattern.ReplaceAllString(v, redacted)
+			return slog.String(a.Key, v)
+		}
+	}
+	return a
+}
+
+func isSensitive(key string) bool {
+	k := strings.ToLower(key)
+	if _, ok := sensitiveKeys[k]; ok {
+		return true
+	}
+	return strings.HasSuffix(k, "_token") || strings.HasSuffix(k, "_secret") || strings.HasSuffix(k, "_key")
+}
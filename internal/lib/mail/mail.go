@@ -0,0 +1,140 @@
+// Package mail отправляет письма пользователям: подтверждение email и сброс
+// пароля. Log и File не отправляют писем и нужны для локального запуска и
+// тестов, SMTP отправляет через почтовый сервер.
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Log пишет письма в лог вместо отправки.
+type Log struct {
+	log *slog.Logger
+}
+
+func NewLog(log *slog.Logger) *Log {
+	return &Log{log: log}
+}
+
+func (m *Log) Send(ctx context.Context, msg Message) error {
+	m.log.InfoContext(ctx, "mail",
+		slog.String("to", msg.To), slog.String("subject", msg.Subject), slog.String("body", msg.Body))
+	return nil
+}
+
+// File дописывает письма в файл по одному JSON на строку; ReadFile читает их
+// обратно.
+type File struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (m *File) Send(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("mail: %w", err)
+	}
+	return nil
+}
+
+// ReadFile возвращает письма, записанные File, в порядке отправки.
+func ReadFile(path string) ([]Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("mail: %w", err)
+	}
+	defer f.Close()
+
+	var messages []Message
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("mail: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("mail: %w", err)
+	}
+	return messages, nil
+}
+
+// SMTP отправляет письма через почтовый сервер addr (host:port) от адреса from.
+// Пустой username отключает аутентификацию.
+type SMTP struct {
+	addr    string
+	from    string
+	auth    smtp.Auth
+	timeout time.Duration
+}
+
+func NewSMTP(addr, from, username, password string, timeout time.Duration) *SMTP {
+	m := &SMTP{addr: addr, from: from, timeout: timeout}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTP) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("mail: header contains newline")
+	}
+	body := "From: " + m.from + "\r\n" +
+		"To: " + msg.To + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + strings.ReplaceAll(msg.Body, "\n", "\r\n")
+
+	// net/smtp не принимает контекст, поэтому отправка ограничена timeout.
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, []byte(body)) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("mail: %w", err)
+		}
+		return nil
+	case <-time.After(m.timeout):
+		return fmt.Errorf("mail: send timeout")
+	case <-ctx.Done():
+		return fmt.Errorf("mail: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,183 @@
+// Package oidc проверяет ID-токены OpenID Connect от Google и Apple: подпись
+// RS256 ключом из JWKS провайдера, iss, aud и срок действия.
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid id token")
+
+var errUnknownKey = errors.New("unknown key")
+
+// keysRefreshInterval - не чаще этого JWKS перечитывается из-за неизвестного kid,
+// чтобы токены с выдуманным kid не превращались в запросы к провайдеру.
+const keysRefreshInterval = time.Minute
+
+// leeway - допустимое расхождение часов с провайдером.
+const leeway = time.Minute
+
+// Provider - параметры провайдера. ClientIDs - допустимые aud: client_id
+// веб-клиента и мобильных приложений (у Apple - Services ID и bundle ID).
+type Provider struct {
+	Issuers   []string
+	ClientIDs []string
+	JWKSURL   string
+}
+
+// Google и Apple - адреса JWKS и издатели провайдеров; ClientIDs задаются в конфиге.
+var (
+	Google = Provider{
+		Issuers: []string{"https://accounts.google.com", "accounts.google.com"},
+		JWKSURL: "https://www.googleapis.com/oauth2/v3/certs",
+	}
+	Apple = Provider{
+		Issuers: []string{"https://appleid.apple.com"},
+		JWKSURL: "https://appleid.apple.com/auth/keys",
+	}
+)
+
+// Claims - поля проверенного ID-токена, которые нужны сервису.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type Verifier struct {
+	provider Provider
+	http     *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func New(provider Provider, timeout time.Duration) *Verifier {
+	return &Verifier{provider: provider, http: &http.Client{Timeout: timeout}}
+}
+
+// Verify проверяет ID-токен и возвращает его claims. Ошибки самого токена
+// оборачивают ErrInvalidToken, недоступный JWKS - нет.
+func (v *Verifier) Verify(ctx context.Context, raw string) (Claims, error) {
+	var fetchErr error
+	token, err := jwt.Parse(raw, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.key(ctx, kid)
+		if err != nil && !errors.Is(err, errUnknownKey) {
+			fetchErr = err
+		}
+		return key, err
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	)
+	if fetchErr != nil {
+		return Claims{}, fetchErr
+	}
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	iss, _ := claims.GetIssuer()
+	if !slices.Contains(v.provider.Issuers, iss) {
+		return Claims{}, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, iss)
+	}
+	aud, _ := claims.GetAudience()
+	if !slices.ContainsFunc(aud, func(a string) bool { return slices.Contains(v.provider.ClientIDs, a) }) {
+		return Claims{}, fmt.Errorf("%w: unexpected audience %v", ErrInvalidToken, aud)
+	}
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return Claims{}, fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+
+	result := Claims{Subject: sub}
+	result.Email, _ = claims["email"].(string)
+	// Apple присылает email_verified строкой "true".
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+	return result, nil
+}
+
+// key возвращает ключ kid, перечитывая JWKS, если ключа нет в кэше.
+func (v *Verifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if v.keys != nil && time.Since(v.fetchedAt) < keysRefreshInterval {
+		return nil, fmt.Errorf("%w %q", errUnknownKey, kid)
+	}
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.keys, v.fetchedAt = keys, time.Now()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w %q", errUnknownKey, kid)
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.provider.JWKSURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: %w", err)
+	}
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: fetch jwks: status %d", resp.StatusCode)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("oidc: decode jwks: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
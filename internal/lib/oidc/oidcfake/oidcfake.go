@@ -0,0 +1,88 @@
+// Package oidcfake - локальный провайдер OpenID Connect для тестов входа через
+// Google и Apple: отдает JWKS и подписывает ID-токены своим ключом.
+//
+//	fake := oidcfake.New()
+//	defer fake.Close()
+//	verifier := oidc.New(fake.Provider("web-client"), time.Second)
+//	claims, err := verifier.Verify(ctx, fake.IDToken("web-client", "user-1", "user@example.com"))
+package oidcfake
+
+import (
+	"auth-service/internal/lib/oidc"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "oidcfake"
+
+type Server struct {
+	// URL - издатель (iss) токенов фейка.
+	URL string
+
+	key  *rsa.PrivateKey
+	http *httptest.Server
+}
+
+func New() *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s := &Server{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keys", s.keys)
+	s.http = httptest.NewServer(mux)
+	s.URL = s.http.URL
+	return s
+}
+
+func (s *Server) Close() {
+	s.http.Close()
+}
+
+// Provider описывает фейк для oidc.New с допустимыми aud clientIDs.
+func (s *Server) Provider(clientIDs ...string) oidc.Provider {
+	return oidc.Provider{Issuers: []string{s.URL}, ClientIDs: clientIDs, JWKSURL: s.URL + "/keys"}
+}
+
+// IDToken выпускает ID-токен пользователя subject для клиента clientID на час.
+func (s *Server) IDToken(clientID, subject, email string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            s.URL,
+		"aud":            clientID,
+		"sub":            subject,
+		"email":          email,
+		"email_verified": email != "",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func (s *Server) keys(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
@@ -0,0 +1,58 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	hash, err := Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("Hash = %q, want PHC argon2id", hash)
+	}
+	if again, _ := Hash("correct horse battery staple"); again == hash {
+		t.Error("Hash reuses the salt")
+	}
+
+	if ok, err := Verify("correct horse battery staple", hash); err != nil || !ok {
+		t.Errorf("Verify(correct) = %v, %v", ok, err)
+	}
+	if ok, err := Verify("Correct horse battery staple", hash); err != nil || ok {
+		t.Errorf("Verify(wrong) = %v, %v", ok, err)
+	}
+}
+
+// Хэш с другими параметрами проверяется по параметрам из самого хэша.
+func TestVerifyOtherParams(t *testing.T) {
+	salt := []byte("somesaltsomesalt")
+	key := argon2.IDKey([]byte("password"), salt, 1, 1024, 2, 16)
+	hash := fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=2$%s$%s",
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+	if ok, err := Verify("password", hash); err != nil || !ok {
+		t.Errorf("Verify = %v, %v", ok, err)
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plain",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$",
+	} {
+		if _, err := Verify("password", hash); !errors.Is(err, ErrMalformedHash) {
+			t.Errorf("Verify(%q) error = %v, want %v", hash, err, ErrMalformedHash)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"auth-service/internal/lib/logger/sl"
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limit описывает token bucket: Rate токенов в секунду, не больше Burst в запасе.
+// Нулевой Rate отключает лимит.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Store хранит состояние бакетов. MemoryStore работает в пределах процесса,
+// postgres.Storage позволяет нескольким инстансам делить лимиты.
+type Store interface {
+	TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (ok bool, retryAfter time.Duration, err error)
+}
+
+// Keys - по чему ограничивается запрос. Пустые значения пропускаются.
+type Keys struct {
+	IP        string
+	User      string
+	ServiceID int64
+}
+
+type Limiter struct {
+	log   *slog.Logger
+	store Store
+	ip    Limit
+	user  Limit
+	app   Limit
+}
+
+func New(log *slog.Logger, store Store, ip, user, app Limit) *Limiter {
+	return &Limiter{log: log, store: store, ip: ip, user: user, app: app}
+}
+
+// Allow списывает по токену из каждого бакета запроса. При ошибке хранилища
+// запрос пропускается: недоступная база лимитов не должна останавливать вход.
+func (l *Limiter) Allow(ctx context.Context, keys Keys) (bool, time.Duration) {
+	now := time.Now()
+
+	type check struct {
+		key   string
+		limit Limit
+	}
+	var checks []check
+	if keys.IP != "" {
+		checks = append(checks, check{"ip:" + keys.IP, l.ip})
+	}
+	if keys.User != "" {
+		checks = append(checks, check{"user:" + keys.User, l.user})
+	}
+	if keys.ServiceID != 0 {
+		checks = append(checks, check{"app:" + strconv.FormatInt(keys.ServiceID, 10), l.app})
+	}
+
+	for _, c := range checks {
+		if !c.limit.enabled() {
+			continue
+		}
+		ok, retryAfter, err := l.store.TakeToken(ctx, c.key, c.limit.Rate, c.limit.Burst, now)
+		if err != nil {
+			sl.FromContext(ctx, l.log).WarnContext(ctx, "rate limit store failed", sl.Err(err))
+			continue
+		}
+		if !ok {
+			return false, retryAfter
+		}
+	}
+	return true, 0
+}
+
+// Take пересчитывает бакет на момент now и пытается списать один токен.
+// Вынесено отдельно, чтобы MemoryStore и postgres.Storage считали одинаково.
+func Take(tokens float64, updatedAt time.Time, rate float64, burst int, now time.Time) (float64, bool, time.Duration) {
+	elapsed := now.Sub(updatedAt).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(float64(burst), tokens+elapsed*rate)
+	}
+	if tokens >= 1 {
+		return tokens - 1, true, 0
+	}
+	wait := time.Duration((1 - tokens) / rate * float64(time.Second))
+	return tokens, false, wait
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	rate      float64
+	burst     int
+}
+
+// MemoryStore - бакеты в памяти процесса. Полностью восстановившиеся бакеты
+// периодически удаляются, чтобы случайные ключи не копились бесконечно.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.rate, b.burst = rate, burst
+
+	tokens, allowed, retryAfter := Take(b.tokens, b.updatedAt, rate, burst, now)
+	b.tokens, b.updatedAt = tokens, now
+
+	return allowed, retryAfter, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.updatedAt).Seconds()*b.rate >= float64(b.burst) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
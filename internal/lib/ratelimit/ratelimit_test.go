@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+
+	tests := []struct {
+		name      string
+		tokens    float64
+		elapsed   time.Duration
+		wantOK    bool
+		wantLeft  float64
+		wantRetry time.Duration
+	}{
+		{name: "full bucket", tokens: 3, wantOK: true, wantLeft: 2},
+		{name: "last token", tokens: 1, wantOK: true, wantLeft: 0},
+		{name: "empty", tokens: 0, wantOK: false, wantLeft: 0, wantRetry: 500 * time.Millisecond},
+		{name: "half refilled", tokens: 0, elapsed: 250 * time.Millisecond, wantOK: false, wantLeft: 0.5, wantRetry: 250 * time.Millisecond},
+		{name: "refilled", tokens: 0, elapsed: time.Second, wantOK: true, wantLeft: 1},
+		{name: "capped by burst", tokens: 1, elapsed: time.Hour, wantOK: true, wantLeft: 2},
+		{name: "clock went back", tokens: 0.5, elapsed: -time.Minute, wantOK: false, wantLeft: 0.5, wantRetry: 250 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, ok, retry := Take(tt.tokens, start, 2, 3, start.Add(tt.elapsed))
+			if ok != tt.wantOK || left != tt.wantLeft || retry != tt.wantRetry {
+				t.Errorf("Take = %v, %v, %v, want %v, %v, %v", left, ok, retry, tt.wantLeft, tt.wantOK, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := s.TakeToken(ctx, "a", 1, 2, now); !ok {
+			t.Fatalf("TakeToken #%d denied", i+1)
+		}
+	}
+	if ok, retry, _ := s.TakeToken(ctx, "a", 1, 2, now); ok || retry != time.Second {
+		t.Errorf("TakeToken over burst = %v, %v, want denied for 1s", ok, retry)
+	}
+	if ok, _, _ := s.TakeToken(ctx, "b", 1, 2, now); !ok {
+		t.Error("bucket b shares tokens with bucket a")
+	}
+
+	// Через минуту бакеты восстановились и удаляются при очистке.
+	later := now.Add(2 * sweepInterval)
+	s.TakeToken(ctx, "c", 1, 2, later)
+	if _, ok := s.buckets["a"]; ok {
+		t.Error("refilled bucket a was not swept")
+	}
+	if _, ok := s.buckets["c"]; !ok {
+		t.Error("bucket c is missing")
+	}
+}
+
+type failingStore struct{ err error }
+
+func (s failingStore) TakeToken(context.Context, string, float64, int, time.Time) (bool, time.Duration, error) {
+	return false, 0, s.err
+}
+
+type recordingStore struct {
+	keys  []string
+	allow map[string]bool
+}
+
+func (s *recordingStore) TakeToken(_ context.Context, key string, _ float64, _ int, _ time.Time) (bool, time.Duration, error) {
+	s.keys = append(s.keys, key)
+	if allowed, ok := s.allow[key]; ok && !allowed {
+		return false, time.Second, nil
+	}
+	return true, 0, nil
+}
+
+func TestLimiterAllow(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.DiscardHandler)
+	limit := Limit{Rate: 1, Burst: 1}
+
+	store := &recordingStore{}
+	l := New(log, store, limit, Limit{}, limit)
+	if ok, _ := l.Allow(ctx, Keys{IP: "10.0.0.1", User: "u", ServiceID: 7}); !ok {
+		t.Fatal("Allow denied")
+	}
+	// Лимит пользователя выключен, поэтому его бакет не трогается.
+	if len(store.keys) != 2 || store.keys[0] != "ip:10.0.0.1" || store.keys[1] != "app:7" {
+		t.Errorf("checked keys %v, want ip and app", store.keys)
+	}
+
+	store = &recordingStore{allow: map[string]bool{"ip:10.0.0.1": false}}
+	l = New(log, store, limit, limit, limit)
+	if ok, retry := l.Allow(ctx, Keys{IP: "10.0.0.1", User: "u"}); ok || retry != time.Second {
+		t.Errorf("Allow over ip limit = %v, %v", ok, retry)
+	}
+
+	l = New(log, failingStore{errors.New("db down")}, limit, limit, limit)
+	if ok, _ := l.Allow(ctx, Keys{IP: "10.0.0.1"}); !ok {
+		t.Error("Allow denied on store failure, want fail open")
+	}
+}
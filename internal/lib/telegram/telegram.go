@@ -0,0 +1,114 @@
+// Package telegram - минимальный клиент Bot API для команд модераторов и типы
+// апдейтов, которые Telegram присылает на вебхук бота.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultAPIURL - адрес Bot API. В тестах его заменяет telegramfake.
+const DefaultAPIURL = "https://api.telegram.org"
+
+// HeaderSecretToken - заголовок, в котором Telegram повторяет secret_token из setWebhook.
+const HeaderSecretToken = "X-Telegram-Bot-Api-Secret-Token"
+
+// Update - апдейт вебхука. Бот обрабатывает только сообщения.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+}
+
+type Message struct {
+	MessageID      int64    `json:"message_id"`
+	From           *User    `json:"from,omitempty"`
+	Chat           Chat     `json:"chat"`
+	Text           string   `json:"text,omitempty"`
+	ReplyToMessage *Message `json:"reply_to_message,omitempty"`
+}
+
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func New(baseURL, token string, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+	return &Client{baseURL: baseURL, token: token, http: &http.Client{Timeout: timeout}}
+}
+
+// SendMessage отправляет текст в чат ответом на сообщение replyTo (0 - без ответа).
+func (c *Client) SendMessage(ctx context.Context, chatID, replyTo int64, text string) error {
+	req := map[string]any{"chat_id": chatID, "text": text}
+	if replyTo != 0 {
+		req["reply_parameters"] = map[string]any{"message_id": replyTo, "allow_sending_without_reply": true}
+	}
+	return c.call(ctx, "sendMessage", req)
+}
+
+// SetWebhook направляет апдейты бота на webhookURL. Telegram будет присылать secret
+// в заголовке HeaderSecretToken.
+func (c *Client) SetWebhook(ctx context.Context, webhookURL, secret string) error {
+	return c.call(ctx, "setWebhook", map[string]any{
+		"url":             webhookURL,
+		"secret_token":    secret,
+		"allowed_updates": []string{"message"},
+	})
+}
+
+// call вызывает метод Bot API. Ошибкой считается и ответ с ok=false.
+func (c *Client) call(ctx context.Context, method string, params any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("telegram.%s: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/bot"+c.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("telegram.%s: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// Ошибка http.Client содержит URL с токеном бота, в лог он попасть не должен.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram.%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("telegram.%s: status %d: %w", method, resp.StatusCode, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram.%s: status %d: %s", method, resp.StatusCode, result.Description)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Package telegramfake поднимает локальный HTTP-сервер с методами Bot API,
+// которые вызывает сервис, и запоминает отправленные сообщения - для тестов
+// команд бота без доступа к api.telegram.org.
+//
+//	fake := telegramfake.New()
+//	defer fake.Close()
+//	client := telegram.New(fake.URL, telegramfake.Token, time.Second)
+package telegramfake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Token - токен бота, который принимает фейк. Запросы с другим токеном получают 401.
+const Token = "1:telegramfake-bot-token"
+
+// Message - сообщение, отправленное через sendMessage.
+type Message struct {
+	ChatID  int64
+	ReplyTo int64
+	Text    string
+}
+
+type Server struct {
+	URL string
+
+	mu         sync.Mutex
+	messages   []Message
+	webhookURL string
+	secret     string
+	http       *httptest.Server
+}
+
+func New() *Server {
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /bot"+Token+"/sendMessage", s.sendMessage)
+	mux.HandleFunc("POST /bot"+Token+"/setWebhook", s.setWebhook)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusUnauthorized, "Unauthorized")
+	})
+	s.http = httptest.NewServer(mux)
+	s.URL = s.http.URL
+	return s
+}
+
+func (s *Server) Close() {
+	s.http.Close()
+}
+
+// Messages возвращает отправленные сообщения в порядке отправки.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Webhook возвращает адрес и секрет из последнего setWebhook.
+func (s *Server) Webhook() (url, secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.webhookURL, s.secret
+}
+
+func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID          int64  `json:"chat_id"`
+		Text            string `json:"text"`
+		ReplyParameters struct {
+			MessageID int64 `json:"message_id"`
+		} `json:"reply_parameters"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChatID == 0 || req.Text == "" {
+		reply(w, http.StatusBadRequest, "Bad Request: chat_id and text are required")
+		return
+	}
+	s.mu.Lock()
+	s.messages = append(s.messages, Message{ChatID: req.ChatID, ReplyTo: req.ReplyParameters.MessageID, Text: req.Text})
+	s.mu.Unlock()
+	reply(w, http.StatusOK, "")
+}
+
+func (s *Server) setWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL         string `json:"url"`
+		SecretToken string `json:"secret_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+		reply(w, http.StatusBadRequest, "Bad Request: url is required")
+		return
+	}
+	s.mu.Lock()
+	s.webhookURL, s.secret = req.URL, req.SecretToken
+	s.mu.Unlock()
+	reply(w, http.StatusOK, "")
+}
+
+// reply отвечает в формате Bot API: {"ok": true, "result": true} или описание ошибки.
+func reply(w http.ResponseWriter, status int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if status == http.StatusOK {
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": true})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": status, "description": description})
+}
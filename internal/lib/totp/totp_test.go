@@ -0,0 +1,100 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret - ключ "12345678901234567890" из тестовых векторов RFC 6238 (SHA-1).
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// В RFC коды из 8 цифр, у нас их последние 6.
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+		if err != nil || got != want {
+			t.Errorf("Code at %d = %q, %v, want %q", unix, got, err, want)
+		}
+	}
+	if lower, _ := Code(strings.ToLower(rfcSecret), 1); lower != mustCode(t, rfcSecret, 1) {
+		t.Error("Code depends on secret case")
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted malformed secret")
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	now := time.Unix(1_700_000_015, 0)
+	step := Step(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantOK   bool
+		wantStep int64
+	}{
+		{"current", mustCode(t, rfcSecret, step), true, step},
+		{"previous", mustCode(t, rfcSecret, step-1), true, step - 1},
+		{"next", mustCode(t, rfcSecret, step+1), true, step + 1},
+		{"with spaces", " " + mustCode(t, rfcSecret, step) + " ", true, step},
+		{"two steps ago", mustCode(t, rfcSecret, step-2), false, 0},
+		{"two steps ahead", mustCode(t, rfcSecret, step+2), false, 0},
+		{"short", "12345", false, 0},
+		{"recovery code", "abcde-fghij", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := Validate(rfcSecret, tt.code, now)
+			if err != nil || ok != tt.wantOK || got != tt.wantStep {
+				t.Errorf("Validate = %d, %v, %v, want %d, %v", got, ok, err, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	b, _ := GenerateSecret()
+	if a == b || len(a) != 32 || strings.Contains(a, "=") {
+		t.Errorf("GenerateSecret = %q, %q", a, b)
+	}
+	if _, err := Code(a, 1); err != nil {
+		t.Errorf("Code with generated secret: %v", err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("My SSO", "@durov", rfcSecret))
+	if err != nil {
+		t.Fatalf("URI: %v", err)
+	}
+	q := uri.Query()
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/My SSO:@durov" ||
+		q.Get("secret") != rfcSecret || q.Get("issuer") != "My SSO" ||
+		q.Get("digits") != "6" || q.Get("period") != "30" || q.Get("algorithm") != "SHA1" {
+		t.Errorf("unexpected URI %s", uri)
+	}
+}
+
+func mustCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	code, err := Code(secret, step)
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	return code
+}
@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// AccountStore - выгрузка данных пользователя и удаление аккаунта.
+type AccountStore interface {
+	SessionHistory(ctx context.Context, tgHash string) ([]models.Session, error)
+	AppMemberships(ctx context.Context, tgHash string) ([]models.Membership, error)
+	ScheduleErasure(ctx context.Context, tgHash string, eraseAt *time.Time) error
+	ClaimErasures(ctx context.Context, now time.Time, limit int) ([]string, error)
+	EraseUser(ctx context.Context, tgHash, pseudonym string, now time.Time, events ...models.OutboxEvent) error
+}
+
+// Export собирает все, что сервис хранит о владельце токена.
+func (a Auth) Export(ctx context.Context, token string) (export models.Export, err error) {
+	ctx, span := tracing.Start(ctx, "auth.Export")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.userClaims(ctx, token)
+	if err != nil {
+		return models.Export{}, fmt.Errorf("app.Export: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditDataExport, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	user, err := a.userProvider.User(ctx, sub)
+	if err != nil {
+		return models.Export{}, fmt.Errorf("app.Export: %w", err)
+	}
+	identities, err := a.identities.Identities(ctx, sub)
+	if err != nil {
+		return models.Export{}, fmt.Errorf("app.Export: %w", err)
+	}
+	sessions, err := a.accounts.SessionHistory(ctx, sub)
+	if err != nil {
+		return models.Export{}, fmt.Errorf("app.Export: %w", err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+	memberships, err := a.memberships(ctx, sub, sessions)
+	if err != nil {
+		return models.Export{}, fmt.Errorf("app.Export: %w", err)
+	}
+
+	events := []models.AuditEvent{}
+	filter := models.AuditFilter{User: sub, Limit: MaxAuditPage}
+	for {
+		page, err := a.audit.AuditEvents(ctx, filter)
+		if err != nil {
+			return models.Export{}, fmt.Errorf("app.Export: %w", err)
+		}
+		events = append(events, page...)
+		if len(page) < filter.Limit {
+			break
+		}
+		filter.AfterID = page[len(page)-1].ID
+	}
+
+	return models.Export{
+		User:        user,
+		Roles:       user.Roles(),
+		Identities:  identities,
+		Sessions:    sessions,
+		Memberships: memberships,
+		AuditEvents: events,
+		ExportedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// memberships перечисляет приложения из app_members - все, в которые пользователь
+// входил, даже если сессий там уже нет. Последний вход берется из сессий, а без
+// них совпадает с первым.
+func (a Auth) memberships(ctx context.Context, sub string, sessions []models.Session) ([]models.Membership, error) {
+	memberships, err := a.accounts.AppMemberships(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	lastSeen := make(map[int64]time.Time)
+	for _, session := range sessions {
+		if session.LastSeenAt.After(lastSeen[session.AppID]) {
+			lastSeen[session.AppID] = session.LastSeenAt
+		}
+	}
+
+	for i := range memberships {
+		m := &memberships[i]
+		m.LastSeenAt = m.FirstSeenAt
+		if seen, ok := lastSeen[m.AppID]; ok && seen.After(m.LastSeenAt) {
+			m.LastSeenAt = seen
+		}
+		app, err := a.appProvider.App(ctx, m.AppID)
+		if err != nil && !errors.Is(err, storage.ErrAppNotFound) {
+			return nil, err
+		}
+		m.AppName = app.Name
+	}
+	return memberships, nil
+}
+
+// RequestDeletion планирует удаление аккаунта владельца токена через срок
+// erasureGrace. Повторный запрос возвращает уже назначенную дату.
+func (a Auth) RequestDeletion(ctx context.Context, token string) (erasure models.Erasure, err error) {
+	ctx, span := tracing.Start(ctx, "auth.RequestDeletion")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.userClaims(ctx, token)
+	if err != nil {
+		return models.Erasure{}, fmt.Errorf("app.RequestDeletion: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditDeletionRequested, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	user, err := a.userProvider.User(ctx, sub)
+	if err != nil {
+		return models.Erasure{}, fmt.Errorf("app.RequestDeletion: %w", err)
+	}
+	if user.EraseAt != nil {
+		return models.Erasure{EraseAt: *user.EraseAt}, nil
+	}
+
+	eraseAt := time.Now().UTC().Add(a.erasureGrace)
+	if err := a.accounts.ScheduleErasure(ctx, sub, &eraseAt); err != nil {
+		return models.Erasure{}, fmt.Errorf("app.RequestDeletion: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "account deletion scheduled",
+		slog.String("op", "app.RequestDeletion"), slog.Time("erase_at", eraseAt))
+	return models.Erasure{EraseAt: eraseAt}, nil
+}
+
+// CancelDeletion отменяет запланированное удаление аккаунта владельца токена.
+func (a Auth) CancelDeletion(ctx context.Context, token string) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.CancelDeletion")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.userClaims(ctx, token)
+	if err != nil {
+		return fmt.Errorf("app.CancelDeletion: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditDeletionCanceled, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	if err := a.accounts.ScheduleErasure(ctx, sub, nil); err != nil {
+		return fmt.Errorf("app.CancelDeletion: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "account deletion canceled", slog.String("op", "app.CancelDeletion"))
+	return nil
+}
+
+// EraseDue удаляет до limit аккаунтов, срок удаления которых наступил, и
+// возвращает число удаленных. Ошибка одного аккаунта не останавливает остальные,
+// а сам он уходит в конец очереди.
+func (a Auth) EraseDue(ctx context.Context, limit int) (int, error) {
+	subs, err := a.accounts.ClaimErasures(ctx, time.Now().UTC(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("app.EraseDue: %w", err)
+	}
+	erased := 0
+	for _, sub := range subs {
+		if err := a.erase(ctx, sub); err != nil {
+			sl.FromContext(ctx, a.log).ErrorContext(ctx, "failed to erase account", slog.String("op", "app.EraseDue"), sl.Err(err))
+			continue
+		}
+		erased++
+	}
+	return erased, nil
+}
+
+// erase удаляет аккаунт sub: сессии вместе с их токенами и профиль. В журнале
+// аудита, app_members и событиях outbox sub заменяется случайным псевдонимом.
+// Событие user.deleted пишется уже от псевдонима, поэтому по app_members его
+// получают все приложения, в которые пользователь входил. Настоящий sub остается
+// только в его данных: без него приложения не узнают, чьи данные удалить.
+func (a Auth) erase(ctx context.Context, sub string) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.erase")
+	defer func() { tracing.End(span, err) }()
+
+	pseudonym := models.DeletedPrefix + newSessionID()
+	defer func() {
+		metrics.AccountErasures.WithLabelValues(metrics.Outcome(err)).Inc()
+		actor := pseudonym
+		if err != nil {
+			actor = sub
+		}
+		a.record(ctx, models.AuditEvent{Type: models.AuditAccountErased, Actor: actor, Subject: actor}, err)
+	}()
+
+	deleted := newEvent(models.EventUserDeleted, pseudonym, 0, models.UserEvent{Sub: sub})
+	if err := a.accounts.EraseUser(ctx, sub, pseudonym, time.Now().UTC(), deleted); err != nil {
+		return fmt.Errorf("app.erase: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "account erased", slog.String("op", "app.erase"))
+	return nil
+}
+
+// userClaims проверяет токен и требует, чтобы он был пользовательским.
+func (a Auth) userClaims(ctx context.Context, token string) (jwt.Claims, error) {
+	claims, err := a.verifyToken(ctx, token)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+	if claims.SubjectType != models.SubjectUser {
+		return jwt.Claims{}, fmt.Errorf("%w: not a user token", ErrInvalidToken)
+	}
+	return claims, nil
+}
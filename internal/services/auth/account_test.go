@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"auth-service/internal/storage/memory"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// brokenErasure - хранилище, в котором удаление одного аккаунта всегда падает.
+type brokenErasure struct {
+	*memory.Storage
+	broken string
+}
+
+func (s brokenErasure) EraseUser(ctx context.Context, tgHash, pseudonym string, now time.Time, events ...models.OutboxEvent) error {
+	if tgHash == s.broken {
+		return errors.New("erase failed")
+	}
+	return s.Storage.EraseUser(ctx, tgHash, pseudonym, now, events...)
+}
+
+func TestEraseDueSkipsFailingAccount(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+	a := newTestAuth(st, Config{TokenTTL: time.Hour})
+	a.accounts = brokenErasure{Storage: st, broken: "broken"}
+
+	// Падающий аккаунт стоит первым в очереди и не должен ее задерживать.
+	now := time.Now().UTC()
+	for i, sub := range []string{"broken", "second", "third"} {
+		if err := st.SaveUser(ctx, sub, models.User{FirstName: sub}); err != nil {
+			t.Fatalf("SaveUser: %v", err)
+		}
+		eraseAt := now.Add(time.Duration(i-10) * time.Minute)
+		if err := st.ScheduleErasure(ctx, sub, &eraseAt); err != nil {
+			t.Fatalf("ScheduleErasure: %v", err)
+		}
+	}
+
+	erased := 0
+	for range 4 {
+		n, err := a.EraseDue(ctx, 1)
+		if err != nil {
+			t.Fatalf("EraseDue: %v", err)
+		}
+		erased += n
+	}
+	if erased != 2 {
+		t.Errorf("erased %d accounts, want 2", erased)
+	}
+	for _, sub := range []string{"second", "third"} {
+		if _, err := st.User(ctx, sub); !errors.Is(err, storage.ErrUserNotFound) {
+			t.Errorf("User(%s) after EraseDue error = %v, want %v", sub, err, storage.ErrUserNotFound)
+		}
+	}
+	if _, err := st.User(ctx, "broken"); err != nil {
+		t.Errorf("User(broken) after failed erasure: %v", err)
+	}
+}
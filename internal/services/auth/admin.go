@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/tracing"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// UserInfo возвращает пользователя по sub из его токенов вместе с ролями.
+func (a Auth) UserInfo(ctx context.Context, sub string) (info models.UserInfo, err error) {
+	ctx, span := tracing.Start(ctx, "auth.UserInfo")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := a.userProvider.User(ctx, sub)
+	if err != nil {
+		return models.UserInfo{}, fmt.Errorf("app.UserInfo: %w", err)
+	}
+	return models.UserInfo{User: user.Response(), Roles: user.Roles()}, nil
+}
+
+// SetBanned банит или разбанивает пользователя по sub от имени приложения caller.
+func (a Auth) SetBanned(ctx context.Context, caller models.Caller, sub string, banned bool) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.SetBanned")
+	defer func() { tracing.End(span, err) }()
+	defer func() {
+		eventType := models.AuditUnban
+		if banned {
+			eventType = models.AuditBan
+		}
+		a.record(ctx, models.AuditEvent{Type: eventType, Actor: jwt.AppSubject(caller.AppID), Subject: sub, AppID: caller.AppID}, err)
+	}()
+
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", "app.SetBanned"), slog.Int64("caller_app", caller.AppID))
+
+	if _, err := a.setBanned(ctx, sub, banned, caller.AppID); err != nil {
+		return fmt.Errorf("app.SetBanned: %w", err)
+	}
+	log.InfoContext(ctx, "ban status changed", slog.Bool("banned", banned))
+	return nil
+}
+
+// setBanned меняет статус бана и пишет событие user.banned или user.updated
+// от имени приложения appID (0 - не из приложения).
+func (a Auth) setBanned(ctx context.Context, sub string, banned bool, appID int64) (models.User, error) {
+	user, err := a.userProvider.User(ctx, sub)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.IsBanned = banned
+	eventType := models.EventUserUpdated
+	if banned {
+		eventType = models.EventUserBanned
+	}
+	if err := a.userSaver.SetBanned(ctx, sub, banned, newEvent(eventType, sub, appID, userEvent(sub, user))); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
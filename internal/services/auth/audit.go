@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/clientinfo"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// MaxAuditPage - наибольший размер страницы журнала аудита.
+const MaxAuditPage = 1000
+
+type AuditLog interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+	AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+}
+
+// record пишет событие аудита с исходом операции err. Ошибка записи журнала
+// только логируется: недоступный журнал не должен останавливать вход.
+func (a Auth) record(ctx context.Context, event models.AuditEvent, err error) {
+	event.IP = clientinfo.From(ctx).IP
+	event.CreatedAt = time.Now().UTC()
+	event.Outcome = models.AuditSuccess
+	if err != nil {
+		event.Outcome = models.AuditFailure
+		event.Reason = auditReason(err)
+	}
+	if err := a.audit.SaveAuditEvent(ctx, event); err != nil {
+		sl.FromContext(ctx, a.log).ErrorContext(ctx, "failed to write audit event",
+			slog.String("type", event.Type), sl.Err(err))
+	}
+}
+
+// AuditEvents возвращает страницу журнала аудита.
+func (a Auth) AuditEvents(ctx context.Context, filter models.AuditFilter) (events []models.AuditEvent, err error) {
+	ctx, span := tracing.Start(ctx, "auth.AuditEvents")
+	defer func() { tracing.End(span, err) }()
+
+	if filter.Limit <= 0 || filter.Limit > MaxAuditPage {
+		filter.Limit = MaxAuditPage
+	}
+	events, err = a.audit.AuditEvents(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("app.AuditEvents: %w", err)
+	}
+	return events, nil
+}
+
+// auditReason переводит ошибку операции в причину события - те же коды, что в /v1.
+func auditReason(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidInitData):
+		return "invalid_init_data"
+	case errors.Is(err, ErrInvalidApp), errors.Is(err, storage.ErrAppNotFound):
+		return "unknown_service"
+	case errors.Is(err, storage.ErrUserNotFound):
+		return "user_not_found"
+	case errors.Is(err, storage.ErrUserBanned):
+		return "user_banned"
+	case errors.Is(err, storage.ErrUserExist):
+		return "user_exists"
+	case errors.Is(err, storage.ErrSessionNotFound):
+		return "session_not_found"
+	case errors.Is(err, storage.ErrHandoffNotFound):
+		return "handoff_not_found"
+	case errors.Is(err, storage.ErrIdentityNotFound):
+		return "identity_not_found"
+	case errors.Is(err, storage.ErrIdentityExists):
+		return "identity_exists"
+	case errors.Is(err, ErrUnknownProvider):
+		return "unknown_provider"
+	case errors.Is(err, ErrInvalidIDToken):
+		return "invalid_id_token"
+	case errors.Is(err, storage.ErrEmailTokenNotFound):
+		return "email_token_not_found"
+	case errors.Is(err, ErrInvalidEmail):
+		return "invalid_email"
+	case errors.Is(err, ErrWeakPassword):
+		return "weak_password"
+	case errors.Is(err, ErrEmailNotVerified):
+		return "email_not_verified"
+	case errors.Is(err, ErrAccountLocked):
+		return "account_locked"
+	case errors.Is(err, ErrLastIdentity):
+		return "last_identity"
+	case errors.Is(err, ErrStepUpRequired):
+		return "step_up_required"
+	case errors.Is(err, ErrReauthRequired):
+		return "reauth_required"
+	case errors.Is(err, ErrInvalidOTP):
+		return "invalid_otp"
+	case errors.Is(err, storage.ErrTOTPNotFound):
+		return "totp_not_found"
+	case errors.Is(err, storage.ErrTOTPExists):
+		return "totp_exists"
+	case errors.Is(err, ErrInvalidCredentials):
+		return "unauthorized"
+	case errors.Is(err, ErrInvalidClient):
+		return "invalid_client"
+	case errors.Is(err, ErrInvalidScope):
+		return "invalid_scope"
+	case errors.Is(err, ErrInvalidGrant):
+		return "invalid_grant"
+	case errors.Is(err, ErrInvalidTarget):
+		return "invalid_target"
+	case errors.Is(err, ErrInvalidToken):
+		return "invalid_token"
+	case errors.Is(err, ErrForbidden):
+		return "forbidden"
+	default:
+		return "internal"
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"auth-service/internal/lib/crypto"
 	"auth-service/internal/lib/jwt"
 	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/tracing"
 	"auth-service/internal/storage"
 	"context"
 	"errors"
@@ -18,20 +20,42 @@ import (
 )
 
 type Auth struct {
-	log          *slog.Logger
-	userSaver    UserSaver
-	userProvider UserProvider
-	appProvider  AppProvider
-	tokenTTL     time.Duration
-	tgToken      string
+	log             *slog.Logger
+	userSaver       UserSaver
+	userProvider    UserProvider
+	appProvider     AppProvider
+	sessions        SessionStore
+	audit           AuditLog
+	outbox          OutboxStore
+	accounts        AccountStore
+	handoffs        HandoffStore
+	identities      IdentityStore
+	passwords       PasswordStore
+	factors         TwoFactorStore
+	tokenTTL        time.Duration
+	serviceTokenTTL time.Duration
+	erasureGrace    time.Duration
+	exchange        ExchangePolicy
+	handoff         HandoffPolicy
+	password        PasswordPolicy
+	twoFactor       TwoFactorPolicy
+	providers       map[string]IdentityVerifier
+	mailer          Mailer
+	tgToken         string
+	keys            *jwt.Keys
 }
 type UserSaver interface {
-	SaveUser(ctx context.Context, tgId string, User models.User) error
+	SaveUser(ctx context.Context, tgId string, User models.User, events ...models.OutboxEvent) error
+	SetBanned(ctx context.Context, tgHash string, banned bool, events ...models.OutboxEvent) error
+	SetAdmin(ctx context.Context, tgHash string, admin bool, events ...models.OutboxEvent) error
+	UpdateProfile(ctx context.Context, tgHash string, profile models.User, events ...models.OutboxEvent) (bool, error)
 }
 
 type UserProvider interface {
 	IsAdmin(ctx context.Context, tgId string) (isAdmin bool, err error)
 	ValidateUser(ctx context.Context, userHash string) (models.UserResponse, error)
+	User(ctx context.Context, tgHash string) (models.User, error)
+	UserByUsername(ctx context.Context, username string) (models.User, error)
 }
 type AppProvider interface {
 	App(ctx context.Context, serviceId int64) (models.App, error)
@@ -40,42 +64,163 @@ type AppProvider interface {
 var (
 	ErrInvalidCredentials = errors.New("invalid Credentials")
 	ErrInvalidApp         = errors.New("invalid App")
+	ErrInvalidInitData    = errors.New("invalid initData")
+	ErrInvalidToken       = errors.New("invalid token")
 )
 
-func New(log *slog.Logger, userSaver UserSaver, userProvider UserProvider, appProvider AppProvider, tokenTTL time.Duration, tgToken string) *Auth {
+// Deps - зависимости сервиса. Обычно все хранилища - одно storage.Storage,
+// но сервис видит его только через узкие интерфейсы. Providers и Mailer
+// нужны, только если включены внешние учетные записи и вход по email.
+type Deps struct {
+	Log          *slog.Logger
+	UserSaver    UserSaver
+	UserProvider UserProvider
+	AppProvider  AppProvider
+	Sessions     SessionStore
+	Audit        AuditLog
+	Outbox       OutboxStore
+	Accounts     AccountStore
+	Handoffs     HandoffStore
+	Identities   IdentityStore
+	Passwords    PasswordStore
+	Factors      TwoFactorStore
+	Providers    map[string]IdentityVerifier
+	Mailer       Mailer
+}
+
+// Config - настройки сервиса. ErasureGrace - через сколько после запроса
+// удаляется аккаунт, BotToken проверяет подпись initData. Keys подписывают
+// токены ES256, без них токены подписываются HS256 секретом приложения.
+type Config struct {
+	TokenTTL        time.Duration
+	ServiceTokenTTL time.Duration
+	ErasureGrace    time.Duration
+	Exchange        ExchangePolicy
+	Handoff         HandoffPolicy
+	Password        PasswordPolicy
+	TwoFactor       TwoFactorPolicy
+	BotToken        string
+	Keys            *jwt.Keys
+}
 
+func New(deps Deps, cfg Config) *Auth {
 	return &Auth{
-		log, userSaver, userProvider, appProvider, tokenTTL, tgToken,
+		log:             deps.Log,
+		userSaver:       deps.UserSaver,
+		userProvider:    deps.UserProvider,
+		appProvider:     deps.AppProvider,
+		sessions:        deps.Sessions,
+		audit:           deps.Audit,
+		outbox:          deps.Outbox,
+		accounts:        deps.Accounts,
+		handoffs:        deps.Handoffs,
+		identities:      deps.Identities,
+		passwords:       deps.Passwords,
+		factors:         deps.Factors,
+		providers:       deps.Providers,
+		mailer:          deps.Mailer,
+		tokenTTL:        cfg.TokenTTL,
+		serviceTokenTTL: cfg.ServiceTokenTTL,
+		erasureGrace:    cfg.ErasureGrace,
+		exchange:        cfg.Exchange,
+		handoff:         cfg.Handoff,
+		password:        cfg.Password,
+		twoFactor:       cfg.TwoFactor,
+		tgToken:         cfg.BotToken,
+		keys:            cfg.Keys,
 	}
 }
 
-func (a Auth) ValidateUser(ctx context.Context, userHash string, serviceId int64) (models.UserResponse, string, error) {
-	log := a.log.With(slog.String("op", "app.ValidateUser"))
+func (a Auth) ValidateUser(ctx context.Context, userHash string, serviceId int64) (user models.UserResponse, token string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.ValidateUser")
+	defer func() { tracing.End(span, err) }()
 
-	log.Info("валидация пользователя")
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", "app.ValidateUser"))
+	var tgHash string
+	defer func() {
+		metrics.Logins.WithLabelValues(metrics.Outcome(err)).Inc()
+		a.record(ctx, models.AuditEvent{Type: models.AuditLogin, Actor: tgHash, Subject: tgHash, AppID: serviceId}, err)
+	}()
+
+	log.InfoContext(ctx, "валидация пользователя")
 	//expIn := 24 * time.Hour
 	if err := initdata.Validate(userHash, a.tgToken, 0); err != nil {
-		return models.UserResponse{}, "", fmt.Errorf("validate user: %w", err)
+		metrics.ValidationFailures.WithLabelValues(validationReason(err)).Inc()
+		return models.UserResponse{}, "", fmt.Errorf("validate user: %w: %w", ErrInvalidInitData, err)
 	}
 	userDecodeHash, err := initdata.Parse(userHash)
+	if err != nil {
+		metrics.ValidationFailures.WithLabelValues(validationReason(err)).Inc()
+		return models.UserResponse{}, "", fmt.Errorf("parse user: %w: %w", ErrInvalidInitData, err)
+	}
 
 	app, err := a.appProvider.App(ctx, serviceId)
-	tgHash, err := crypto.HashTgID(userDecodeHash.User.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.UserResponse{}, "", fmt.Errorf("app.ValidateUser: %w", ErrInvalidApp)
+		}
+		return models.UserResponse{}, "", err
+	}
+	tgHash, err = crypto.HashTgID(userDecodeHash.User.ID)
 
 	if err != nil {
 		return models.UserResponse{}, "", fmt.Errorf("Ошибка хеширования: %w", err)
 	}
-	user, err := a.userProvider.ValidateUser(ctx, tgHash)
+	user, err = a.userProvider.ValidateUser(ctx, tgHash)
+	if err != nil {
+		return models.UserResponse{}, "", err
+	}
+	user = a.syncProfile(ctx, user, userDecodeHash.User, serviceId)
+	token, err = a.issueToken(ctx, tgHash, app)
 	if err != nil {
 		return models.UserResponse{}, "", err
 	}
-	token, err := jwt.NewToken(tgHash, app, a.tokenTTL)
+	return user, token, nil
+}
 
+// issueToken начинает сессию пользователя tgHash в приложении app и выпускает
+// для нее токен.
+func (a Auth) issueToken(ctx context.Context, tgHash string, app models.App) (string, error) {
+	sessionID, err := a.startSession(ctx, tgHash, app)
 	if err != nil {
-		return models.UserResponse{}, "", fmt.Errorf("Ошибка генерации токена: %w", err)
+		return "", err
+	}
+	token, err := jwt.NewToken(a.keys, tgHash, app, sessionID, a.tokenTTL)
+	if err != nil {
+		return "", fmt.Errorf("Ошибка генерации токена: %w", err)
+	}
+	metrics.TokensIssued.WithLabelValues(app.Name).Inc()
+	return token, nil
+}
 
+// syncProfile обновляет имя, username и фото пользователя, если в initData они
+// изменились, и публикует user.updated. Ошибка только логируется: устаревший
+// профиль не повод отказывать во входе.
+func (a Auth) syncProfile(ctx context.Context, user models.UserResponse, tg initdata.User, serviceId int64) models.UserResponse {
+	if user.FirstName == tg.FirstName && user.LastName == tg.LastName &&
+		user.Username == tg.Username && user.PhotoURL == tg.PhotoURL {
+		return user
 	}
-	return user, token, nil
+	updated := user
+	updated.FirstName, updated.LastName, updated.Username, updated.PhotoURL = tg.FirstName, tg.LastName, tg.Username, tg.PhotoURL
+	profile := models.User{
+		FirstName:      updated.FirstName,
+		LastName:       updated.LastName,
+		Username:       updated.Username,
+		UserNameLocale: updated.UserNameLocale,
+		PhotoURL:       updated.PhotoURL,
+	}
+
+	changed, err := a.userSaver.UpdateProfile(ctx, user.TgId, profile,
+		newEvent(models.EventUserUpdated, user.TgId, serviceId, userEvent(user.TgId, profile)))
+	if err != nil {
+		sl.FromContext(ctx, a.log).WarnContext(ctx, "failed to update profile", sl.Err(err))
+		return user
+	}
+	if !changed {
+		return user
+	}
+	return updated
 }
 
 //func (s *Storage) User(ctx context.Context, userHash string) (models.User, error) {
@@ -96,27 +241,45 @@ func (a Auth) ValidateUser(ctx context.Context, userHash string, serviceId int64
 //	return user, nil
 //}
 
-func (a Auth) RegisterUser(ctx context.Context, userHash string, userNameLocale string, serviceId int64) (string, error) {
+func (a Auth) RegisterUser(ctx context.Context, userHash string, userNameLocale string, serviceId int64) (token string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.RegisterUser")
+	defer func() { tracing.End(span, err) }()
 
-	log := a.log.With(slog.String("op", "app.RegisterUser"), slog.Int("serviceId", int(serviceId)))
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", "app.RegisterUser"))
+	var tgHash string
+	defer func() {
+		metrics.Registrations.WithLabelValues(metrics.Outcome(err)).Inc()
+		a.record(ctx, models.AuditEvent{Type: models.AuditRegister, Actor: tgHash, Subject: tgHash, AppID: serviceId}, err)
+	}()
 
-	log.Info("Регистрация")
+	log.InfoContext(ctx, "Регистрация")
 
-	err := initdata.Validate(userHash, a.tgToken, 0)
+	err = initdata.Validate(userHash, a.tgToken, 0)
 	if err != nil {
-		log.Error("Ошибка валидации", err)
-		return "", status.Errorf(codes.Unauthenticated, "Токен не прошел валидацию")
+		metrics.ValidationFailures.WithLabelValues(validationReason(err)).Inc()
+		log.ErrorContext(ctx, "Ошибка валидации", sl.Err(err))
+		return "", fmt.Errorf("Токен не прошел валидацию: %w: %w", ErrInvalidInitData, err)
 	}
 	userDecodeHash, err := initdata.Parse(userHash)
 
 	if err != nil {
-		log.Error("Ошибка десереализации")
+		metrics.ValidationFailures.WithLabelValues(validationReason(err)).Inc()
+		log.ErrorContext(ctx, "Ошибка десереализации", sl.Err(err))
+		return "", fmt.Errorf("parse user: %w: %w", ErrInvalidInitData, err)
+	}
+
+	app, err := a.appProvider.App(ctx, serviceId)
+	if err != nil {
+		log.ErrorContext(ctx, "Ошибка получения приложения", sl.Err(err))
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return "", fmt.Errorf("app.RegisterUser: %w", ErrInvalidApp)
+		}
 		return "", status.Errorf(codes.Internal, "internal error")
 	}
 
-	tgHash, err := crypto.HashTgID(userDecodeHash.User.ID)
+	tgHash, err = crypto.HashTgID(userDecodeHash.User.ID)
 	if err != nil {
-		log.Error("ошибка хеширования тг айди", sl.Err(err))
+		log.ErrorContext(ctx, "ошибка хеширования тг айди", sl.Err(err))
 
 		return "", status.Errorf(codes.Internal, "Ошибка хеширования")
 	}
@@ -129,50 +292,185 @@ func (a Auth) RegisterUser(ctx context.Context, userHash string, userNameLocale
 		Username:       userDecodeHash.User.Username,
 		IsAdmin:        false,
 	}
-	err = a.userSaver.SaveUser(ctx, tgHash, User)
+	err = a.userSaver.SaveUser(ctx, tgHash, User, newEvent(models.EventUserRegistered, tgHash, serviceId, userEvent(tgHash, User)))
 	if err != nil {
-		log.Error("Ошибка сохранениня юзера", sl.Err(err))
+		log.ErrorContext(ctx, "Ошибка сохранениня юзера", sl.Err(err))
 
-		if errors.As(err, &storage.ErrUserExist) {
+		if errors.Is(err, storage.ErrUserExist) {
 			return "", err
 		}
 		return "", status.Errorf(codes.Internal, "internal error")
 	}
 
-	app, err := a.appProvider.App(ctx, serviceId)
+	log.InfoContext(ctx, "Пользователь зарегистрирован")
 
-	log.Info("Пользователь зарегистрирован")
-
-	token, err := jwt.NewToken(User.ID, app, a.tokenTTL)
+	sessionID, err := a.startSession(ctx, tgHash, app)
+	if err != nil {
+		log.ErrorContext(ctx, "Ошибка создания сессии", sl.Err(err))
+		return "", status.Errorf(codes.Internal, "internal error")
+	}
+	token, err = jwt.NewToken(a.keys, User.ID, app, sessionID, a.tokenTTL)
 	if err != nil {
-		log.Error("Ошибка генерации токена", sl.Err(err))
-		status.Errorf(codes.Internal, "Ошибка генерации токена")
+		log.ErrorContext(ctx, "Ошибка генерации токена", sl.Err(err))
+		return "", status.Errorf(codes.Internal, "Ошибка генерации токена")
 	}
-	log.Info("asdasddasdds")
+	metrics.TokensIssued.WithLabelValues(app.Name).Inc()
 	return token, nil
 }
-func (a Auth) IsAdmin(ctx context.Context, initData string) (bool, error) {
-	log := a.log.With(slog.String("op", "app.IsAdmin"))
 
-	log.Info("authorise user")
-	err := initdata.Validate(initData, a.tgToken, 0)
+// IsAdmin проверяет initData и сообщает, администратор ли пользователь.
+// Администратору со вторым фактором нужен еще otp - код TOTP или код
+// восстановления, без него возвращается ErrStepUpRequired.
+func (a Auth) IsAdmin(ctx context.Context, initData, otp string) (isAdmin bool, err error) {
+	ctx, span := tracing.Start(ctx, "auth.IsAdmin")
+	defer func() { tracing.End(span, err) }()
+
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", "app.IsAdmin"))
+	defer func() {
+		result := "not_admin"
+		switch {
+		case err != nil:
+			result = "error"
+		case isAdmin:
+			result = "admin"
+		}
+		metrics.AdminChecks.WithLabelValues(result).Inc()
+	}()
+
+	log.InfoContext(ctx, "authorise user")
+	err = initdata.Validate(initData, a.tgToken, 0)
 	if err != nil {
-		log.Error("Ошибка валидации", err)
-		return false, fmt.Errorf("Токен не прошел валидацию: %w", err)
+		metrics.ValidationFailures.WithLabelValues(validationReason(err)).Inc()
+		log.ErrorContext(ctx, "Ошибка валидации", sl.Err(err))
+		return false, fmt.Errorf("Токен не прошел валидацию: %w: %w", ErrInvalidInitData, err)
 	}
 	userDecodeHash, err := initdata.Parse(initData)
+	if err != nil {
+		metrics.ValidationFailures.WithLabelValues(validationReason(err)).Inc()
+		return false, fmt.Errorf("parse user: %w: %w", ErrInvalidInitData, err)
+	}
 	tgHash, err := crypto.HashTgID(userDecodeHash.User.ID)
-	isAdmin, err := a.userProvider.IsAdmin(ctx, tgHash)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка хеширования: %w", err)
+	}
+	isAdmin, err = a.userProvider.IsAdmin(ctx, tgHash)
 
 	if err != nil {
-		if errors.Is(err, storage.ErrAppNotFound) {
-			log.Warn("user not found", sl.Err(err))
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.WarnContext(ctx, "user not found", sl.Err(err))
 
-			return false, fmt.Errorf("app.IsAdmin, %w", ErrInvalidApp)
+			return false, fmt.Errorf("app.IsAdmin, %w", err)
 		}
-		log.Error("Failed to authorise user", sl.Err(err))
+		log.ErrorContext(ctx, "Failed to authorise user", sl.Err(err))
 		return false, fmt.Errorf("app.IsAdmin, %w", err)
 	}
+	if isAdmin {
+		if err := a.adminCheckStepUp(ctx, tgHash, otp); err != nil {
+			return false, fmt.Errorf("app.IsAdmin: %w", err)
+		}
+	}
 
 	return isAdmin, nil
 }
+
+// Me проверяет токен, выпущенный NewToken, секретом приложения из claim serviceID
+// и возвращает профиль, роли и статус бана. Забаненный пользователь не считается
+// ошибкой: решение остается за вызывающим сервисом. Роль admin выдается, только
+// если сессия токена недавно подтвердила второй фактор (см. TwoFactorPolicy).
+func (a Auth) Me(ctx context.Context, token string) (me models.Me, err error) {
+	ctx, span := tracing.Start(ctx, "auth.Me")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.verifyToken(ctx, token)
+	if err != nil {
+		return models.Me{}, fmt.Errorf("app.Me: %w", err)
+	}
+	if claims.SubjectType != models.SubjectUser {
+		return models.Me{}, fmt.Errorf("app.Me: %w: not a user token", ErrInvalidToken)
+	}
+
+	user, err := a.userProvider.User(ctx, claims.Subject)
+	if err != nil {
+		return models.Me{}, fmt.Errorf("app.Me: %w", err)
+	}
+	me = models.Me{User: user.Response(), Roles: user.Roles(), ServiceID: claims.ServiceID, EraseAt: user.EraseAt}
+
+	_, required, err := a.adminStepUp(ctx, claims.Subject, user.IsAdmin)
+	if err != nil {
+		return models.Me{}, fmt.Errorf("app.Me: %w", err)
+	}
+	if required {
+		steppedUp, err := a.sessionSteppedUp(ctx, claims.SessionID)
+		if err != nil {
+			return models.Me{}, fmt.Errorf("app.Me: %w", err)
+		}
+		if !steppedUp {
+			me.Roles, me.StepUpRequired = []string{models.RoleUser}, true
+		}
+	}
+	return me, nil
+}
+
+// verifyToken находит приложение по claim serviceID, проверяет токен ключами
+// SSO или секретом приложения и то, что сессия токена не завершена.
+func (a Auth) verifyToken(ctx context.Context, token string) (jwt.Claims, error) {
+	serviceId, err := jwt.ServiceID(token)
+	if err != nil {
+		return jwt.Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	app, err := a.appProvider.App(ctx, serviceId)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return jwt.Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+		return jwt.Claims{}, err
+	}
+	claims, err := jwt.ValidateToken(token, app, a.keys)
+	if err != nil {
+		sl.FromContext(ctx, a.log).WarnContext(ctx, "token rejected", slog.Int64("serviceId", serviceId), sl.Err(err))
+		return jwt.Claims{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if err := a.checkSession(ctx, claims.Subject, claims.SessionID); err != nil {
+		return jwt.Claims{}, err
+	}
+	return claims, nil
+}
+
+// JWKS возвращает открытые ключи, которыми потребители проверяют токены.
+// Пока ключи не заданы, набор пуст.
+func (a Auth) JWKS() jwt.JWKS {
+	return a.keys.JWKS()
+}
+
+// UserPseudonym возвращает псевдоним пользователя из initData с проверенной подписью.
+// Используется как ключ лимитов, поэтому поддельные initData не дают ключа.
+func (a Auth) UserPseudonym(userHash string) (string, bool) {
+	if userHash == "" || initdata.Validate(userHash, a.tgToken, 0) != nil {
+		return "", false
+	}
+	data, err := initdata.Parse(userHash)
+	if err != nil || data.User.ID == 0 {
+		return "", false
+	}
+	return crypto.Pseudonym(data.User.ID), true
+}
+
+// validationReason переводит ошибку initdata в значение label reason для метрик.
+func validationReason(err error) string {
+	switch {
+	case errors.Is(err, initdata.ErrExpired):
+		return "expired"
+	case errors.Is(err, initdata.ErrSignInvalid):
+		return "sign_invalid"
+	case errors.Is(err, initdata.ErrSignMissing):
+		return "sign_missing"
+	case errors.Is(err, initdata.ErrAuthDateMissing):
+		return "auth_date_missing"
+	case errors.Is(err, initdata.ErrAuthDateInvalid):
+		return "auth_date_invalid"
+	case errors.Is(err, initdata.ErrUnexpectedFormat):
+		return "unexpected_format"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/crypto"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// ErrSelfModeration - модератор пытается забанить или понизить самого себя.
+var ErrSelfModeration = errors.New("cannot apply to yourself")
+
+// Команды бота модераторов. Вызывающий - пользователь Telegram moderatorID,
+// он должен быть администратором. target - "@username", числовой Telegram ID
+// или sub пользователя. События аудита пишутся от sub модератора.
+
+// BotSetBanned банит или разбанивает пользователя по команде /ban или /unban.
+func (a Auth) BotSetBanned(ctx context.Context, moderatorID int64, target string, banned bool) (user models.User, err error) {
+	ctx, span := tracing.Start(ctx, "auth.BotSetBanned")
+	defer func() { tracing.End(span, err) }()
+
+	eventType, command := models.AuditUnban, "unban"
+	if banned {
+		eventType, command = models.AuditBan, "ban"
+	}
+	moderator := a.moderatorSub(moderatorID)
+	defer func() { a.recordBot(ctx, command, eventType, moderator, user.TgId, err) }()
+
+	if user, err = a.botTarget(ctx, moderatorID, target); err != nil {
+		return models.User{}, fmt.Errorf("app.BotSetBanned: %w", err)
+	}
+	if banned && user.TgId == moderator {
+		return user, fmt.Errorf("app.BotSetBanned: %w: %w", ErrForbidden, ErrSelfModeration)
+	}
+	updated, err := a.setBanned(ctx, user.TgId, banned, 0)
+	if err != nil {
+		return user, fmt.Errorf("app.BotSetBanned: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "ban status changed by moderator",
+		slog.String("op", "app.BotSetBanned"), slog.Bool("banned", banned))
+	return updated, nil
+}
+
+// BotSetAdmin выдает или снимает роль администратора по команде /promote или /demote.
+func (a Auth) BotSetAdmin(ctx context.Context, moderatorID int64, target string, admin bool) (user models.User, err error) {
+	ctx, span := tracing.Start(ctx, "auth.BotSetAdmin")
+	defer func() { tracing.End(span, err) }()
+
+	eventType, command := models.AuditDemote, "demote"
+	if admin {
+		eventType, command = models.AuditPromote, "promote"
+	}
+	moderator := a.moderatorSub(moderatorID)
+	defer func() { a.recordBot(ctx, command, eventType, moderator, user.TgId, err) }()
+
+	if user, err = a.botTarget(ctx, moderatorID, target); err != nil {
+		return models.User{}, fmt.Errorf("app.BotSetAdmin: %w", err)
+	}
+	// Иначе последний администратор может случайно остаться без доступа к боту.
+	if !admin && user.TgId == moderator {
+		return user, fmt.Errorf("app.BotSetAdmin: %w: %w", ErrForbidden, ErrSelfModeration)
+	}
+	user.IsAdmin = admin
+	if err = a.userSaver.SetAdmin(ctx, user.TgId, admin, newEvent(models.EventUserUpdated, user.TgId, 0, userEvent(user.TgId, user))); err != nil {
+		return user, fmt.Errorf("app.BotSetAdmin: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "admin role changed by moderator",
+		slog.String("op", "app.BotSetAdmin"), slog.Bool("admin", admin))
+	return user, nil
+}
+
+// BotWhois возвращает пользователя по команде /whois.
+func (a Auth) BotWhois(ctx context.Context, moderatorID int64, target string) (user models.User, err error) {
+	ctx, span := tracing.Start(ctx, "auth.BotWhois")
+	defer func() { tracing.End(span, err) }()
+	defer func() { a.recordBot(ctx, "whois", models.AuditWhois, a.moderatorSub(moderatorID), user.TgId, err) }()
+
+	if user, err = a.botTarget(ctx, moderatorID, target); err != nil {
+		return models.User{}, fmt.Errorf("app.BotWhois: %w", err)
+	}
+	return user, nil
+}
+
+// botTarget проверяет, что moderatorID - администратор с недавно подтвержденным
+// вторым фактором (если он нужен), и находит пользователя target.
+func (a Auth) botTarget(ctx context.Context, moderatorID int64, target string) (models.User, error) {
+	isAdmin, err := a.userProvider.IsAdmin(ctx, a.moderatorSub(moderatorID))
+	if errors.Is(err, storage.ErrUserNotFound) || err == nil && !isAdmin {
+		return models.User{}, fmt.Errorf("%w: admin role required", ErrForbidden)
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	// В боте нет сессии, поэтому действует последнее подтверждение второго
+	// фактора в любой сессии модератора.
+	factor, required, err := a.adminStepUp(ctx, a.moderatorSub(moderatorID), true)
+	if err != nil {
+		return models.User{}, err
+	}
+	if required && !a.steppedUp(factor.LastVerifiedAt) {
+		return models.User{}, fmt.Errorf("%w: %w", ErrForbidden, ErrStepUpRequired)
+	}
+
+	if username, ok := strings.CutPrefix(target, "@"); ok {
+		return a.userProvider.UserByUsername(ctx, username)
+	}
+	if tgID, err := strconv.ParseInt(target, 10, 64); err == nil {
+		sub, err := crypto.HashTgID(tgID)
+		if err != nil {
+			return models.User{}, fmt.Errorf("Ошибка хеширования: %w", err)
+		}
+		target = sub
+	}
+	return a.userProvider.User(ctx, target)
+}
+
+// moderatorSub - sub пользователя Telegram, от которого пришла команда.
+// Пустая строка, если ключ шифрования недоступен: такой sub не найдется.
+func (a Auth) moderatorSub(tgID int64) string {
+	sub, _ := crypto.HashTgID(tgID)
+	return sub
+}
+
+func (a Auth) recordBot(ctx context.Context, command, eventType, actor, subject string, err error) {
+	metrics.BotCommands.WithLabelValues(command, metrics.Outcome(err)).Inc()
+	a.record(ctx, models.AuditEvent{Type: eventType, Actor: actor, Subject: subject}, err)
+}
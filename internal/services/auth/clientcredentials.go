@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+var (
+	ErrInvalidClient = errors.New("invalid client")
+	ErrInvalidScope  = errors.New("invalid scope")
+	ErrForbidden     = errors.New("insufficient scope")
+)
+
+// ClientCredentials выдает приложению токен от его собственного имени (OAuth2
+// client_credentials). Пустой scopes означает все права приложения.
+func (a Auth) ClientCredentials(ctx context.Context, clientID int64, secret string, scopes []string) (tok models.ServiceToken, err error) {
+	ctx, span := tracing.Start(ctx, "auth.ClientCredentials")
+	defer func() { tracing.End(span, err) }()
+	defer func() {
+		actor := jwt.AppSubject(clientID)
+		a.record(ctx, models.AuditEvent{Type: models.AuditClientCredentials, Actor: actor, Subject: actor, AppID: clientID}, err)
+	}()
+
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", "app.ClientCredentials"), slog.Int64("client_id", clientID))
+
+	app, err := a.authenticateClient(ctx, clientID, secret)
+	if err != nil {
+		return models.ServiceToken{}, fmt.Errorf("app.ClientCredentials: %w", err)
+	}
+
+	if len(scopes) == 0 {
+		scopes = app.Scopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(app.Scopes, scope) {
+			return models.ServiceToken{}, fmt.Errorf("app.ClientCredentials: %w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	token, err := jwt.NewServiceToken(a.keys, app, scopes, a.serviceTokenTTL)
+	if err != nil {
+		return models.ServiceToken{}, fmt.Errorf("Ошибка генерации токена: %w", err)
+	}
+	metrics.TokensIssued.WithLabelValues(app.Name).Inc()
+	log.InfoContext(ctx, "service token issued", slog.Any("scopes", scopes))
+
+	return models.ServiceToken{AccessToken: token, ExpiresIn: a.serviceTokenTTL, Scopes: scopes}, nil
+}
+
+// authenticateClient проверяет client_id и client_secret приложения.
+func (a Auth) authenticateClient(ctx context.Context, clientID int64, secret string) (models.App, error) {
+	app, err := a.appProvider.App(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.App{}, ErrInvalidClient
+		}
+		return models.App{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(app.Secret), []byte(secret)) != 1 {
+		sl.FromContext(ctx, a.log).WarnContext(ctx, "client secret mismatch", slog.Int64("client_id", clientID))
+		return models.App{}, ErrInvalidClient
+	}
+	return app, nil
+}
+
+// Caller проверяет сервисный токен и наличие у него права scope.
+// Пользовательские токены сюда не подходят, даже если пользователь - админ.
+func (a Auth) Caller(ctx context.Context, token string, scope string) (models.Caller, error) {
+	claims, err := a.verifyToken(ctx, token)
+	if err != nil {
+		return models.Caller{}, fmt.Errorf("app.Caller: %w", err)
+	}
+	if claims.SubjectType != models.SubjectService {
+		return models.Caller{}, fmt.Errorf("app.Caller: %w: service token required", ErrForbidden)
+	}
+	if !slices.Contains(claims.Scopes, scope) {
+		return models.Caller{}, fmt.Errorf("app.Caller: %w: %s", ErrForbidden, scope)
+	}
+	return models.Caller{AppID: claims.ServiceID, Scopes: claims.Scopes}, nil
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/tracing"
+	"context"
+	"fmt"
+	"time"
+)
+
+// MaxEventPage - наибольшее число событий за один ответ /v1/events.
+const MaxEventPage = 1000
+
+// eventsPollInterval - как часто ожидающий запрос проверяет outbox. Инстансов
+// может быть несколько, поэтому новые события видны только через базу.
+const eventsPollInterval = time.Second
+
+// Events возвращает события после курсора afterID, видимые приложению caller.
+// Если событий нет, ждет их до wait, проверяя outbox раз в eventsPollInterval;
+// по истечении wait возвращает пустую страницу.
+func (a Auth) Events(ctx context.Context, caller models.Caller, afterID int64, limit int, wait time.Duration) (events []models.OutboxEvent, err error) {
+	ctx, span := tracing.Start(ctx, "auth.Events")
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 || limit > MaxEventPage {
+		limit = MaxEventPage
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		events, err = a.outbox.Events(ctx, caller.AppID, afterID, limit)
+		if err != nil {
+			return nil, fmt.Errorf("app.Events: %w", err)
+		}
+		left := time.Until(deadline)
+		if len(events) > 0 || left <= 0 {
+			return events, nil
+		}
+
+		timer := time.NewTimer(min(eventsPollInterval, left))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return events, nil
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+var (
+	ErrInvalidGrant  = errors.New("invalid grant")
+	ErrInvalidTarget = errors.New("invalid target")
+)
+
+// TrustRule разрешает приложению From обменивать токены своих пользователей
+// на токены приложений To. Scopes - права, которые получает выданный токен.
+type TrustRule struct {
+	From   int64
+	To     []int64
+	Scopes []string
+}
+
+// ExchangePolicy - политика обмена токенов (RFC 8693). Без правил обмен запрещен.
+type ExchangePolicy struct {
+	TTL   time.Duration
+	Rules []TrustRule
+}
+
+func (p ExchangePolicy) rule(from, to int64) (TrustRule, bool) {
+	for _, rule := range p.Rules {
+		if rule.From == from && slices.Contains(rule.To, to) {
+			return rule, true
+		}
+	}
+	return TrustRule{}, false
+}
+
+// ExchangeToken меняет пользовательский токен приложения clientID на токен того же
+// пользователя для приложения audience. Выданный токен живет не дольше исходного
+// и несет claim act с цепочкой приложений, выполнявших обмен.
+func (a Auth) ExchangeToken(ctx context.Context, clientID int64, secret, subjectToken string, audience int64, scopes []string) (tok models.ServiceToken, err error) {
+	ctx, span := tracing.Start(ctx, "auth.ExchangeToken")
+	defer func() { tracing.End(span, err) }()
+
+	var subject string
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditTokenExchange, Actor: jwt.AppSubject(clientID), Subject: subject, AppID: audience}, err)
+	}()
+
+	log := sl.FromContext(ctx, a.log).With(
+		slog.String("op", "app.ExchangeToken"),
+		slog.Int64("client_id", clientID),
+		slog.Int64("audience", audience),
+	)
+
+	if _, err := a.authenticateClient(ctx, clientID, secret); err != nil {
+		return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w", err)
+	}
+
+	claims, err := a.verifyToken(ctx, subjectToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w: %w", ErrInvalidGrant, err)
+		}
+		return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w", err)
+	}
+	if claims.SubjectType != models.SubjectUser {
+		return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w: not a user token", ErrInvalidGrant)
+	}
+	subject = claims.Subject
+	if claims.ServiceID != clientID {
+		// Обменять можно только токен, выданный самому клиенту: иначе любое
+		// доверенное приложение могло бы переиспользовать перехваченные чужие токены.
+		return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w: token issued for app %d", ErrInvalidGrant, claims.ServiceID)
+	}
+
+	rule, ok := a.exchange.rule(clientID, audience)
+	if !ok {
+		log.WarnContext(ctx, "token exchange not allowed by trust policy")
+		return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w: no trust rule", ErrInvalidTarget)
+	}
+	target, err := a.appProvider.App(ctx, audience)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w: %w", ErrInvalidTarget, err)
+		}
+		return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w", err)
+	}
+
+	user, err := a.userProvider.User(ctx, claims.Subject)
+	if err != nil {
+		return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w", err)
+	}
+	if user.IsBanned {
+		return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w", storage.ErrUserBanned)
+	}
+
+	// Права только сужаются: в пределах правила и прав исходного токена, если они были.
+	allowed := rule.Scopes
+	if len(claims.Scopes) > 0 {
+		allowed = slices.DeleteFunc(slices.Clone(allowed), func(s string) bool {
+			return !slices.Contains(claims.Scopes, s)
+		})
+	}
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			return models.ServiceToken{}, fmt.Errorf("app.ExchangeToken: %w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	ttl := min(a.exchange.TTL, time.Until(claims.ExpiresAt).Truncate(time.Second))
+	actor := &jwt.Actor{Subject: jwt.AppSubject(clientID), Actor: claims.Actor}
+
+	token, err := jwt.NewExchangedToken(a.keys, claims.Subject, target, scopes, actor, claims.SessionID, ttl)
+	if err != nil {
+		return models.ServiceToken{}, fmt.Errorf("Ошибка генерации токена: %w", err)
+	}
+	metrics.TokensIssued.WithLabelValues(target.Name).Inc()
+	log.InfoContext(ctx, "token exchanged", slog.Any("scopes", scopes), slog.Duration("ttl", ttl))
+
+	return models.ServiceToken{AccessToken: token, ExpiresIn: ttl, Scopes: scopes}, nil
+}
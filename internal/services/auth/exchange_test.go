@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/storage"
+	"auth-service/internal/storage/memory"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExchangePolicyRule(t *testing.T) {
+	policy := ExchangePolicy{Rules: []TrustRule{
+		{From: 1, To: []int64{2, 3}, Scopes: []string{"profile"}},
+		{From: 2, To: []int64{1}},
+	}}
+
+	tests := []struct {
+		from, to int64
+		want     bool
+	}{
+		{1, 2, true},
+		{1, 3, true},
+		{2, 1, true},
+		{2, 3, false},
+		{3, 1, false},
+		{1, 1, false},
+	}
+	for _, tt := range tests {
+		rule, ok := policy.rule(tt.from, tt.to)
+		if ok != tt.want || (ok && rule.From != tt.from) {
+			t.Errorf("rule(%d, %d) = %+v, %v, want %v", tt.from, tt.to, rule, ok, tt.want)
+		}
+	}
+	if _, ok := (ExchangePolicy{}).rule(1, 2); ok {
+		t.Error("empty policy allows exchange")
+	}
+}
+
+func TestExchangeToken(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+	apps := []models.App{
+		{ID: 1, Name: "web", Secret: "web-secret"},
+		{ID: 2, Name: "shop", Secret: "shop-secret"},
+		{ID: 3, Name: "bank", Secret: "bank-secret"},
+	}
+	for _, app := range apps {
+		st.SaveApp(ctx, app)
+	}
+	if err := st.SaveUser(ctx, "user-1", models.User{FirstName: "Pavel"}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	policy := ExchangePolicy{TTL: time.Hour, Rules: []TrustRule{
+		{From: 1, To: []int64{2}, Scopes: []string{"profile", "orders"}},
+	}}
+	a := newTestAuth(st, Config{TokenTTL: time.Hour, ServiceTokenTTL: time.Hour, Exchange: policy})
+
+	subject := userToken(t, apps[0])
+	foreign := userToken(t, apps[2])
+	service, _ := jwt.NewServiceToken(nil, apps[0], nil, time.Minute)
+
+	tok, err := a.ExchangeToken(ctx, 1, "web-secret", subject, 2, []string{"orders"})
+	if err != nil {
+		t.Fatalf("ExchangeToken: %v", err)
+	}
+	if !slices.Equal(tok.Scopes, []string{"orders"}) || tok.ExpiresIn > 10*time.Minute {
+		t.Errorf("unexpected token %+v, want scope orders and ttl of the subject token", tok)
+	}
+	claims, err := jwt.ValidateToken(tok.AccessToken, apps[1], nil)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Actor == nil || claims.Actor.Subject != "app:1" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	// Без запрошенных прав выдаются все права правила.
+	if tok, err := a.ExchangeToken(ctx, 1, "web-secret", subject, 2, nil); err != nil ||
+		!slices.Equal(tok.Scopes, []string{"profile", "orders"}) {
+		t.Errorf("ExchangeToken without scopes = %+v, %v", tok, err)
+	}
+
+	errs := map[string]struct {
+		clientID int64
+		secret   string
+		token    string
+		audience int64
+		scopes   []string
+		want     error
+	}{
+		"wrong secret":      {1, "bad", subject, 2, nil, ErrInvalidClient},
+		"no trust rule":     {1, "web-secret", subject, 3, nil, ErrInvalidTarget},
+		"reverse rule":      {2, "shop-secret", userToken(t, apps[1]), 1, nil, ErrInvalidTarget},
+		"scope not in rule": {1, "web-secret", subject, 2, []string{"admin"}, ErrInvalidScope},
+		"token of another":  {1, "web-secret", foreign, 2, nil, ErrInvalidGrant},
+		"service token":     {1, "web-secret", service, 2, nil, ErrInvalidGrant},
+		"garbage token":     {1, "web-secret", "garbage", 2, nil, ErrInvalidGrant},
+	}
+	for name, tt := range errs {
+		t.Run(name, func(t *testing.T) {
+			if _, err := a.ExchangeToken(ctx, tt.clientID, tt.secret, tt.token, tt.audience, tt.scopes); !errors.Is(err, tt.want) {
+				t.Errorf("ExchangeToken error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := st.SetBanned(ctx, "user-1", true); err != nil {
+		t.Fatalf("SetBanned: %v", err)
+	}
+	if _, err := a.ExchangeToken(ctx, 1, "web-secret", subject, 2, nil); !errors.Is(err, storage.ErrUserBanned) {
+		t.Errorf("ExchangeToken for banned user error = %v, want %v", err, storage.ErrUserBanned)
+	}
+}
+
+func userToken(t *testing.T, app models.App) string {
+	t.Helper()
+	token, err := jwt.NewToken(nil, "user-1", app, "", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	return token
+}
+
+// newTestAuth собирает сервис поверх одного хранилища в памяти.
+func newTestAuth(st *memory.Storage, cfg Config) *Auth {
+	return New(Deps{
+		Log:          slog.New(slog.DiscardHandler),
+		UserSaver:    st,
+		UserProvider: st,
+		AppProvider:  st,
+		Sessions:     st,
+		Audit:        st,
+		Outbox:       st,
+		Accounts:     st,
+		Handoffs:     st,
+		Identities:   st,
+		Passwords:    st,
+		Factors:      st,
+	}, cfg)
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/clientinfo"
+	"auth-service/internal/lib/crypto"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+// handoffPollInterval - как часто ожидающий опрос проверяет, подтвержден ли код.
+// Подтверждение может прийти на другой инстанс, поэтому оно видно только через базу.
+const handoffPollInterval = time.Second
+
+// ErrHandoffPending - код входа еще не подтвержден в мини-приложении.
+var ErrHandoffPending = errors.New("handoff is pending")
+
+type HandoffStore interface {
+	CreateHandoff(ctx context.Context, handoff models.Handoff) error
+	Handoff(ctx context.Context, code string, now time.Time) (models.Handoff, error)
+	ApproveHandoff(ctx context.Context, code, tgHash string, now time.Time) (models.Handoff, error)
+	ClaimHandoff(ctx context.Context, deviceHash string, now time.Time) (models.Handoff, error)
+}
+
+// HandoffPolicy - TTL кода входа и ссылка на мини-приложение, например
+// https://t.me/my_bot, к которой добавляется ?startapp=<code>.
+type HandoffPolicy struct {
+	TTL        time.Duration
+	MiniAppURL string
+}
+
+// StartHandoff создает код входа в приложение serviceId для браузера.
+func (a Auth) StartHandoff(ctx context.Context, serviceId int64) (start models.HandoffStart, err error) {
+	ctx, span := tracing.Start(ctx, "auth.StartHandoff")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := a.appProvider.App(ctx, serviceId); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.HandoffStart{}, fmt.Errorf("app.StartHandoff: %w", ErrInvalidApp)
+		}
+		return models.HandoffStart{}, fmt.Errorf("app.StartHandoff: %w", err)
+	}
+
+	now := time.Now().UTC()
+	deviceCode := newDeviceCode()
+	client := clientinfo.From(ctx)
+	handoff := models.Handoff{
+		Code:       newSessionID(),
+		DeviceHash: hashDeviceCode(deviceCode),
+		AppID:      serviceId,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(a.handoff.TTL),
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		Platform:   client.Platform,
+	}
+	if err := a.handoffs.CreateHandoff(ctx, handoff); err != nil {
+		return models.HandoffStart{}, fmt.Errorf("app.StartHandoff: %w", err)
+	}
+
+	start = models.HandoffStart{Code: handoff.Code, DeviceCode: deviceCode, ExpiresAt: handoff.ExpiresAt}
+	if a.handoff.MiniAppURL != "" {
+		start.Link = a.handoff.MiniAppURL + "?startapp=" + url.QueryEscape(handoff.Code)
+	}
+	return start, nil
+}
+
+// HandoffInfo показывает пользователю из initData, какое приложение и какой
+// браузер запросили код, до того как он его подтвердит.
+func (a Auth) HandoffInfo(ctx context.Context, initData, code string) (info models.HandoffInfo, err error) {
+	ctx, span := tracing.Start(ctx, "auth.HandoffInfo")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := a.handoffApprover(ctx, initData); err != nil {
+		return models.HandoffInfo{}, fmt.Errorf("app.HandoffInfo: %w", err)
+	}
+	handoff, err := a.handoffs.Handoff(ctx, code, time.Now().UTC())
+	if err != nil {
+		return models.HandoffInfo{}, fmt.Errorf("app.HandoffInfo: %w", err)
+	}
+	info, err = a.handoffInfo(ctx, handoff)
+	if err != nil {
+		return models.HandoffInfo{}, fmt.Errorf("app.HandoffInfo: %w", err)
+	}
+	return info, nil
+}
+
+// ApproveHandoff подтверждает код входа от имени пользователя из initData и
+// возвращает то же, что HandoffInfo: что именно было подтверждено.
+// Пользователь должен быть зарегистрирован и не забанен.
+func (a Auth) ApproveHandoff(ctx context.Context, initData, code string) (info models.HandoffInfo, err error) {
+	ctx, span := tracing.Start(ctx, "auth.ApproveHandoff")
+	defer func() { tracing.End(span, err) }()
+
+	var (
+		tgHash string
+		appID  int64
+	)
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditHandoffApproved, Actor: tgHash, Subject: tgHash, AppID: appID}, err)
+	}()
+
+	if tgHash, err = a.handoffApprover(ctx, initData); err != nil {
+		return models.HandoffInfo{}, fmt.Errorf("app.ApproveHandoff: %w", err)
+	}
+	handoff, err := a.handoffs.ApproveHandoff(ctx, code, tgHash, time.Now().UTC())
+	if err != nil {
+		return models.HandoffInfo{}, fmt.Errorf("app.ApproveHandoff: %w", err)
+	}
+	appID = handoff.AppID
+	info, err = a.handoffInfo(ctx, handoff)
+	if err != nil {
+		return models.HandoffInfo{}, fmt.Errorf("app.ApproveHandoff: %w", err)
+	}
+	return info, nil
+}
+
+// handoffApprover проверяет initData и возвращает sub пользователя, который
+// может подтверждать коды: зарегистрированного и не забаненного.
+func (a Auth) handoffApprover(ctx context.Context, initData string) (string, error) {
+	if err := initdata.Validate(initData, a.tgToken, 0); err != nil {
+		metrics.ValidationFailures.WithLabelValues(validationReason(err)).Inc()
+		return "", fmt.Errorf("%w: %w", ErrInvalidInitData, err)
+	}
+	parsed, err := initdata.Parse(initData)
+	if err != nil {
+		metrics.ValidationFailures.WithLabelValues(validationReason(err)).Inc()
+		return "", fmt.Errorf("%w: %w", ErrInvalidInitData, err)
+	}
+	tgHash, err := crypto.HashTgID(parsed.User.ID)
+	if err != nil {
+		return "", fmt.Errorf("Ошибка хеширования: %w", err)
+	}
+
+	user, err := a.userProvider.User(ctx, tgHash)
+	if err != nil {
+		return tgHash, err
+	}
+	if user.IsBanned {
+		return tgHash, storage.ErrUserBanned
+	}
+	return tgHash, nil
+}
+
+func (a Auth) handoffInfo(ctx context.Context, handoff models.Handoff) (models.HandoffInfo, error) {
+	app, err := a.appProvider.App(ctx, handoff.AppID)
+	if err != nil {
+		return models.HandoffInfo{}, err
+	}
+	return models.HandoffInfo{
+		AppID:     handoff.AppID,
+		AppName:   app.Name,
+		IP:        handoff.IP,
+		UserAgent: handoff.UserAgent,
+		Platform:  handoff.Platform,
+		CreatedAt: handoff.CreatedAt,
+		ExpiresAt: handoff.ExpiresAt,
+	}, nil
+}
+
+// PollHandoff выпускает токен браузеру, предъявившему deviceCode, как только
+// код подтвержден. Пока код не подтвержден, ждет до wait и возвращает
+// ErrHandoffPending. Токен по коду выпускается один раз, поэтому код
+// погашается, только пока ctx жив: ушедший браузер не должен потерять вход.
+// Погашенный код доводится до токена и при отмене ctx - вызывающий должен
+// попытаться его отдать.
+func (a Auth) PollHandoff(ctx context.Context, deviceCode string, wait time.Duration) (user models.UserResponse, token string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.PollHandoff")
+	defer func() { tracing.End(span, err) }()
+
+	deviceHash := hashDeviceCode(deviceCode)
+	deadline := time.Now().Add(wait)
+	for {
+		if ctx.Err() != nil {
+			return models.UserResponse{}, "", fmt.Errorf("app.PollHandoff: %w", ErrHandoffPending)
+		}
+		handoff, err := a.handoffs.ClaimHandoff(ctx, deviceHash, time.Now().UTC())
+		if err != nil {
+			return models.UserResponse{}, "", fmt.Errorf("app.PollHandoff: %w", err)
+		}
+		if handoff.UserID != "" {
+			return a.completeHandoff(context.WithoutCancel(ctx), handoff)
+		}
+
+		left := time.Until(deadline)
+		if left <= 0 {
+			return models.UserResponse{}, "", fmt.Errorf("app.PollHandoff: %w", ErrHandoffPending)
+		}
+		timer := time.NewTimer(min(handoffPollInterval, left))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return models.UserResponse{}, "", fmt.Errorf("app.PollHandoff: %w", ErrHandoffPending)
+		case <-timer.C:
+		}
+	}
+}
+
+// completeHandoff входит в приложение подтвержденного кода: это обычный вход,
+// сессия записывается с устройства браузера.
+func (a Auth) completeHandoff(ctx context.Context, handoff models.Handoff) (user models.UserResponse, token string, err error) {
+	defer func() {
+		metrics.Logins.WithLabelValues(metrics.Outcome(err)).Inc()
+		a.record(ctx, models.AuditEvent{Type: models.AuditLogin, Actor: handoff.UserID, Subject: handoff.UserID, AppID: handoff.AppID}, err)
+	}()
+
+	app, err := a.appProvider.App(ctx, handoff.AppID)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.PollHandoff: %w", err)
+	}
+	// Пользователя могли забанить между подтверждением и опросом.
+	user, err = a.userProvider.ValidateUser(ctx, handoff.UserID)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.PollHandoff: %w", err)
+	}
+	token, err = a.issueToken(ctx, handoff.UserID, app)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.PollHandoff: %w", err)
+	}
+	return user, token, nil
+}
+
+// newDeviceCode - секрет браузера, 256 бит.
+func newDeviceCode() string {
+	b := make([]byte, 32)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func hashDeviceCode(deviceCode string) string {
+	sum := sha256.Sum256([]byte(deviceCode))
+	return hex.EncodeToString(sum[:])
+}
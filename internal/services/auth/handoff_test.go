@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/clientinfo"
+	"auth-service/internal/lib/crypto"
+	"auth-service/internal/storage"
+	"auth-service/internal/storage/memory"
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+const testBotToken = "1:handoff-test"
+
+func signedInitData(tgID int64) string {
+	user := `{"id":` + strconv.FormatInt(tgID, 10) + `,"first_name":"Pavel"}`
+	authDate := time.Now()
+	values := url.Values{}
+	values.Set("user", user)
+	values.Set("auth_date", strconv.FormatInt(authDate.Unix(), 10))
+	values.Set("hash", initdata.Sign(map[string]string{"user": user}, testBotToken, authDate))
+	return values.Encode()
+}
+
+func TestHandoffShowsRequester(t *testing.T) {
+	if !crypto.Initialized() {
+		crypto.InitCrypto("handoff-test")
+	}
+	ctx := context.Background()
+	st := memory.New()
+	tgHash, err := crypto.HashTgID(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveUser(ctx, tgHash, models.User{FirstName: "Pavel"}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	a := newTestAuth(st, Config{TokenTTL: time.Hour, Handoff: HandoffPolicy{TTL: time.Minute}, BotToken: testBotToken})
+
+	browser := clientinfo.With(ctx, clientinfo.Info{IP: "203.0.113.7", UserAgent: "Mozilla/5.0 (X11; Linux x86_64)"})
+	start, err := a.StartHandoff(browser, 1)
+	if err != nil {
+		t.Fatalf("StartHandoff: %v", err)
+	}
+	want := models.HandoffInfo{AppID: 1, AppName: "test", IP: "203.0.113.7", UserAgent: "Mozilla/5.0 (X11; Linux x86_64)", Platform: "web"}
+
+	check := func(name string, info models.HandoffInfo) {
+		t.Helper()
+		info.CreatedAt, info.ExpiresAt = time.Time{}, time.Time{}
+		if info != want {
+			t.Errorf("%s = %+v, want %+v", name, info, want)
+		}
+	}
+	if _, err := a.HandoffInfo(ctx, "hash=forged", start.Code); !errors.Is(err, ErrInvalidInitData) {
+		t.Errorf("HandoffInfo with forged initData error = %v, want %v", err, ErrInvalidInitData)
+	}
+	info, err := a.HandoffInfo(ctx, signedInitData(42), start.Code)
+	if err != nil {
+		t.Fatalf("HandoffInfo: %v", err)
+	}
+	check("HandoffInfo", info)
+
+	approved, err := a.ApproveHandoff(ctx, signedInitData(42), start.Code)
+	if err != nil {
+		t.Fatalf("ApproveHandoff: %v", err)
+	}
+	check("ApproveHandoff", approved)
+	if _, err := a.HandoffInfo(ctx, signedInitData(42), start.Code); !errors.Is(err, storage.ErrHandoffNotFound) {
+		t.Errorf("HandoffInfo after approve error = %v, want %v", err, storage.ErrHandoffNotFound)
+	}
+}
+
+func TestPollHandoffKeepsCodeForCanceledClient(t *testing.T) {
+	if !crypto.Initialized() {
+		crypto.InitCrypto("handoff-test")
+	}
+	ctx := context.Background()
+	st := memory.New()
+	tgHash, err := crypto.HashTgID(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveUser(ctx, tgHash, models.User{FirstName: "Pavel"}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	a := newTestAuth(st, Config{TokenTTL: time.Hour, Handoff: HandoffPolicy{TTL: time.Minute}, BotToken: testBotToken})
+
+	start, err := a.StartHandoff(ctx, 1)
+	if err != nil {
+		t.Fatalf("StartHandoff: %v", err)
+	}
+	if _, err := a.ApproveHandoff(ctx, signedInitData(42), start.Code); err != nil {
+		t.Fatalf("ApproveHandoff: %v", err)
+	}
+
+	// Браузер отключился: код не погашается, и следующий опрос получает токен.
+	gone, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, _, err := a.PollHandoff(gone, start.DeviceCode, 0); !errors.Is(err, ErrHandoffPending) {
+		t.Errorf("PollHandoff with canceled context error = %v, want %v", err, ErrHandoffPending)
+	}
+	if _, token, err := a.PollHandoff(ctx, start.DeviceCode, 0); err != nil || token == "" {
+		t.Errorf("PollHandoff after reconnect = %q, %v", token, err)
+	}
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/oidc"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidIDToken  = errors.New("invalid id token")
+)
+
+// IdentityStore - внешние учетные записи (Google, Apple, email), привязанные к
+// аккаунту. Аккаунт по-прежнему определяется sub = users.tgid.
+type IdentityStore interface {
+	LinkIdentity(ctx context.Context, tgHash string, identity models.Identity) (models.Identity, error)
+	Identities(ctx context.Context, tgHash string) ([]models.Identity, error)
+	UnlinkIdentity(ctx context.Context, tgHash, provider string) error
+	UserByIdentity(ctx context.Context, provider, subject string) (models.User, error)
+}
+
+// IdentityVerifier проверяет ID-токен провайдера, реализуется oidc.Verifier.
+type IdentityVerifier interface {
+	Verify(ctx context.Context, idToken string) (oidc.Claims, error)
+}
+
+// Identities возвращает учетные записи, привязанные к аккаунту владельца токена.
+func (a Auth) Identities(ctx context.Context, token string) (identities []models.Identity, err error) {
+	ctx, span := tracing.Start(ctx, "auth.Identities")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.userClaims(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("app.Identities: %w", err)
+	}
+	identities, err = a.identities.Identities(ctx, claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("app.Identities: %w", err)
+	}
+	return identities, nil
+}
+
+// LinkIdentity привязывает к аккаунту владельца токена учетную запись провайдера
+// из idToken. Нужен токен сессии: привязка дает еще один способ войти в аккаунт.
+func (a Auth) LinkIdentity(ctx context.Context, token, provider, idToken string) (identity models.Identity, err error) {
+	ctx, span := tracing.Start(ctx, "auth.LinkIdentity")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.sessionClaims(ctx, token)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("app.LinkIdentity: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditIdentityLinked, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	external, err := a.verifyIdentity(ctx, provider, idToken)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("app.LinkIdentity: %w", err)
+	}
+	user, err := a.userProvider.User(ctx, sub)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("app.LinkIdentity: %w", err)
+	}
+	if user.IsBanned {
+		return models.Identity{}, fmt.Errorf("app.LinkIdentity: %w", storage.ErrUserBanned)
+	}
+
+	identity, err = a.identities.LinkIdentity(ctx, sub, models.Identity{
+		Provider:  provider,
+		Subject:   external.Subject,
+		Email:     external.Email,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("app.LinkIdentity: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "identity linked",
+		slog.String("op", "app.LinkIdentity"), slog.String("provider", provider))
+	return identity, nil
+}
+
+// UnlinkIdentity отвязывает от аккаунта владельца токена учетную запись провайдера.
+func (a Auth) UnlinkIdentity(ctx context.Context, token, provider string) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.UnlinkIdentity")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.sessionClaims(ctx, token)
+	if err != nil {
+		return fmt.Errorf("app.UnlinkIdentity: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditIdentityUnlinked, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	// У аккаунта без Telegram учетные записи - единственный способ войти.
+	if strings.HasPrefix(sub, models.AccountPrefix) {
+		identities, err := a.identities.Identities(ctx, sub)
+		if err != nil {
+			return fmt.Errorf("app.UnlinkIdentity: %w", err)
+		}
+		if len(identities) == 1 && identities[0].Provider == provider {
+			return fmt.Errorf("app.UnlinkIdentity: %w", ErrLastIdentity)
+		}
+	}
+	if err := a.identities.UnlinkIdentity(ctx, sub, provider); err != nil {
+		return fmt.Errorf("app.UnlinkIdentity: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "identity unlinked",
+		slog.String("op", "app.UnlinkIdentity"), slog.String("provider", provider))
+	return nil
+}
+
+// LoginWithIdentity входит в приложение serviceId по ID-токену привязанной
+// учетной записи. Токен выпускается на тот же sub, что и при входе через Telegram.
+func (a Auth) LoginWithIdentity(ctx context.Context, provider, idToken string, serviceId int64) (user models.UserResponse, token string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.LoginWithIdentity")
+	defer func() { tracing.End(span, err) }()
+
+	var sub string
+	defer func() {
+		metrics.Logins.WithLabelValues(metrics.Outcome(err)).Inc()
+		a.record(ctx, models.AuditEvent{Type: models.AuditLogin, Actor: sub, Subject: sub, AppID: serviceId}, err)
+	}()
+
+	external, err := a.verifyIdentity(ctx, provider, idToken)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithIdentity: %w", err)
+	}
+	app, err := a.appProvider.App(ctx, serviceId)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.UserResponse{}, "", fmt.Errorf("app.LoginWithIdentity: %w", ErrInvalidApp)
+		}
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithIdentity: %w", err)
+	}
+	linked, err := a.identities.UserByIdentity(ctx, provider, external.Subject)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithIdentity: %w", err)
+	}
+	sub = linked.TgId
+
+	user, err = a.userProvider.ValidateUser(ctx, sub)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithIdentity: %w", err)
+	}
+	token, err = a.issueToken(ctx, sub, app)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithIdentity: %w", err)
+	}
+	return user, token, nil
+}
+
+// verifyIdentity проверяет ID-токен верификатором провайдера.
+func (a Auth) verifyIdentity(ctx context.Context, provider, idToken string) (oidc.Claims, error) {
+	verifier, ok := a.providers[provider]
+	if !ok {
+		return oidc.Claims{}, fmt.Errorf("%w: %q", ErrUnknownProvider, provider)
+	}
+	claims, err := verifier.Verify(ctx, idToken)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidToken) {
+			return oidc.Claims{}, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+		}
+		return oidc.Claims{}, err
+	}
+	return claims, nil
+}
+
+// sessionClaims - userClaims для операций, которым нужна сессия: токены без sid
+// выпущены до появления сессий и их нельзя отозвать.
+func (a Auth) sessionClaims(ctx context.Context, token string) (jwt.Claims, error) {
+	claims, err := a.userClaims(ctx, token)
+	if err != nil {
+		return jwt.Claims{}, err
+	}
+	if claims.SessionID == "" {
+		return jwt.Claims{}, fmt.Errorf("%w: session required", ErrInvalidToken)
+	}
+	return claims, nil
+}
@@ -0,0 +1,457 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/mail"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/password"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Ограничения пароля. Верхняя граница не дает превратить хэширование в нагрузку.
+const (
+	minPasswordLen = 8
+	maxPasswordLen = 128
+)
+
+var (
+	ErrInvalidEmail     = errors.New("invalid email")
+	ErrWeakPassword     = errors.New("weak password")
+	ErrEmailNotVerified = errors.New("email not verified")
+	ErrAccountLocked    = errors.New("account locked")
+	ErrLastIdentity     = errors.New("last identity")
+)
+
+// PasswordStore - учетные записи email с паролем и одноразовые токены из писем.
+type PasswordStore interface {
+	SaveEmailUser(ctx context.Context, tgHash string, user models.User, identity models.Identity, events ...models.OutboxEvent) (models.Identity, error)
+	EmailIdentity(ctx context.Context, email string) (models.Identity, error)
+	RecordLoginFailure(ctx context.Context, id int64, maxFailures int, lockUntil time.Time) (bool, error)
+	ResetLoginFailures(ctx context.Context, id int64) error
+	CreateEmailToken(ctx context.Context, token models.EmailToken) error
+	VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (models.Identity, error)
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (models.Identity, error)
+}
+
+// Mailer отправляет письма, реализуется пакетом mail.
+type Mailer interface {
+	Send(ctx context.Context, msg mail.Message) error
+}
+
+// PasswordPolicy - вход по email и паролю. После MaxFailures неудачных попыток
+// подряд вход блокируется на LockoutDuration. VerifyURL и ResetURL - страницы
+// клиента, к ним добавляется ?token=<токен из письма>.
+type PasswordPolicy struct {
+	Enabled         bool
+	MaxFailures     int
+	LockoutDuration time.Duration
+	VerifyTTL       time.Duration
+	ResetTTL        time.Duration
+	VerifyURL       string
+	ResetURL        string
+}
+
+// dummyHash сравнивается с паролем, когда email не найден, чтобы время ответа
+// не выдавало, зарегистрирован ли адрес.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := password.Hash("dummy-password")
+	return hash
+})
+
+// RegisterEmail создает аккаунт без Telegram и отправляет письмо для
+// подтверждения адреса. Войти можно только после подтверждения. Если адрес уже
+// занят, владельцу уходит письмо об этом, а вызывающий получает тот же ответ,
+// что и при успехе, - регистрация не раскрывает, какие адреса есть в сервисе.
+// Неподтвержденная привязка адреса к другому аккаунту (LinkEmail) адрес не
+// занимает: хранилище заменяет ее новой учетной записью.
+func (a Auth) RegisterEmail(ctx context.Context, email, pw, firstName string, serviceId int64) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.RegisterEmail")
+	defer func() { tracing.End(span, err) }()
+
+	// auditErr - исход для журнала, если он отличается от ответа вызывающему.
+	var sub string
+	var auditErr error
+	defer func() {
+		if err != nil {
+			auditErr = err
+		}
+		metrics.Registrations.WithLabelValues(metrics.Outcome(auditErr)).Inc()
+		a.record(ctx, models.AuditEvent{Type: models.AuditRegister, Actor: sub, Subject: sub, AppID: serviceId}, auditErr)
+	}()
+
+	if !a.password.Enabled {
+		return fmt.Errorf("app.RegisterEmail: %w: %q", ErrUnknownProvider, models.ProviderEmail)
+	}
+	email, err = normalizeEmail(email)
+	if err != nil {
+		return fmt.Errorf("app.RegisterEmail: %w", err)
+	}
+	if err := checkPassword(pw); err != nil {
+		return fmt.Errorf("app.RegisterEmail: %w", err)
+	}
+	if _, err := a.appProvider.App(ctx, serviceId); err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return fmt.Errorf("app.RegisterEmail: %w", ErrInvalidApp)
+		}
+		return fmt.Errorf("app.RegisterEmail: %w", err)
+	}
+	hash, err := password.Hash(pw)
+	if err != nil {
+		return fmt.Errorf("app.RegisterEmail: %w", err)
+	}
+
+	sub = models.AccountPrefix + newSessionID()
+	user := models.User{FirstName: firstName}
+	identity, err := a.passwords.SaveEmailUser(ctx, sub, user, models.Identity{
+		Provider:     models.ProviderEmail,
+		Subject:      email,
+		Email:        email,
+		CreatedAt:    time.Now().UTC(),
+		PasswordHash: hash,
+	}, newEvent(models.EventUserRegistered, sub, serviceId, userEvent(sub, user)))
+	if errors.Is(err, storage.ErrIdentityExists) {
+		auditErr, sub = err, ""
+		a.sendMail(ctx, mail.Message{
+			To:      email,
+			Subject: "Попытка регистрации",
+			Body:    "Кто-то пытался зарегистрироваться с этим адресом, но он уже привязан к аккаунту. Если это были вы, войдите или восстановите пароль.",
+		})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("app.RegisterEmail: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "email account registered", slog.String("op", "app.RegisterEmail"))
+
+	a.sendVerification(ctx, identity)
+	return nil
+}
+
+// VerifyEmail подтверждает адрес по токену из письма.
+func (a Auth) VerifyEmail(ctx context.Context, token string) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.VerifyEmail")
+	defer func() { tracing.End(span, err) }()
+
+	var sub string
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditEmailVerified, Actor: sub, Subject: sub}, err)
+	}()
+
+	identity, err := a.passwords.VerifyEmail(ctx, hashEmailToken(token), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("app.VerifyEmail: %w", err)
+	}
+	sub = identity.UserID
+	return nil
+}
+
+// LoginWithPassword входит в приложение serviceId по email и паролю. Как и при
+// входе через Telegram, забаненному пользователю токен не выдается.
+func (a Auth) LoginWithPassword(ctx context.Context, email, pw string, serviceId int64) (user models.UserResponse, token string, err error) {
+	ctx, span := tracing.Start(ctx, "auth.LoginWithPassword")
+	defer func() { tracing.End(span, err) }()
+
+	var sub string
+	defer func() {
+		metrics.Logins.WithLabelValues(metrics.Outcome(err)).Inc()
+		a.record(ctx, models.AuditEvent{Type: models.AuditLogin, Actor: sub, Subject: sub, AppID: serviceId}, err)
+	}()
+
+	if !a.password.Enabled {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w: %q", ErrUnknownProvider, models.ProviderEmail)
+	}
+	app, err := a.appProvider.App(ctx, serviceId)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", ErrInvalidApp)
+		}
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", err)
+	}
+	email, err = normalizeEmail(email)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", ErrInvalidCredentials)
+	}
+	identity, err := a.passwords.EmailIdentity(ctx, email)
+	if errors.Is(err, storage.ErrIdentityNotFound) {
+		password.Verify(pw, dummyHash())
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", ErrInvalidCredentials)
+	}
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", err)
+	}
+	sub = identity.UserID
+
+	// О блокировке сообщается только знающему пароль: иначе по ней можно было бы
+	// узнать, какие адреса зарегистрированы. Неверный пароль при блокировке не
+	// считается новой неудачей.
+	now := time.Now().UTC()
+	locked := identity.LockedUntil != nil && now.Before(*identity.LockedUntil)
+	ok, err := password.Verify(pw, identity.PasswordHash)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", err)
+	}
+	if !ok && !locked {
+		justLocked, err := a.passwords.RecordLoginFailure(ctx, identity.ID, a.password.MaxFailures, now.Add(a.password.LockoutDuration))
+		if err != nil {
+			return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", err)
+		}
+		if justLocked {
+			sl.FromContext(ctx, a.log).WarnContext(ctx, "email account locked",
+				slog.String("op", "app.LoginWithPassword"), slog.Duration("lockout", a.password.LockoutDuration))
+		}
+	}
+	if !ok {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", ErrInvalidCredentials)
+	}
+	if locked {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", ErrAccountLocked)
+	}
+	if identity.VerifiedAt == nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", ErrEmailNotVerified)
+	}
+	if identity.FailedLogins > 0 || identity.LockedUntil != nil {
+		if err := a.passwords.ResetLoginFailures(ctx, identity.ID); err != nil {
+			return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", err)
+		}
+	}
+
+	user, err = a.userProvider.ValidateUser(ctx, sub)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", err)
+	}
+	token, err = a.issueToken(ctx, sub, app)
+	if err != nil {
+		return models.UserResponse{}, "", fmt.Errorf("app.LoginWithPassword: %w", err)
+	}
+	return user, token, nil
+}
+
+// RequestPasswordReset отправляет письмо со ссылкой для сброса пароля, если
+// адрес привязан к аккаунту. Ответ не зависит от того, найден ли адрес.
+func (a Auth) RequestPasswordReset(ctx context.Context, email string) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.RequestPasswordReset")
+	defer func() { tracing.End(span, err) }()
+
+	if !a.password.Enabled {
+		return fmt.Errorf("app.RequestPasswordReset: %w: %q", ErrUnknownProvider, models.ProviderEmail)
+	}
+	email, err = normalizeEmail(email)
+	if err != nil {
+		return fmt.Errorf("app.RequestPasswordReset: %w", err)
+	}
+	identity, err := a.passwords.EmailIdentity(ctx, email)
+	if errors.Is(err, storage.ErrIdentityNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("app.RequestPasswordReset: %w", err)
+	}
+
+	token, err := a.createEmailToken(ctx, identity.ID, models.EmailTokenReset, a.password.ResetTTL)
+	if err != nil {
+		return fmt.Errorf("app.RequestPasswordReset: %w", err)
+	}
+	a.sendMail(ctx, mail.Message{
+		To:      identity.Email,
+		Subject: "Сброс пароля",
+		Body: emailAction("Чтобы задать новый пароль,", a.password.ResetURL, token) +
+			"\n\nЕсли вы не запрашивали сброс, просто проигнорируйте это письмо.",
+	})
+	return nil
+}
+
+// ResetPassword задает новый пароль по токену из письма и завершает все сессии
+// аккаунта: сброс обычно означает, что старый пароль мог попасть к кому-то еще.
+func (a Auth) ResetPassword(ctx context.Context, token, pw string) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+
+	var sub string
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditPasswordReset, Actor: sub, Subject: sub}, err)
+	}()
+
+	if err := checkPassword(pw); err != nil {
+		return fmt.Errorf("app.ResetPassword: %w", err)
+	}
+	hash, err := password.Hash(pw)
+	if err != nil {
+		return fmt.Errorf("app.ResetPassword: %w", err)
+	}
+	now := time.Now().UTC()
+	identity, err := a.passwords.ResetPassword(ctx, hashEmailToken(token), hash, now)
+	if err != nil {
+		return fmt.Errorf("app.ResetPassword: %w", err)
+	}
+	sub = identity.UserID
+
+	sessions, err := a.sessions.Sessions(ctx, sub)
+	if err != nil {
+		return fmt.Errorf("app.ResetPassword: %w", err)
+	}
+	for _, session := range sessions {
+		event := newEvent(models.EventSessionRevoked, sub, session.AppID, models.SessionEvent{Sub: sub, SessionID: session.ID})
+		if err := a.sessions.RevokeSession(ctx, sub, session.ID, now, event); err != nil && !errors.Is(err, storage.ErrSessionNotFound) {
+			return fmt.Errorf("app.ResetPassword: %w", err)
+		}
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "password reset",
+		slog.String("op", "app.ResetPassword"), slog.Int("revoked_sessions", len(sessions)))
+	return nil
+}
+
+// LinkEmail привязывает к аккаунту владельца токена вход по email и паролю.
+// Войти по нему можно после подтверждения адреса из письма. Сброс пароля такую
+// привязку не подтверждает, а до подтверждения ее заменяет регистрация с тем же
+// адресом: чужой адрес, привязанный заранее, не должен перехватить его владельца.
+func (a Auth) LinkEmail(ctx context.Context, token, email, pw string) (identity models.Identity, err error) {
+	ctx, span := tracing.Start(ctx, "auth.LinkEmail")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.sessionClaims(ctx, token)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("app.LinkEmail: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditIdentityLinked, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	if !a.password.Enabled {
+		return models.Identity{}, fmt.Errorf("app.LinkEmail: %w: %q", ErrUnknownProvider, models.ProviderEmail)
+	}
+	email, err = normalizeEmail(email)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("app.LinkEmail: %w", err)
+	}
+	if err := checkPassword(pw); err != nil {
+		return models.Identity{}, fmt.Errorf("app.LinkEmail: %w", err)
+	}
+	user, err := a.userProvider.User(ctx, sub)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("app.LinkEmail: %w", err)
+	}
+	if user.IsBanned {
+		return models.Identity{}, fmt.Errorf("app.LinkEmail: %w", storage.ErrUserBanned)
+	}
+	hash, err := password.Hash(pw)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("app.LinkEmail: %w", err)
+	}
+
+	identity, err = a.identities.LinkIdentity(ctx, sub, models.Identity{
+		Provider:     models.ProviderEmail,
+		Subject:      email,
+		Email:        email,
+		CreatedAt:    time.Now().UTC(),
+		PasswordHash: hash,
+	})
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("app.LinkEmail: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "identity linked",
+		slog.String("op", "app.LinkEmail"), slog.String("provider", models.ProviderEmail))
+
+	a.sendVerification(ctx, identity)
+	return identity, nil
+}
+
+// sendVerification отправляет письмо для подтверждения адреса identity.
+// Ошибка только логируется: адрес зарегистрированного аккаунта подтверждает и
+// сброс пароля, привязанный адрес можно отвязать и привязать заново.
+func (a Auth) sendVerification(ctx context.Context, identity models.Identity) {
+	token, err := a.createEmailToken(ctx, identity.ID, models.EmailTokenVerify, a.password.VerifyTTL)
+	if err != nil {
+		sl.FromContext(ctx, a.log).ErrorContext(ctx, "failed to create verification token", sl.Err(err))
+		return
+	}
+	a.sendMail(ctx, mail.Message{
+		To:      identity.Email,
+		Subject: "Подтверждение email",
+		Body:    emailAction("Чтобы подтвердить адрес,", a.password.VerifyURL, token),
+	})
+}
+
+// createEmailToken сохраняет хэш нового токена из письма и возвращает сам токен.
+func (a Auth) createEmailToken(ctx context.Context, identityID int64, purpose string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	crand.Read(b)
+	token := hex.EncodeToString(b)
+
+	now := time.Now().UTC()
+	err := a.passwords.CreateEmailToken(ctx, models.EmailToken{
+		Hash:       hashEmailToken(token),
+		IdentityID: identityID,
+		Purpose:    purpose,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// sendMail отправляет письмо. Ошибка только логируется: ответ клиенту не должен
+// зависеть от почтового сервера.
+func (a Auth) sendMail(ctx context.Context, msg mail.Message) {
+	if err := a.mailer.Send(ctx, msg); err != nil {
+		sl.FromContext(ctx, a.log).ErrorContext(ctx, "failed to send mail",
+			slog.String("subject", msg.Subject), sl.Err(err))
+	}
+}
+
+func hashEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// emailAction - текст письма со ссылкой на страницу клиента с токеном. Без
+// страницы клиент принимает токен как код, и в письмо попадает только он.
+func emailAction(intro, page, token string) string {
+	if page == "" {
+		return intro + " введите код: " + token
+	}
+	sep := "?"
+	if strings.Contains(page, "?") {
+		sep = "&"
+	}
+	return intro + " перейдите по ссылке: " + page + sep + "token=" + url.QueryEscape(token)
+}
+
+// normalizeEmail проверяет адрес и приводит его к нижнему регистру: по нему
+// ищется учетная запись, и регистр не должен давать второй аккаунт.
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 254 {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+func checkPassword(pw string) error {
+	n := utf8.RuneCountInString(pw)
+	if n < minPasswordLen {
+		return fmt.Errorf("%w: at least %d characters required", ErrWeakPassword, minPasswordLen)
+	}
+	if n > maxPasswordLen {
+		return fmt.Errorf("%w: at most %d characters allowed", ErrWeakPassword, maxPasswordLen)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/password"
+	"auth-service/internal/storage/memory"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoginWithPasswordHidesLockout(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+	a := newTestAuth(st, Config{TokenTTL: time.Hour, Password: PasswordPolicy{Enabled: true, MaxFailures: 2, LockoutDuration: time.Hour}})
+
+	const email = "pavel@example.com"
+	hash, err := password.Hash("correct-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	_, err = st.SaveEmailUser(ctx, models.AccountPrefix+"pavel", models.User{FirstName: "Pavel"}, models.Identity{
+		Provider: models.ProviderEmail, Subject: email, Email: email, CreatedAt: now, PasswordHash: hash, VerifiedAt: &now,
+	})
+	if err != nil {
+		t.Fatalf("SaveEmailUser: %v", err)
+	}
+
+	// Неверный пароль получает один и тот же ответ до блокировки, на ней и после.
+	for i := range 4 {
+		if _, _, err := a.LoginWithPassword(ctx, email, "wrong-password", 1); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("LoginWithPassword(wrong) #%d error = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+	if _, _, err := a.LoginWithPassword(ctx, "missing@example.com", "wrong-password", 1); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("LoginWithPassword(missing) error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, _, err := a.LoginWithPassword(ctx, email, "correct-password", 1); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("LoginWithPassword(correct, locked) error = %v, want %v", err, ErrAccountLocked)
+	}
+}
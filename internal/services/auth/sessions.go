@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/clientinfo"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// sessionTouchInterval - как часто обновляется last_seen_at. Чаще писать
+// в базу на каждую проверку токена незачем.
+const sessionTouchInterval = time.Minute
+
+type SessionStore interface {
+	CreateSession(ctx context.Context, session models.Session) error
+	Session(ctx context.Context, id string) (models.Session, error)
+	Sessions(ctx context.Context, tgHash string) ([]models.Session, error)
+	TouchSession(ctx context.Context, id string, at time.Time) error
+	RevokeSession(ctx context.Context, tgHash, id string, at time.Time, events ...models.OutboxEvent) error
+}
+
+// startSession записывает новую сессию входа пользователя tgHash в приложение app.
+// Устройство и адрес берутся из контекста запроса.
+func (a Auth) startSession(ctx context.Context, tgHash string, app models.App) (string, error) {
+	client := clientinfo.From(ctx)
+	now := time.Now().UTC()
+	session := models.Session{
+		ID:         newSessionID(),
+		UserID:     tgHash,
+		AppID:      int64(app.ID),
+		Platform:   client.Platform,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := a.sessions.CreateSession(ctx, session); err != nil {
+		return "", fmt.Errorf("Ошибка создания сессии: %w", err)
+	}
+	return session.ID, nil
+}
+
+// checkSession отклоняет токен завершенной сессии и отмечает активность.
+// Токены без sid выпущены до появления сессий и проверяются только подписью.
+func (a Auth) checkSession(ctx context.Context, sub, sid string) error {
+	if sid == "" {
+		return nil
+	}
+	session, err := a.sessions.Session(ctx, sid)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+		return err
+	}
+	if !session.Active() || session.UserID != sub {
+		return fmt.Errorf("%w: session revoked", ErrInvalidToken)
+	}
+
+	now := time.Now().UTC()
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := a.sessions.TouchSession(ctx, sid, now); err != nil {
+			sl.FromContext(ctx, a.log).WarnContext(ctx, "failed to touch session", sl.Err(err))
+		}
+	}
+	return nil
+}
+
+// Sessions возвращает активные сессии владельца токена. Сессия самого токена
+// отмечена Current.
+func (a Auth) Sessions(ctx context.Context, token string) (sessions []models.Session, err error) {
+	ctx, span := tracing.Start(ctx, "auth.Sessions")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.verifyToken(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("app.Sessions: %w", err)
+	}
+	if claims.SubjectType != models.SubjectUser {
+		return nil, fmt.Errorf("app.Sessions: %w: not a user token", ErrInvalidToken)
+	}
+
+	sessions, err = a.sessions.Sessions(ctx, claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("app.Sessions: %w", err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == claims.SessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает сессию id владельца токена. Все токены с этим sid,
+// включая полученные обменом, перестают проходить проверку.
+func (a Auth) RevokeSession(ctx context.Context, token, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.RevokeSession")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.verifyToken(ctx, token)
+	if err != nil {
+		return fmt.Errorf("app.RevokeSession: %w", err)
+	}
+	if claims.SubjectType != models.SubjectUser {
+		return fmt.Errorf("app.RevokeSession: %w: not a user token", ErrInvalidToken)
+	}
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditSessionRevoked, Actor: claims.Subject, Subject: claims.Subject, AppID: claims.ServiceID}, err)
+	}()
+
+	event := newEvent(models.EventSessionRevoked, claims.Subject, claims.ServiceID, models.SessionEvent{Sub: claims.Subject, SessionID: id})
+	if err := a.sessions.RevokeSession(ctx, claims.Subject, id, time.Now().UTC(), event); err != nil {
+		return fmt.Errorf("app.RevokeSession: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "session revoked",
+		slog.String("op", "app.RevokeSession"), slog.Bool("current", id == claims.SessionID))
+	return nil
+}
+
+// UserSessions возвращает активные сессии пользователя sub для admin API.
+func (a Auth) UserSessions(ctx context.Context, sub string) (sessions []models.Session, err error) {
+	ctx, span := tracing.Start(ctx, "auth.UserSessions")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := a.userProvider.User(ctx, sub); err != nil {
+		return nil, fmt.Errorf("app.UserSessions: %w", err)
+	}
+	sessions, err = a.sessions.Sessions(ctx, sub)
+	if err != nil {
+		return nil, fmt.Errorf("app.UserSessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeUserSession завершает сессию пользователя sub от имени приложения caller.
+func (a Auth) RevokeUserSession(ctx context.Context, caller models.Caller, sub, id string) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.RevokeUserSession")
+	defer func() { tracing.End(span, err) }()
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditSessionRevoked, Actor: jwt.AppSubject(caller.AppID), Subject: sub, AppID: caller.AppID}, err)
+	}()
+
+	event := newEvent(models.EventSessionRevoked, sub, caller.AppID, models.SessionEvent{Sub: sub, SessionID: id})
+	if err := a.sessions.RevokeSession(ctx, sub, id, time.Now().UTC(), event); err != nil {
+		return fmt.Errorf("app.RevokeUserSession: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "session revoked",
+		slog.String("op", "app.RevokeUserSession"), slog.Int64("caller_app", caller.AppID))
+	return nil
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,470 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/crypto"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/totp"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// recoveryCodeCount - сколько кодов восстановления выдается за раз.
+const recoveryCodeCount = 10
+
+// totpSealPurpose - назначение ключа crypto.Seal для секретов TOTP.
+const totpSealPurpose = "totp"
+
+// providerTOTP - имя второго фактора в ошибке ErrUnknownProvider, когда
+// two_factor выключен.
+const providerTOTP = "totp"
+
+var (
+	ErrStepUpRequired = errors.New("second factor required")
+	ErrInvalidOTP     = errors.New("invalid one-time code")
+	ErrReauthRequired = errors.New("recent login required")
+)
+
+// TwoFactorStore - второй фактор TOTP, коды восстановления и отметка о
+// подтверждении фактора в сессии.
+type TwoFactorStore interface {
+	SaveTOTP(ctx context.Context, factor models.TOTPFactor) error
+	TOTP(ctx context.Context, tgHash string) (models.TOTPFactor, error)
+	ConfirmTOTP(ctx context.Context, tgHash string, step int64, at time.Time, recoveryHashes []string) error
+	UseTOTPStep(ctx context.Context, tgHash string, step int64, at time.Time) (bool, error)
+	RecordOTPFailure(ctx context.Context, tgHash string, maxFailures int, lockUntil time.Time) (bool, error)
+	DeleteTOTP(ctx context.Context, tgHash string) error
+	ReplaceRecoveryCodes(ctx context.Context, tgHash string, hashes []string) error
+	UseRecoveryCode(ctx context.Context, tgHash, hash string, at time.Time) (bool, error)
+	RecoveryCodesLeft(ctx context.Context, tgHash string) (int, error)
+	StepUpSession(ctx context.Context, id string, at time.Time) error
+}
+
+// TwoFactorPolicy - второй фактор для прав администратора. Администратор с
+// включенным TOTP (или любой администратор при RequiredForAdmins) получает роль
+// admin, только если подтвердил код не раньше StepUpTTL назад; без заведенного
+// фактора при RequiredForAdmins он роль не получает вовсе. После
+// MaxFailures неверных кодов подряд фактор блокируется на LockoutDuration.
+type TwoFactorPolicy struct {
+	Enabled           bool
+	RequiredForAdmins bool
+	StepUpTTL         time.Duration
+	Issuer            string
+	MaxFailures       int
+	LockoutDuration   time.Duration
+}
+
+// TwoFactorStatus возвращает состояние второго фактора владельца токена.
+func (a Auth) TwoFactorStatus(ctx context.Context, token string) (status models.TwoFactorStatus, err error) {
+	ctx, span := tracing.Start(ctx, "auth.TwoFactorStatus")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.sessionClaims(ctx, token)
+	if err != nil {
+		return models.TwoFactorStatus{}, fmt.Errorf("app.TwoFactorStatus: %w", err)
+	}
+	status, err = a.twoFactorStatus(ctx, claims.Subject, claims.SessionID)
+	if err != nil {
+		return models.TwoFactorStatus{}, fmt.Errorf("app.TwoFactorStatus: %w", err)
+	}
+	return status, nil
+}
+
+// EnrollTOTP заводит новый секрет TOTP. Фактор начинает действовать после
+// ConfirmTOTP, до этого EnrollTOTP можно повторять - прежний секрет заменяется.
+// Сессия должна быть создана не раньше StepUpTTL назад: кто первым заведет
+// фактор, тот и будет его владельцем, поэтому одного украденного токена для
+// этого мало - нужен свежий вход.
+func (a Auth) EnrollTOTP(ctx context.Context, token string) (enrollment models.TOTPEnrollment, err error) {
+	ctx, span := tracing.Start(ctx, "auth.EnrollTOTP")
+	defer func() { tracing.End(span, err) }()
+
+	if !a.twoFactor.Enabled {
+		return models.TOTPEnrollment{}, fmt.Errorf("app.EnrollTOTP: %w: %q", ErrUnknownProvider, providerTOTP)
+	}
+	claims, err := a.sessionClaims(ctx, token)
+	if err != nil {
+		return models.TOTPEnrollment{}, fmt.Errorf("app.EnrollTOTP: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditTOTPEnrollStarted, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	session, err := a.sessions.Session(ctx, claims.SessionID)
+	if err != nil {
+		return models.TOTPEnrollment{}, fmt.Errorf("app.EnrollTOTP: %w", err)
+	}
+	if time.Since(session.CreatedAt) > a.twoFactor.StepUpTTL {
+		return models.TOTPEnrollment{}, fmt.Errorf("app.EnrollTOTP: %w", ErrReauthRequired)
+	}
+	user, err := a.userProvider.User(ctx, claims.Subject)
+	if err != nil {
+		return models.TOTPEnrollment{}, fmt.Errorf("app.EnrollTOTP: %w", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return models.TOTPEnrollment{}, fmt.Errorf("app.EnrollTOTP: %w", err)
+	}
+	sealed, err := crypto.Seal(totpSealPurpose, []byte(secret))
+	if err != nil {
+		return models.TOTPEnrollment{}, fmt.Errorf("app.EnrollTOTP: %w", err)
+	}
+	factor := models.TOTPFactor{UserID: claims.Subject, Secret: sealed, CreatedAt: time.Now().UTC()}
+	if err := a.factors.SaveTOTP(ctx, factor); err != nil {
+		return models.TOTPEnrollment{}, fmt.Errorf("app.EnrollTOTP: %w", err)
+	}
+	return models.TOTPEnrollment{Secret: secret, URI: totp.URI(a.twoFactor.Issuer, accountLabel(user), secret)}, nil
+}
+
+// ConfirmTOTP включает заведенный фактор первым кодом из аутентификатора и
+// выдает коды восстановления. Сессия токена сразу считается подтвержденной.
+func (a Auth) ConfirmTOTP(ctx context.Context, token, code string) (codes models.RecoveryCodes, err error) {
+	ctx, span := tracing.Start(ctx, "auth.ConfirmTOTP")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.sessionClaims(ctx, token)
+	if err != nil {
+		return models.RecoveryCodes{}, fmt.Errorf("app.ConfirmTOTP: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditTOTPEnrolled, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	if !a.twoFactor.Enabled {
+		return models.RecoveryCodes{}, fmt.Errorf("app.ConfirmTOTP: %w: %q", ErrUnknownProvider, providerTOTP)
+	}
+	factor, err := a.factors.TOTP(ctx, sub)
+	if err != nil {
+		return models.RecoveryCodes{}, fmt.Errorf("app.ConfirmTOTP: %w", err)
+	}
+	if factor.Confirmed() {
+		return models.RecoveryCodes{}, fmt.Errorf("app.ConfirmTOTP: %w", storage.ErrTOTPExists)
+	}
+	now := time.Now().UTC()
+	if err := a.checkLockout(factor, now); err != nil {
+		return models.RecoveryCodes{}, fmt.Errorf("app.ConfirmTOTP: %w", err)
+	}
+	step, ok, err := a.validateTOTP(factor, code, now)
+	if err != nil {
+		return models.RecoveryCodes{}, fmt.Errorf("app.ConfirmTOTP: %w", err)
+	}
+	if !ok {
+		return models.RecoveryCodes{}, fmt.Errorf("app.ConfirmTOTP: %w", a.otpFailure(ctx, sub, now))
+	}
+
+	codes, hashes := newRecoveryCodes()
+	if err := a.factors.ConfirmTOTP(ctx, sub, step, now, hashes); err != nil {
+		return models.RecoveryCodes{}, fmt.Errorf("app.ConfirmTOTP: %w", err)
+	}
+	if err := a.factors.StepUpSession(ctx, claims.SessionID, now); err != nil {
+		return models.RecoveryCodes{}, fmt.Errorf("app.ConfirmTOTP: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "totp enrolled", slog.String("op", "app.ConfirmTOTP"))
+	return codes, nil
+}
+
+// DisableTOTP выключает второй фактор. Нужен действующий код: украденной
+// сессии недостаточно, чтобы снять защиту.
+func (a Auth) DisableTOTP(ctx context.Context, token, code string) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.DisableTOTP")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.sessionClaims(ctx, token)
+	if err != nil {
+		return fmt.Errorf("app.DisableTOTP: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditTOTPDisabled, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	factor, err := a.confirmedFactor(ctx, sub)
+	if err != nil {
+		return fmt.Errorf("app.DisableTOTP: %w", err)
+	}
+	if err := a.verifyOTP(ctx, factor, code); err != nil {
+		return fmt.Errorf("app.DisableTOTP: %w", err)
+	}
+	if err := a.factors.DeleteTOTP(ctx, sub); err != nil {
+		return fmt.Errorf("app.DisableTOTP: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "totp disabled", slog.String("op", "app.DisableTOTP"))
+	return nil
+}
+
+// StepUp подтверждает второй фактор в сессии токена кодом TOTP или кодом
+// восстановления. На StepUpTTL сессия получает права администратора, а
+// команды модератора в боте становятся доступны.
+func (a Auth) StepUp(ctx context.Context, token, code string) (status models.TwoFactorStatus, err error) {
+	ctx, span := tracing.Start(ctx, "auth.StepUp")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.sessionClaims(ctx, token)
+	if err != nil {
+		return models.TwoFactorStatus{}, fmt.Errorf("app.StepUp: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditStepUp, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	factor, err := a.confirmedFactor(ctx, sub)
+	if err != nil {
+		return models.TwoFactorStatus{}, fmt.Errorf("app.StepUp: %w", err)
+	}
+	if err := a.verifyOTP(ctx, factor, code); err != nil {
+		return models.TwoFactorStatus{}, fmt.Errorf("app.StepUp: %w", err)
+	}
+	if err := a.factors.StepUpSession(ctx, claims.SessionID, time.Now().UTC()); err != nil {
+		return models.TwoFactorStatus{}, fmt.Errorf("app.StepUp: %w", err)
+	}
+	status, err = a.twoFactorStatus(ctx, sub, claims.SessionID)
+	if err != nil {
+		return models.TwoFactorStatus{}, fmt.Errorf("app.StepUp: %w", err)
+	}
+	return status, nil
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления новыми. Прежние коды,
+// в том числе неиспользованные, перестают действовать.
+func (a Auth) RegenerateRecoveryCodes(ctx context.Context, token, code string) (codes models.RecoveryCodes, err error) {
+	ctx, span := tracing.Start(ctx, "auth.RegenerateRecoveryCodes")
+	defer func() { tracing.End(span, err) }()
+
+	claims, err := a.sessionClaims(ctx, token)
+	if err != nil {
+		return models.RecoveryCodes{}, fmt.Errorf("app.RegenerateRecoveryCodes: %w", err)
+	}
+	sub := claims.Subject
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditRecoveryCodes, Actor: sub, Subject: sub, AppID: claims.ServiceID}, err)
+	}()
+
+	factor, err := a.confirmedFactor(ctx, sub)
+	if err != nil {
+		return models.RecoveryCodes{}, fmt.Errorf("app.RegenerateRecoveryCodes: %w", err)
+	}
+	if err := a.verifyOTP(ctx, factor, code); err != nil {
+		return models.RecoveryCodes{}, fmt.Errorf("app.RegenerateRecoveryCodes: %w", err)
+	}
+	codes, hashes := newRecoveryCodes()
+	if err := a.factors.ReplaceRecoveryCodes(ctx, sub, hashes); err != nil {
+		return models.RecoveryCodes{}, fmt.Errorf("app.RegenerateRecoveryCodes: %w", err)
+	}
+	return codes, nil
+}
+
+// adminStepUp сообщает, нужен ли пользователю sub второй фактор для прав
+// администратора, и возвращает его фактор, если он заведен.
+func (a Auth) adminStepUp(ctx context.Context, sub string, isAdmin bool) (models.TOTPFactor, bool, error) {
+	if !a.twoFactor.Enabled || !isAdmin {
+		return models.TOTPFactor{}, false, nil
+	}
+	factor, err := a.factors.TOTP(ctx, sub)
+	if errors.Is(err, storage.ErrTOTPNotFound) {
+		return models.TOTPFactor{}, a.twoFactor.RequiredForAdmins, nil
+	}
+	if err != nil {
+		return models.TOTPFactor{}, false, err
+	}
+	return factor, factor.Confirmed() || a.twoFactor.RequiredForAdmins, nil
+}
+
+// adminCheckStepUp требует otp от администратора sub, если ему нужен второй
+// фактор. Проверка кода пишется в журнал как step_up.
+func (a Auth) adminCheckStepUp(ctx context.Context, sub, otp string) (err error) {
+	factor, required, err := a.adminStepUp(ctx, sub, true)
+	if err != nil || !required {
+		return err
+	}
+	if !factor.Confirmed() {
+		return fmt.Errorf("%w: enroll TOTP first", ErrStepUpRequired)
+	}
+	if otp == "" {
+		return fmt.Errorf("%w: otp is missing", ErrStepUpRequired)
+	}
+	defer func() {
+		a.record(ctx, models.AuditEvent{Type: models.AuditStepUp, Actor: sub, Subject: sub}, err)
+	}()
+	return a.verifyOTP(ctx, factor, otp)
+}
+
+// sessionSteppedUp сообщает, что в сессии sid второй фактор подтвержден не
+// раньше StepUpTTL назад.
+func (a Auth) sessionSteppedUp(ctx context.Context, sid string) (bool, error) {
+	if sid == "" {
+		return false, nil
+	}
+	session, err := a.sessions.Session(ctx, sid)
+	if err != nil {
+		return false, err
+	}
+	return a.steppedUp(session.MFAAt), nil
+}
+
+func (a Auth) steppedUp(at *time.Time) bool {
+	return at != nil && time.Since(*at) < a.twoFactor.StepUpTTL
+}
+
+func (a Auth) twoFactorStatus(ctx context.Context, sub, sid string) (models.TwoFactorStatus, error) {
+	user, err := a.userProvider.User(ctx, sub)
+	if err != nil {
+		return models.TwoFactorStatus{}, err
+	}
+	factor, required, err := a.adminStepUp(ctx, sub, user.IsAdmin)
+	if err != nil {
+		return models.TwoFactorStatus{}, err
+	}
+	status := models.TwoFactorStatus{Required: required}
+	if !factor.Confirmed() {
+		return status, nil
+	}
+	status.Enabled, status.EnrolledAt = true, factor.ConfirmedAt
+	if status.RecoveryCodesLeft, err = a.factors.RecoveryCodesLeft(ctx, sub); err != nil {
+		return models.TwoFactorStatus{}, err
+	}
+	session, err := a.sessions.Session(ctx, sid)
+	if err != nil {
+		return models.TwoFactorStatus{}, err
+	}
+	if a.steppedUp(session.MFAAt) {
+		until := session.MFAAt.Add(a.twoFactor.StepUpTTL)
+		status.StepUpUntil = &until
+	}
+	return status, nil
+}
+
+// confirmedFactor возвращает включенный фактор пользователя sub.
+func (a Auth) confirmedFactor(ctx context.Context, sub string) (models.TOTPFactor, error) {
+	if !a.twoFactor.Enabled {
+		return models.TOTPFactor{}, fmt.Errorf("%w: %q", ErrUnknownProvider, providerTOTP)
+	}
+	factor, err := a.factors.TOTP(ctx, sub)
+	if err != nil {
+		return models.TOTPFactor{}, err
+	}
+	if !factor.Confirmed() {
+		return models.TOTPFactor{}, fmt.Errorf("Второй фактор не подтвержден: %w", storage.ErrTOTPNotFound)
+	}
+	return factor, nil
+}
+
+// verifyOTP принимает код из аутентификатора или код восстановления
+// включенного фактора. Каждый код действует один раз.
+func (a Auth) verifyOTP(ctx context.Context, factor models.TOTPFactor, code string) error {
+	now := time.Now().UTC()
+	if err := a.checkLockout(factor, now); err != nil {
+		return err
+	}
+
+	var ok bool
+	if code = strings.TrimSpace(code); isTOTPCode(code) {
+		step, valid, err := a.validateTOTP(factor, code, now)
+		if err != nil {
+			return err
+		}
+		if valid {
+			if ok, err = a.factors.UseTOTPStep(ctx, factor.UserID, step, now); err != nil {
+				return err
+			}
+		}
+	} else if code != "" {
+		var err error
+		if ok, err = a.factors.UseRecoveryCode(ctx, factor.UserID, hashRecoveryCode(code), now); err != nil {
+			return err
+		}
+	}
+	if !ok {
+		return a.otpFailure(ctx, factor.UserID, now)
+	}
+	return nil
+}
+
+func (a Auth) checkLockout(factor models.TOTPFactor, now time.Time) error {
+	if factor.LockedUntil != nil && now.Before(*factor.LockedUntil) {
+		return fmt.Errorf("%w: too many invalid codes", ErrAccountLocked)
+	}
+	return nil
+}
+
+func (a Auth) validateTOTP(factor models.TOTPFactor, code string, now time.Time) (int64, bool, error) {
+	secret, err := crypto.Open(totpSealPurpose, factor.Secret)
+	if err != nil {
+		return 0, false, err
+	}
+	return totp.Validate(string(secret), code, now)
+}
+
+// otpFailure считает неверный код и возвращает ErrInvalidOTP или, если
+// попытки кончились, ErrAccountLocked.
+func (a Auth) otpFailure(ctx context.Context, sub string, now time.Time) error {
+	locked, err := a.factors.RecordOTPFailure(ctx, sub, a.twoFactor.MaxFailures, now.Add(a.twoFactor.LockoutDuration))
+	if err != nil {
+		return err
+	}
+	if locked {
+		sl.FromContext(ctx, a.log).WarnContext(ctx, "second factor locked",
+			slog.Duration("lockout", a.twoFactor.LockoutDuration))
+		return fmt.Errorf("%w: too many invalid codes", ErrAccountLocked)
+	}
+	return ErrInvalidOTP
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRecoveryCodes возвращает коды для пользователя и их хэши для хранения.
+// Код - 10 символов base32 в виде xxxxx-xxxxx.
+func newRecoveryCodes() (models.RecoveryCodes, []string) {
+	codes := models.RecoveryCodes{Codes: make([]string, recoveryCodeCount)}
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes.Codes {
+		b := make([]byte, 8)
+		crand.Read(b)
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+		codes.Codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+	return codes, hashes
+}
+
+// hashRecoveryCode не зависит от регистра и дефисов: код вводят вручную.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// accountLabel - подпись записи в аутентификаторе.
+func accountLabel(user models.User) string {
+	switch {
+	case user.Username != "":
+		return "@" + user.Username
+	case user.FirstName != "":
+		return user.FirstName
+	default:
+		return "account"
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/crypto"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/storage/memory"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEnrollTOTPRequiresRecentLogin(t *testing.T) {
+	if !crypto.Initialized() {
+		crypto.InitCrypto("twofactor-test")
+	}
+	ctx := context.Background()
+	st := memory.New()
+	if err := st.SaveUser(ctx, "admin", models.User{FirstName: "Pavel", IsAdmin: true}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	app, err := st.App(ctx, 1)
+	if err != nil {
+		t.Fatalf("App: %v", err)
+	}
+	a := newTestAuth(st, Config{TokenTTL: time.Hour, TwoFactor: TwoFactorPolicy{Enabled: true, RequiredForAdmins: true, StepUpTTL: 15 * time.Minute, Issuer: "SSO"}})
+
+	sessionToken := func(id string, createdAt time.Time) string {
+		t.Helper()
+		if err := st.CreateSession(ctx, models.Session{ID: id, UserID: "admin", AppID: 1, CreatedAt: createdAt, LastSeenAt: time.Now().UTC()}); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		token, err := jwt.NewToken(nil, "admin", app, id, time.Hour)
+		if err != nil {
+			t.Fatalf("NewToken: %v", err)
+		}
+		return token
+	}
+
+	// Давняя сессия - например, украденный токен - фактор не заводит.
+	stale := sessionToken("stale", time.Now().UTC().Add(-time.Hour))
+	if _, err := a.EnrollTOTP(ctx, stale); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("EnrollTOTP with stale session error = %v, want %v", err, ErrReauthRequired)
+	}
+	if _, err := st.TOTP(ctx, "admin"); err == nil {
+		t.Error("TOTP factor saved for stale session")
+	}
+
+	fresh := sessionToken("fresh", time.Now().UTC())
+	if _, err := a.EnrollTOTP(ctx, fresh); err != nil {
+		t.Fatalf("EnrollTOTP with fresh session: %v", err)
+	}
+
+	events, err := st.AuditEvents(ctx, models.AuditFilter{Type: models.AuditTOTPEnrollStarted, Limit: 10})
+	if err != nil {
+		t.Fatalf("AuditEvents: %v", err)
+	}
+	outcomes := map[string]int{}
+	for _, e := range events {
+		outcomes[e.Outcome]++
+	}
+	if outcomes[models.AuditSuccess] != 1 || outcomes[models.AuditFailure] != 1 {
+		t.Errorf("totp_enroll_started audit outcomes = %v, want one success and one failure", outcomes)
+	}
+}
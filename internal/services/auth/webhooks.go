@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// MaxDeliveryPage - наибольший размер страницы списка доставок.
+const MaxDeliveryPage = 500
+
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// OutboxStore - события outbox и подписки приложений на них.
+type OutboxStore interface {
+	Events(ctx context.Context, appID, afterID int64, limit int) ([]models.OutboxEvent, error)
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	Webhooks(ctx context.Context, appID int64) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, appID, id int64) error
+	Deliveries(ctx context.Context, appID int64, status string, afterID int64, limit int) ([]models.Delivery, error)
+	RedeliverDelivery(ctx context.Context, appID, id int64, now time.Time) error
+}
+
+// newEvent собирает событие outbox. data - UserEvent или SessionEvent, их
+// сериализация не падает.
+func newEvent(eventType, sub string, appID int64, data any) models.OutboxEvent {
+	raw, _ := json.Marshal(data)
+	return models.OutboxEvent{Type: eventType, Subject: sub, AppID: appID, Data: raw, CreatedAt: time.Now().UTC()}
+}
+
+func userEvent(sub string, user models.User) models.UserEvent {
+	return models.UserEvent{
+		Sub:            sub,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		Username:       user.Username,
+		UserNameLocale: user.UserNameLocale,
+		PhotoURL:       user.PhotoURL,
+		IsBanned:       user.IsBanned,
+		Roles:          user.Roles(),
+	}
+}
+
+// CreateWebhook подписывает приложение caller на события events.
+func (a Auth) CreateWebhook(ctx context.Context, caller models.Caller, rawURL string, events []string) (webhook models.Webhook, err error) {
+	ctx, span := tracing.Start(ctx, "auth.CreateWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateWebhookURL(rawURL); err != nil {
+		return models.Webhook{}, fmt.Errorf("app.CreateWebhook: %w", err)
+	}
+	if len(events) == 0 {
+		return models.Webhook{}, fmt.Errorf("app.CreateWebhook: %w: events are required", ErrInvalidWebhook)
+	}
+	for _, e := range events {
+		if !slices.Contains(models.EventTypes, e) {
+			return models.Webhook{}, fmt.Errorf("app.CreateWebhook: %w: unknown event %q", ErrInvalidWebhook, e)
+		}
+	}
+	events = slices.Compact(slices.Sorted(slices.Values(events)))
+
+	webhook, err = a.outbox.CreateWebhook(ctx, models.Webhook{
+		AppID:     caller.AppID,
+		URL:       rawURL,
+		Events:    events,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("app.CreateWebhook: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "webhook created",
+		slog.String("op", "app.CreateWebhook"), slog.Int64("caller_app", caller.AppID), slog.Int64("webhook_id", webhook.ID))
+	return webhook, nil
+}
+
+// Webhooks возвращает подписки приложения caller.
+func (a Auth) Webhooks(ctx context.Context, caller models.Caller) (webhooks []models.Webhook, err error) {
+	ctx, span := tracing.Start(ctx, "auth.Webhooks")
+	defer func() { tracing.End(span, err) }()
+
+	webhooks, err = a.outbox.Webhooks(ctx, caller.AppID)
+	if err != nil {
+		return nil, fmt.Errorf("app.Webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет подписку приложения caller вместе с ее доставками.
+func (a Auth) DeleteWebhook(ctx context.Context, caller models.Caller, id int64) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.DeleteWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	if err := a.outbox.DeleteWebhook(ctx, caller.AppID, id); err != nil {
+		return fmt.Errorf("app.DeleteWebhook: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "webhook deleted",
+		slog.String("op", "app.DeleteWebhook"), slog.Int64("caller_app", caller.AppID), slog.Int64("webhook_id", id))
+	return nil
+}
+
+// Deliveries возвращает страницу доставок приложения caller в статусе status.
+func (a Auth) Deliveries(ctx context.Context, caller models.Caller, status string, afterID int64, limit int) (deliveries []models.Delivery, err error) {
+	ctx, span := tracing.Start(ctx, "auth.Deliveries")
+	defer func() { tracing.End(span, err) }()
+
+	switch status {
+	case models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		return nil, fmt.Errorf("app.Deliveries: %w: unknown status %q", ErrInvalidWebhook, status)
+	}
+	if limit <= 0 || limit > MaxDeliveryPage {
+		limit = MaxDeliveryPage
+	}
+	deliveries, err = a.outbox.Deliveries(ctx, caller.AppID, status, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("app.Deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RedeliverDelivery ставит доставку приложения caller в очередь заново
+// с обнуленным счетчиком попыток.
+func (a Auth) RedeliverDelivery(ctx context.Context, caller models.Caller, id int64) (err error) {
+	ctx, span := tracing.Start(ctx, "auth.RedeliverDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	if err := a.outbox.RedeliverDelivery(ctx, caller.AppID, id, time.Now().UTC()); err != nil {
+		return fmt.Errorf("app.RedeliverDelivery: %w", err)
+	}
+	sl.FromContext(ctx, a.log).InfoContext(ctx, "delivery requeued",
+		slog.String("op", "app.RedeliverDelivery"), slog.Int64("caller_app", caller.AppID), slog.Int64("delivery_id", id))
+	return nil
+}
+
+// validateWebhookURL допускает только https. http разрешен для loopback,
+// чтобы вебхуки можно было принимать при локальной разработке.
+func validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: url must be absolute", ErrInvalidWebhook)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: url must use https", ErrInvalidWebhook)
+}
@@ -0,0 +1,208 @@
+// Package webhooks доставляет события outbox в вебхуки приложений: раскладывает
+// новые события по подпискам, отправляет подписанные POST-запросы и повторяет
+// неудачные доставки с экспоненциальной задержкой.
+package webhooks
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/logger/sl"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/tracing"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Заголовки запроса доставки. Подпись - "sha256=" + hex(HMAC-SHA256(secret,
+// timestamp + "." + body)), где secret - секрет приложения.
+const (
+	HeaderEvent     = "X-SSO-Event"
+	HeaderDelivery  = "X-SSO-Delivery"
+	HeaderTimestamp = "X-SSO-Timestamp"
+	HeaderSignature = "X-SSO-Signature"
+)
+
+// maxErrorLen ограничивает last_error, чтобы тело ошибки не раздувало таблицу.
+const maxErrorLen = 500
+
+type Store interface {
+	DispatchEvents(ctx context.Context, now time.Time, limit int) (int, error)
+	ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.Delivery, error)
+	UpdateDelivery(ctx context.Context, delivery models.Delivery) error
+}
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Timeout      time.Duration
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+}
+
+type Dispatcher struct {
+	log    *slog.Logger
+	store  Store
+	client *http.Client
+	cfg    Config
+}
+
+func New(log *slog.Logger, store Store, cfg Config) *Dispatcher {
+	return &Dispatcher{
+		log:    log.With(slog.String("op", "webhooks.Dispatcher")),
+		store:  store,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+	}
+}
+
+// Run опрашивает outbox каждые PollInterval до отмены ctx.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.poll(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) poll(ctx context.Context) {
+	for {
+		n, err := d.store.DispatchEvents(ctx, time.Now().UTC(), d.cfg.BatchSize)
+		if err != nil {
+			d.log.Error("failed to dispatch events", sl.Err(err))
+			return
+		}
+		if n < d.cfg.BatchSize {
+			break
+		}
+	}
+
+	// Все доставки пачки отправляются параллельно, поэтому аренды на два
+	// таймаута хватает с запасом.
+	now := time.Now().UTC()
+	deliveries, err := d.store.ClaimDeliveries(ctx, now, now.Add(2*d.cfg.Timeout), d.cfg.BatchSize)
+	if err != nil {
+		d.log.Error("failed to claim deliveries", sl.Err(err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+// deliver отправляет одну доставку и сохраняет результат попытки.
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.Delivery) {
+	ctx, span := tracing.Start(ctx, "webhooks.deliver")
+	status, err := d.send(ctx, delivery)
+	tracing.End(span, err)
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.NextAttemptAt = now
+	delivery.UpdatedAt = now
+	delivery.LastError = ""
+
+	result := models.DeliveryDelivered
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryDelivered
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = models.DeliveryDead
+		result = models.DeliveryDead
+	default:
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+		result = "retry"
+	}
+	if err != nil {
+		delivery.LastError = truncate(err.Error(), maxErrorLen)
+		d.log.Warn("webhook delivery failed",
+			slog.Int64("delivery_id", delivery.ID), slog.Int("attempts", delivery.Attempts),
+			slog.String("status", delivery.Status), sl.Err(err))
+	}
+	metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+
+	if err := d.store.UpdateDelivery(ctx, delivery); err != nil {
+		d.log.Error("failed to save delivery", slog.Int64("delivery_id", delivery.ID), sl.Err(err))
+	}
+}
+
+// send возвращает HTTP-статус ответа. Успехом считается любой 2xx.
+func (d *Dispatcher) send(ctx context.Context, delivery models.Delivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, fmt.Errorf("encode event: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "auth-sso-webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff - задержка перед попыткой attempts+1: BackoffBase * 2^(attempts-1), не больше BackoffMax.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.BackoffBase
+	for i := 1; i < attempts && delay < d.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.BackoffMax)
+}
+
+// Sign вычисляет значение заголовка X-SSO-Signature.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,383 @@
+// Package instrumented оборачивает любой бэкенд хранилища и снимает метрики
+// и спаны по каждому вызову, не трогая код самих бэкендов.
+package instrumented
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/lib/metrics"
+	"auth-service/internal/lib/tracing"
+	"auth-service/internal/storage"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Storage struct {
+	next storage.Storage
+}
+
+func New(next storage.Storage) *Storage {
+	return &Storage{next: next}
+}
+
+func (s *Storage) SaveUser(ctx context.Context, tgId string, User models.User, events ...models.OutboxEvent) (err error) {
+	ctx, end := observe(ctx, "SaveUser")
+	defer end(&err)
+	return s.next.SaveUser(ctx, tgId, User, events...)
+}
+
+func (s *Storage) ValidateUser(ctx context.Context, tgHash string) (_ models.UserResponse, err error) {
+	ctx, end := observe(ctx, "ValidateUser")
+	defer end(&err)
+	return s.next.ValidateUser(ctx, tgHash)
+}
+
+func (s *Storage) IsAdmin(ctx context.Context, tgHash string) (_ bool, err error) {
+	ctx, end := observe(ctx, "IsAdmin")
+	defer end(&err)
+	return s.next.IsAdmin(ctx, tgHash)
+}
+
+func (s *Storage) User(ctx context.Context, tgHash string) (_ models.User, err error) {
+	ctx, end := observe(ctx, "User")
+	defer end(&err)
+	return s.next.User(ctx, tgHash)
+}
+
+func (s *Storage) UserByUsername(ctx context.Context, username string) (_ models.User, err error) {
+	ctx, end := observe(ctx, "UserByUsername")
+	defer end(&err)
+	return s.next.UserByUsername(ctx, username)
+}
+
+func (s *Storage) SetBanned(ctx context.Context, tgHash string, banned bool, events ...models.OutboxEvent) (err error) {
+	ctx, end := observe(ctx, "SetBanned")
+	defer end(&err)
+	return s.next.SetBanned(ctx, tgHash, banned, events...)
+}
+
+func (s *Storage) SetAdmin(ctx context.Context, tgHash string, admin bool, events ...models.OutboxEvent) (err error) {
+	ctx, end := observe(ctx, "SetAdmin")
+	defer end(&err)
+	return s.next.SetAdmin(ctx, tgHash, admin, events...)
+}
+
+func (s *Storage) UpdateProfile(ctx context.Context, tgHash string, profile models.User, events ...models.OutboxEvent) (_ bool, err error) {
+	ctx, end := observe(ctx, "UpdateProfile")
+	defer end(&err)
+	return s.next.UpdateProfile(ctx, tgHash, profile, events...)
+}
+
+func (s *Storage) App(ctx context.Context, serviceId int64) (_ models.App, err error) {
+	ctx, end := observe(ctx, "App")
+	defer end(&err)
+	return s.next.App(ctx, serviceId)
+}
+
+func (s *Storage) Apps(ctx context.Context) (_ []models.App, err error) {
+	ctx, end := observe(ctx, "Apps")
+	defer end(&err)
+	return s.next.Apps(ctx)
+}
+
+func (s *Storage) CreateSession(ctx context.Context, session models.Session) (err error) {
+	ctx, end := observe(ctx, "CreateSession")
+	defer end(&err)
+	return s.next.CreateSession(ctx, session)
+}
+
+func (s *Storage) Session(ctx context.Context, id string) (_ models.Session, err error) {
+	ctx, end := observe(ctx, "Session")
+	defer end(&err)
+	return s.next.Session(ctx, id)
+}
+
+func (s *Storage) Sessions(ctx context.Context, tgHash string) (_ []models.Session, err error) {
+	ctx, end := observe(ctx, "Sessions")
+	defer end(&err)
+	return s.next.Sessions(ctx, tgHash)
+}
+
+func (s *Storage) TouchSession(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, end := observe(ctx, "TouchSession")
+	defer end(&err)
+	return s.next.TouchSession(ctx, id, at)
+}
+
+func (s *Storage) RevokeSession(ctx context.Context, tgHash, id string, at time.Time, events ...models.OutboxEvent) (err error) {
+	ctx, end := observe(ctx, "RevokeSession")
+	defer end(&err)
+	return s.next.RevokeSession(ctx, tgHash, id, at, events...)
+}
+
+func (s *Storage) SessionHistory(ctx context.Context, tgHash string) (_ []models.Session, err error) {
+	ctx, end := observe(ctx, "SessionHistory")
+	defer end(&err)
+	return s.next.SessionHistory(ctx, tgHash)
+}
+
+func (s *Storage) AppMemberships(ctx context.Context, tgHash string) (_ []models.Membership, err error) {
+	ctx, end := observe(ctx, "AppMemberships")
+	defer end(&err)
+	return s.next.AppMemberships(ctx, tgHash)
+}
+
+func (s *Storage) ScheduleErasure(ctx context.Context, tgHash string, eraseAt *time.Time) (err error) {
+	ctx, end := observe(ctx, "ScheduleErasure")
+	defer end(&err)
+	return s.next.ScheduleErasure(ctx, tgHash, eraseAt)
+}
+
+func (s *Storage) ClaimErasures(ctx context.Context, now time.Time, limit int) (_ []string, err error) {
+	ctx, end := observe(ctx, "ClaimErasures")
+	defer end(&err)
+	return s.next.ClaimErasures(ctx, now, limit)
+}
+
+func (s *Storage) EraseUser(ctx context.Context, tgHash, pseudonym string, now time.Time, events ...models.OutboxEvent) (err error) {
+	ctx, end := observe(ctx, "EraseUser")
+	defer end(&err)
+	return s.next.EraseUser(ctx, tgHash, pseudonym, now, events...)
+}
+
+func (s *Storage) CreateHandoff(ctx context.Context, handoff models.Handoff) (err error) {
+	ctx, end := observe(ctx, "CreateHandoff")
+	defer end(&err)
+	return s.next.CreateHandoff(ctx, handoff)
+}
+
+func (s *Storage) Handoff(ctx context.Context, code string, now time.Time) (_ models.Handoff, err error) {
+	ctx, end := observe(ctx, "Handoff")
+	defer end(&err)
+	return s.next.Handoff(ctx, code, now)
+}
+
+func (s *Storage) ApproveHandoff(ctx context.Context, code, tgHash string, now time.Time) (_ models.Handoff, err error) {
+	ctx, end := observe(ctx, "ApproveHandoff")
+	defer end(&err)
+	return s.next.ApproveHandoff(ctx, code, tgHash, now)
+}
+
+func (s *Storage) ClaimHandoff(ctx context.Context, deviceHash string, now time.Time) (_ models.Handoff, err error) {
+	ctx, end := observe(ctx, "ClaimHandoff")
+	defer end(&err)
+	return s.next.ClaimHandoff(ctx, deviceHash, now)
+}
+
+func (s *Storage) LinkIdentity(ctx context.Context, tgHash string, identity models.Identity) (_ models.Identity, err error) {
+	ctx, end := observe(ctx, "LinkIdentity")
+	defer end(&err)
+	return s.next.LinkIdentity(ctx, tgHash, identity)
+}
+
+func (s *Storage) Identities(ctx context.Context, tgHash string) (_ []models.Identity, err error) {
+	ctx, end := observe(ctx, "Identities")
+	defer end(&err)
+	return s.next.Identities(ctx, tgHash)
+}
+
+func (s *Storage) UnlinkIdentity(ctx context.Context, tgHash, provider string) (err error) {
+	ctx, end := observe(ctx, "UnlinkIdentity")
+	defer end(&err)
+	return s.next.UnlinkIdentity(ctx, tgHash, provider)
+}
+
+func (s *Storage) UserByIdentity(ctx context.Context, provider, subject string) (_ models.User, err error) {
+	ctx, end := observe(ctx, "UserByIdentity")
+	defer end(&err)
+	return s.next.UserByIdentity(ctx, provider, subject)
+}
+
+func (s *Storage) SaveEmailUser(ctx context.Context, tgHash string, user models.User, identity models.Identity, events ...models.OutboxEvent) (_ models.Identity, err error) {
+	ctx, end := observe(ctx, "SaveEmailUser")
+	defer end(&err)
+	return s.next.SaveEmailUser(ctx, tgHash, user, identity, events...)
+}
+
+func (s *Storage) EmailIdentity(ctx context.Context, email string) (_ models.Identity, err error) {
+	ctx, end := observe(ctx, "EmailIdentity")
+	defer end(&err)
+	return s.next.EmailIdentity(ctx, email)
+}
+
+func (s *Storage) RecordLoginFailure(ctx context.Context, id int64, maxFailures int, lockUntil time.Time) (_ bool, err error) {
+	ctx, end := observe(ctx, "RecordLoginFailure")
+	defer end(&err)
+	return s.next.RecordLoginFailure(ctx, id, maxFailures, lockUntil)
+}
+
+func (s *Storage) ResetLoginFailures(ctx context.Context, id int64) (err error) {
+	ctx, end := observe(ctx, "ResetLoginFailures")
+	defer end(&err)
+	return s.next.ResetLoginFailures(ctx, id)
+}
+
+func (s *Storage) CreateEmailToken(ctx context.Context, token models.EmailToken) (err error) {
+	ctx, end := observe(ctx, "CreateEmailToken")
+	defer end(&err)
+	return s.next.CreateEmailToken(ctx, token)
+}
+
+func (s *Storage) VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (_ models.Identity, err error) {
+	ctx, end := observe(ctx, "VerifyEmail")
+	defer end(&err)
+	return s.next.VerifyEmail(ctx, tokenHash, now)
+}
+
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (_ models.Identity, err error) {
+	ctx, end := observe(ctx, "ResetPassword")
+	defer end(&err)
+	return s.next.ResetPassword(ctx, tokenHash, passwordHash, now)
+}
+
+func (s *Storage) SaveTOTP(ctx context.Context, factor models.TOTPFactor) (err error) {
+	ctx, end := observe(ctx, "SaveTOTP")
+	defer end(&err)
+	return s.next.SaveTOTP(ctx, factor)
+}
+
+func (s *Storage) TOTP(ctx context.Context, tgHash string) (_ models.TOTPFactor, err error) {
+	ctx, end := observe(ctx, "TOTP")
+	defer end(&err)
+	return s.next.TOTP(ctx, tgHash)
+}
+
+func (s *Storage) ConfirmTOTP(ctx context.Context, tgHash string, step int64, at time.Time, recoveryHashes []string) (err error) {
+	ctx, end := observe(ctx, "ConfirmTOTP")
+	defer end(&err)
+	return s.next.ConfirmTOTP(ctx, tgHash, step, at, recoveryHashes)
+}
+
+func (s *Storage) UseTOTPStep(ctx context.Context, tgHash string, step int64, at time.Time) (_ bool, err error) {
+	ctx, end := observe(ctx, "UseTOTPStep")
+	defer end(&err)
+	return s.next.UseTOTPStep(ctx, tgHash, step, at)
+}
+
+func (s *Storage) RecordOTPFailure(ctx context.Context, tgHash string, maxFailures int, lockUntil time.Time) (_ bool, err error) {
+	ctx, end := observe(ctx, "RecordOTPFailure")
+	defer end(&err)
+	return s.next.RecordOTPFailure(ctx, tgHash, maxFailures, lockUntil)
+}
+
+func (s *Storage) DeleteTOTP(ctx context.Context, tgHash string) (err error) {
+	ctx, end := observe(ctx, "DeleteTOTP")
+	defer end(&err)
+	return s.next.DeleteTOTP(ctx, tgHash)
+}
+
+func (s *Storage) ReplaceRecoveryCodes(ctx context.Context, tgHash string, hashes []string) (err error) {
+	ctx, end := observe(ctx, "ReplaceRecoveryCodes")
+	defer end(&err)
+	return s.next.ReplaceRecoveryCodes(ctx, tgHash, hashes)
+}
+
+func (s *Storage) UseRecoveryCode(ctx context.Context, tgHash, hash string, at time.Time) (_ bool, err error) {
+	ctx, end := observe(ctx, "UseRecoveryCode")
+	defer end(&err)
+	return s.next.UseRecoveryCode(ctx, tgHash, hash, at)
+}
+
+func (s *Storage) RecoveryCodesLeft(ctx context.Context, tgHash string) (_ int, err error) {
+	ctx, end := observe(ctx, "RecoveryCodesLeft")
+	defer end(&err)
+	return s.next.RecoveryCodesLeft(ctx, tgHash)
+}
+
+func (s *Storage) StepUpSession(ctx context.Context, id string, at time.Time) (err error) {
+	ctx, end := observe(ctx, "StepUpSession")
+	defer end(&err)
+	return s.next.StepUpSession(ctx, id, at)
+}
+
+func (s *Storage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) (err error) {
+	ctx, end := observe(ctx, "SaveAuditEvent")
+	defer end(&err)
+	return s.next.SaveAuditEvent(ctx, event)
+}
+
+func (s *Storage) AuditEvents(ctx context.Context, filter models.AuditFilter) (_ []models.AuditEvent, err error) {
+	ctx, end := observe(ctx, "AuditEvents")
+	defer end(&err)
+	return s.next.AuditEvents(ctx, filter)
+}
+
+func (s *Storage) Events(ctx context.Context, appID, afterID int64, limit int) (_ []models.OutboxEvent, err error) {
+	ctx, end := observe(ctx, "Events")
+	defer end(&err)
+	return s.next.Events(ctx, appID, afterID, limit)
+}
+
+func (s *Storage) CreateWebhook(ctx context.Context, webhook models.Webhook) (_ models.Webhook, err error) {
+	ctx, end := observe(ctx, "CreateWebhook")
+	defer end(&err)
+	return s.next.CreateWebhook(ctx, webhook)
+}
+
+func (s *Storage) Webhooks(ctx context.Context, appID int64) (_ []models.Webhook, err error) {
+	ctx, end := observe(ctx, "Webhooks")
+	defer end(&err)
+	return s.next.Webhooks(ctx, appID)
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, appID, id int64) (err error) {
+	ctx, end := observe(ctx, "DeleteWebhook")
+	defer end(&err)
+	return s.next.DeleteWebhook(ctx, appID, id)
+}
+
+func (s *Storage) DispatchEvents(ctx context.Context, now time.Time, limit int) (_ int, err error) {
+	ctx, end := observe(ctx, "DispatchEvents")
+	defer end(&err)
+	return s.next.DispatchEvents(ctx, now, limit)
+}
+
+func (s *Storage) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) (_ []models.Delivery, err error) {
+	ctx, end := observe(ctx, "ClaimDeliveries")
+	defer end(&err)
+	return s.next.ClaimDeliveries(ctx, now, leaseUntil, limit)
+}
+
+func (s *Storage) UpdateDelivery(ctx context.Context, delivery models.Delivery) (err error) {
+	ctx, end := observe(ctx, "UpdateDelivery")
+	defer end(&err)
+	return s.next.UpdateDelivery(ctx, delivery)
+}
+
+func (s *Storage) Deliveries(ctx context.Context, appID int64, status string, afterID int64, limit int) (_ []models.Delivery, err error) {
+	ctx, end := observe(ctx, "Deliveries")
+	defer end(&err)
+	return s.next.Deliveries(ctx, appID, status, afterID, limit)
+}
+
+func (s *Storage) RedeliverDelivery(ctx context.Context, appID, id int64, now time.Time) (err error) {
+	ctx, end := observe(ctx, "RedeliverDelivery")
+	defer end(&err)
+	return s.next.RedeliverDelivery(ctx, appID, id, now)
+}
+
+func (s *Storage) Ping(ctx context.Context) (err error) {
+	ctx, end := observe(ctx, "Ping")
+	defer end(&err)
+	return s.next.Ping(ctx)
+}
+
+func (s *Storage) Close() {
+	s.next.Close()
+}
+
+// observe открывает спан storage.<op> и по завершении пишет длительность в метрики.
+func observe(ctx context.Context, op string) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "storage."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.operation.name", op)),
+	)
+
+	return ctx, func(err *error) {
+		metrics.ObserveStorage(op, start, err)
+		tracing.End(span, *err)
+	}
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+)
+
+func (s *Storage) SessionHistory(ctx context.Context, tgHash string) ([]models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := []models.Session{}
+	for _, session := range s.sessions {
+		if session.UserID == tgHash {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+func (s *Storage) AppMemberships(ctx context.Context, tgHash string) ([]models.Membership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	memberships := []models.Membership{}
+	for _, appID := range slices.Sorted(maps.Keys(s.members[tgHash])) {
+		memberships = append(memberships, models.Membership{AppID: appID, FirstSeenAt: s.members[tgHash][appID]})
+	}
+	return memberships, nil
+}
+
+func (s *Storage) ScheduleErasure(ctx context.Context, tgHash string, eraseAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[tgHash]
+	if !ok {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	user.EraseAt = eraseAt
+	s.users[tgHash] = user
+	delete(s.eraseAttempts, tgHash)
+	return nil
+}
+
+func (s *Storage) ClaimErasures(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []models.User
+	for _, user := range s.users {
+		if user.EraseAt != nil && !user.EraseAt.After(now) {
+			due = append(due, user)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		ai, iok := s.eraseAttempts[due[i].TgId]
+		aj, jok := s.eraseAttempts[due[j].TgId]
+		if iok != jok {
+			return !iok
+		}
+		if !ai.Equal(aj) {
+			return ai.Before(aj)
+		}
+		return due[i].EraseAt.Before(*due[j].EraseAt)
+	})
+
+	subs := []string{}
+	for _, user := range due[:min(len(due), limit)] {
+		s.eraseAttempts[user.TgId] = now
+		subs = append(subs, user.TgId)
+	}
+	return subs, nil
+}
+
+func (s *Storage) EraseUser(ctx context.Context, tgHash, pseudonym string, now time.Time, events ...models.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[tgHash]
+	if !ok || user.EraseAt == nil || user.EraseAt.After(now) {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+
+	for i := range s.audit {
+		e := &s.audit[i]
+		if e.Actor != tgHash && e.Subject != tgHash {
+			continue
+		}
+		if e.Actor == tgHash {
+			e.Actor = pseudonym
+		}
+		if e.Subject == tgHash {
+			e.Subject = pseudonym
+		}
+		e.IP = ""
+	}
+	scrubbed, _ := json.Marshal(map[string]string{"sub": pseudonym})
+	for i := range s.outbox {
+		e := &s.outbox[i].event
+		if e.Subject != tgHash {
+			continue
+		}
+		e.Subject = pseudonym
+		if slices.Contains(models.ProfileEventTypes, e.Type) {
+			e.Data = scrubbed
+			continue
+		}
+		var data map[string]any
+		if err := json.Unmarshal(e.Data, &data); err == nil {
+			data["sub"] = pseudonym
+			e.Data, _ = json.Marshal(data)
+		}
+	}
+	if apps, ok := s.members[tgHash]; ok {
+		s.members[pseudonym] = apps
+		delete(s.members, tgHash)
+	}
+	for id, session := range s.sessions {
+		if session.UserID == tgHash {
+			delete(s.sessions, id)
+		}
+	}
+	for code, handoff := range s.handoffs {
+		if handoff.UserID == tgHash {
+			delete(s.handoffs, code)
+		}
+	}
+	for id, identity := range s.identities {
+		if identity.UserID == tgHash {
+			s.deleteIdentity(id)
+		}
+	}
+	delete(s.totp, tgHash)
+	delete(s.recoveryCodes, tgHash)
+	delete(s.users, tgHash)
+	delete(s.eraseAttempts, tgHash)
+	s.appendEvents(events)
+	return nil
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *Storage) CreateHandoff(ctx context.Context, handoff models.Handoff) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apps[handoff.AppID]; !ok {
+		return fmt.Errorf("Клиент сервис не найден: %w", storage.ErrAppNotFound)
+	}
+	for code, h := range s.handoffs {
+		if !h.ExpiresAt.After(handoff.CreatedAt) {
+			delete(s.handoffs, code)
+		}
+	}
+	s.handoffs[handoff.Code] = handoff
+	return nil
+}
+
+func (s *Storage) Handoff(ctx context.Context, code string, now time.Time) (models.Handoff, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	handoff, ok := s.handoffs[code]
+	if !ok || handoff.UserID != "" || !handoff.ExpiresAt.After(now) {
+		return models.Handoff{}, fmt.Errorf("Код входа не найден: %w", storage.ErrHandoffNotFound)
+	}
+	return handoff, nil
+}
+
+func (s *Storage) ApproveHandoff(ctx context.Context, code, tgHash string, now time.Time) (models.Handoff, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	handoff, ok := s.handoffs[code]
+	if !ok || handoff.UserID != "" || !handoff.ExpiresAt.After(now) {
+		return models.Handoff{}, fmt.Errorf("Код входа не найден: %w", storage.ErrHandoffNotFound)
+	}
+	if _, ok := s.users[tgHash]; !ok {
+		return models.Handoff{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	handoff.UserID, handoff.ApprovedAt = tgHash, &now
+	s.handoffs[code] = handoff
+	return handoff, nil
+}
+
+func (s *Storage) ClaimHandoff(ctx context.Context, deviceHash string, now time.Time) (models.Handoff, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for code, handoff := range s.handoffs {
+		if handoff.DeviceHash != deviceHash || !handoff.ExpiresAt.After(now) {
+			continue
+		}
+		if handoff.UserID != "" {
+			delete(s.handoffs, code)
+		}
+		return handoff, nil
+	}
+	return models.Handoff{}, fmt.Errorf("Код входа не найден: %w", storage.ErrHandoffNotFound)
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"fmt"
+	"sort"
+)
+
+func (s *Storage) LinkIdentity(ctx context.Context, tgHash string, identity models.Identity) (models.Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[tgHash]; !ok {
+		return models.Identity{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	for _, i := range s.identities {
+		if i.Provider == identity.Provider && (i.Subject == identity.Subject || i.UserID == tgHash) {
+			return models.Identity{}, fmt.Errorf("Учетная запись уже привязана: %w", storage.ErrIdentityExists)
+		}
+	}
+	s.nextIdentityID++
+	identity.ID, identity.UserID = s.nextIdentityID, tgHash
+	s.identities[identity.ID] = identity
+	return identity, nil
+}
+
+func (s *Storage) Identities(ctx context.Context, tgHash string) ([]models.Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	identities := []models.Identity{}
+	for _, i := range s.identities {
+		if i.UserID == tgHash {
+			identities = append(identities, i)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].ID < identities[j].ID })
+	return identities, nil
+}
+
+func (s *Storage) UnlinkIdentity(ctx context.Context, tgHash, provider string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, i := range s.identities {
+		if i.UserID == tgHash && i.Provider == provider {
+			s.deleteIdentity(id)
+			return nil
+		}
+	}
+	return fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+}
+
+func (s *Storage) UserByIdentity(ctx context.Context, provider, subject string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, i := range s.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return s.users[i.UserID], nil
+		}
+	}
+	return models.User{}, fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+}
+
+// deleteIdentity удаляет учетную запись вместе с ее токенами из писем, как
+// ON DELETE CASCADE в postgres и sqlite.
+func (s *Storage) deleteIdentity(id int64) {
+	delete(s.identities, id)
+	for hash, token := range s.emailTokens {
+		if token.IdentityID == id {
+			delete(s.emailTokens, hash)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package memory
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage хранит пользователей и приложения в памяти процесса.
+// Повторяет семантику postgres.Storage и подходит для локального запуска и тестов.
+type Storage struct {
+	mu       sync.RWMutex
+	users    map[string]models.User
+	apps     map[int64]models.App
+	sessions map[string]models.Session
+	// members - приложения, в которые входил пользователь, и время первого
+	// входа, как app_members.
+	members  map[string]map[int64]time.Time
+	handoffs map[string]models.Handoff
+	// eraseAttempts - время последней попытки удаления, как erase_attempt_at.
+	eraseAttempts map[string]time.Time
+	audit         []models.AuditEvent
+	nextID        int64
+
+	identities     map[int64]models.Identity
+	emailTokens    map[string]models.EmailToken
+	nextIdentityID int64
+
+	totp          map[string]models.TOTPFactor
+	recoveryCodes map[string]map[string]bool
+
+	outbox         []outboxEntry
+	webhooks       map[int64]models.Webhook
+	deliveries     []models.Delivery
+	nextWebhookID  int64
+	nextDeliveryID int64
+}
+
+// New создает хранилище с тем же тестовым приложением, что добавляет миграция 2_add_app.
+func New() *Storage {
+	s := &Storage{
+		users:    make(map[string]models.User),
+		apps:     make(map[int64]models.App),
+		sessions: make(map[string]models.Session),
+		members:  make(map[string]map[int64]time.Time),
+		handoffs: make(map[string]models.Handoff),
+		webhooks: make(map[int64]models.Webhook),
+
+		eraseAttempts: make(map[string]time.Time),
+
+		identities:  make(map[int64]models.Identity),
+		emailTokens: make(map[string]models.EmailToken),
+
+		totp:          make(map[string]models.TOTPFactor),
+		recoveryCodes: make(map[string]map[string]bool),
+	}
+	s.apps[1] = models.App{ID: 1, Name: "test", Secret: "test-secret"}
+
+	return s
+}
+
+func (s *Storage) SaveApp(ctx context.Context, app models.App) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apps[int64(app.ID)] = app
+	return nil
+}
+
+func (s *Storage) SaveUser(ctx context.Context, tgId string, User models.User, events ...models.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[tgId]; ok {
+		return storage.ErrUserExist
+	}
+
+	s.nextID++
+	User.ID = strconv.FormatInt(s.nextID, 10)
+	User.TgId = tgId
+	User.LastLogin = time.Now()
+	User.IsBanned = false
+	s.users[tgId] = User
+	s.appendEvents(events)
+
+	return nil
+}
+
+func (s *Storage) ValidateUser(ctx context.Context, tgHash string) (models.UserResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[tgHash]
+	if !ok {
+		return models.UserResponse{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	if user.IsBanned {
+		return models.UserResponse{}, fmt.Errorf("Пользователь забанен: %w", storage.ErrUserBanned)
+	}
+
+	user.LastLogin = time.Now()
+	s.users[tgHash] = user
+
+	return toResponse(user), nil
+}
+
+func (s *Storage) IsAdmin(ctx context.Context, tgHash string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[tgHash]
+	if !ok {
+		return false, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	return user.IsAdmin, nil
+}
+
+func (s *Storage) User(ctx context.Context, tgHash string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[tgHash]
+	if !ok {
+		return models.User{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	return user, nil
+}
+
+func (s *Storage) UserByUsername(ctx context.Context, username string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var (
+		found models.User
+		ok    bool
+	)
+	for _, user := range s.users {
+		if strings.EqualFold(user.Username, username) && (!ok || user.LastLogin.After(found.LastLogin)) {
+			found, ok = user, true
+		}
+	}
+	if !ok {
+		return models.User{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	return found, nil
+}
+
+func (s *Storage) SetBanned(ctx context.Context, tgHash string, banned bool, events ...models.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[tgHash]
+	if !ok {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	user.IsBanned = banned
+	s.users[tgHash] = user
+	s.appendEvents(events)
+
+	return nil
+}
+
+func (s *Storage) SetAdmin(ctx context.Context, tgHash string, admin bool, events ...models.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[tgHash]
+	if !ok {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	user.IsAdmin = admin
+	s.users[tgHash] = user
+	s.appendEvents(events)
+
+	return nil
+}
+
+func (s *Storage) UpdateProfile(ctx context.Context, tgHash string, profile models.User, events ...models.OutboxEvent) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[tgHash]
+	if !ok {
+		return false, nil
+	}
+	if user.FirstName == profile.FirstName && user.LastName == profile.LastName &&
+		user.Username == profile.Username && user.PhotoURL == profile.PhotoURL {
+		return false, nil
+	}
+	user.FirstName, user.LastName = profile.FirstName, profile.LastName
+	user.Username, user.PhotoURL = profile.Username, profile.PhotoURL
+	s.users[tgHash] = user
+	s.appendEvents(events)
+
+	return true, nil
+}
+
+func (s *Storage) App(ctx context.Context, serviceId int64) (models.App, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.apps[serviceId]
+	if !ok {
+		return models.App{}, fmt.Errorf("Клиент сервис не найден: %w", storage.ErrAppNotFound)
+	}
+	return app, nil
+}
+
+func (s *Storage) Apps(ctx context.Context) ([]models.App, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	apps := make([]models.App, 0, len(s.apps))
+	for _, app := range s.apps {
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].ID < apps[j].ID })
+	return apps, nil
+}
+
+func (s *Storage) CreateSession(ctx context.Context, session models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[session.UserID]; !ok {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	s.sessions[session.ID] = session
+	if s.members[session.UserID] == nil {
+		s.members[session.UserID] = make(map[int64]time.Time)
+	}
+	if _, ok := s.members[session.UserID][session.AppID]; !ok {
+		s.members[session.UserID][session.AppID] = session.CreatedAt
+	}
+	return nil
+}
+
+func (s *Storage) Session(ctx context.Context, id string) (models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return models.Session{}, fmt.Errorf("Сессия не найдена: %w", storage.ErrSessionNotFound)
+	}
+	return session, nil
+}
+
+func (s *Storage) Sessions(ctx context.Context, tgHash string) ([]models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := []models.Session{}
+	for _, session := range s.sessions {
+		if session.UserID == tgHash && session.Active() {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+func (s *Storage) TouchSession(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, ok := s.sessions[id]; ok && session.LastSeenAt.Before(at) {
+		session.LastSeenAt = at
+		s.sessions[id] = session
+	}
+	return nil
+}
+
+func (s *Storage) RevokeSession(ctx context.Context, tgHash, id string, at time.Time, events ...models.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || session.UserID != tgHash || !session.Active() {
+		return fmt.Errorf("Сессия не найдена: %w", storage.ErrSessionNotFound)
+	}
+	session.RevokedAt = &at
+	s.sessions[id] = session
+	s.appendEvents(events)
+	return nil
+}
+
+func (s *Storage) StepUpSession(ctx context.Context, id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || !session.Active() {
+		return fmt.Errorf("Сессия не найдена: %w", storage.ErrSessionNotFound)
+	}
+	session.MFAAt = &at
+	s.sessions[id] = session
+	return nil
+}
+
+func (s *Storage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event.ID = int64(len(s.audit) + 1)
+	s.audit = append(s.audit, event)
+	return nil
+}
+
+func (s *Storage) AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := []models.AuditEvent{}
+	for _, e := range s.audit {
+		if len(events) >= filter.Limit {
+			break
+		}
+		switch {
+		case e.ID <= filter.AfterID,
+			filter.User != "" && e.Subject != filter.User && e.Actor != filter.User,
+			filter.AppID != 0 && e.AppID != filter.AppID,
+			filter.Type != "" && e.Type != filter.Type,
+			!filter.Since.IsZero() && e.CreatedAt.Before(filter.Since),
+			!filter.Until.IsZero() && !e.CreatedAt.Before(filter.Until):
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Storage) Close() {}
+
+func toResponse(user models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:             user.ID,
+		TgId:           user.TgId,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		Username:       user.Username,
+		UserNameLocale: user.UserNameLocale,
+		PhotoURL:       user.PhotoURL,
+		IsBanned:       user.IsBanned,
+	}
+}
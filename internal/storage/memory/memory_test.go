@@ -0,0 +1,11 @@
+package memory_test
+
+import (
+	"auth-service/internal/storage/memory"
+	"auth-service/internal/storage/storagetest"
+	"testing"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage { return memory.New() })
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (s *Storage) SaveEmailUser(ctx context.Context, tgHash string, user models.User, identity models.Identity, events ...models.OutboxEvent) (models.Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[tgHash]; ok {
+		return models.Identity{}, storage.ErrUserExist
+	}
+	for id, i := range s.identities {
+		if i.Provider != identity.Provider || i.Subject != identity.Subject {
+			continue
+		}
+		if i.VerifiedAt != nil || !s.linked(i) {
+			return models.Identity{}, fmt.Errorf("Учетная запись уже привязана: %w", storage.ErrIdentityExists)
+		}
+		s.deleteIdentity(id)
+	}
+
+	s.nextID++
+	s.users[tgHash] = models.User{ID: strconv.FormatInt(s.nextID, 10), TgId: tgHash, FirstName: user.FirstName}
+	s.nextIdentityID++
+	identity.ID, identity.UserID = s.nextIdentityID, tgHash
+	s.identities[identity.ID] = identity
+	s.appendEvents(events)
+	return identity, nil
+}
+
+func (s *Storage) EmailIdentity(ctx context.Context, email string) (models.Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, i := range s.identities {
+		if i.Provider == models.ProviderEmail && i.Subject == email {
+			return i, nil
+		}
+	}
+	return models.Identity{}, fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+}
+
+func (s *Storage) RecordLoginFailure(ctx context.Context, id int64, maxFailures int, lockUntil time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, ok := s.identities[id]
+	if !ok {
+		return false, fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+	}
+	identity.FailedLogins++
+	locked := identity.FailedLogins >= maxFailures
+	if locked {
+		identity.FailedLogins, identity.LockedUntil = 0, &lockUntil
+	}
+	s.identities[id] = identity
+	return locked, nil
+}
+
+func (s *Storage) ResetLoginFailures(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if identity, ok := s.identities[id]; ok {
+		identity.FailedLogins, identity.LockedUntil = 0, nil
+		s.identities[id] = identity
+	}
+	return nil
+}
+
+func (s *Storage) CreateEmailToken(ctx context.Context, token models.EmailToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.identities[token.IdentityID]; !ok {
+		return fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+	}
+	for hash, t := range s.emailTokens {
+		if !t.ExpiresAt.After(token.CreatedAt) || (t.IdentityID == token.IdentityID && t.Purpose == token.Purpose) {
+			delete(s.emailTokens, hash)
+		}
+	}
+	s.emailTokens[token.Hash] = token
+	return nil
+}
+
+func (s *Storage) VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (models.Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.consumeEmailToken(tokenHash, models.EmailTokenVerify, now)
+	if err != nil {
+		return models.Identity{}, err
+	}
+	if identity.VerifiedAt == nil {
+		identity.VerifiedAt = &now
+	}
+	s.identities[identity.ID] = identity
+	return identity, nil
+}
+
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (models.Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, err := s.consumeEmailToken(tokenHash, models.EmailTokenReset, now)
+	if err != nil {
+		return models.Identity{}, err
+	}
+	identity.PasswordHash = passwordHash
+	if identity.VerifiedAt == nil && !s.linked(identity) {
+		identity.VerifiedAt = &now
+	}
+	identity.FailedLogins, identity.LockedUntil = 0, nil
+	s.identities[identity.ID] = identity
+	return identity, nil
+}
+
+// linked сообщает, привязана ли учетная запись к аккаунту с другим способом
+// входа, как linkedIdentity в postgres и sqlite.
+func (s *Storage) linked(identity models.Identity) bool {
+	if !strings.HasPrefix(identity.UserID, models.AccountPrefix) {
+		return true
+	}
+	for _, other := range s.identities {
+		if other.UserID == identity.UserID && other.ID != identity.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) consumeEmailToken(tokenHash, purpose string, now time.Time) (models.Identity, error) {
+	token, ok := s.emailTokens[tokenHash]
+	if !ok || token.Purpose != purpose || !token.ExpiresAt.After(now) {
+		return models.Identity{}, fmt.Errorf("Токен не найден: %w", storage.ErrEmailTokenNotFound)
+	}
+	delete(s.emailTokens, tokenHash)
+	return s.identities[token.IdentityID], nil
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"fmt"
+	"time"
+)
+
+func (s *Storage) SaveTOTP(ctx context.Context, factor models.TOTPFactor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[factor.UserID]; !ok {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	if existing, ok := s.totp[factor.UserID]; ok && existing.Confirmed() {
+		return storage.ErrTOTPExists
+	}
+	s.totp[factor.UserID] = models.TOTPFactor{UserID: factor.UserID, Secret: factor.Secret, CreatedAt: factor.CreatedAt}
+	return nil
+}
+
+func (s *Storage) TOTP(ctx context.Context, tgHash string) (models.TOTPFactor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	factor, ok := s.totp[tgHash]
+	if !ok {
+		return models.TOTPFactor{}, fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+	}
+	return factor, nil
+}
+
+func (s *Storage) ConfirmTOTP(ctx context.Context, tgHash string, step int64, at time.Time, recoveryHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	factor, ok := s.totp[tgHash]
+	if !ok || factor.Confirmed() {
+		return fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+	}
+	factor.ConfirmedAt, factor.LastVerifiedAt, factor.LastStep = &at, &at, step
+	factor.FailedAttempts, factor.LockedUntil = 0, nil
+	s.totp[tgHash] = factor
+	s.replaceRecoveryCodes(tgHash, recoveryHashes)
+	return nil
+}
+
+func (s *Storage) UseTOTPStep(ctx context.Context, tgHash string, step int64, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	factor, ok := s.totp[tgHash]
+	if !ok || factor.LastStep >= step {
+		return false, nil
+	}
+	factor.LastStep, factor.LastVerifiedAt = step, &at
+	factor.FailedAttempts, factor.LockedUntil = 0, nil
+	s.totp[tgHash] = factor
+	return true, nil
+}
+
+func (s *Storage) RecordOTPFailure(ctx context.Context, tgHash string, maxFailures int, lockUntil time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	factor, ok := s.totp[tgHash]
+	if !ok {
+		return false, fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+	}
+	factor.FailedAttempts++
+	locked := factor.FailedAttempts >= maxFailures
+	if locked {
+		factor.FailedAttempts, factor.LockedUntil = 0, &lockUntil
+	}
+	s.totp[tgHash] = factor
+	return locked, nil
+}
+
+func (s *Storage) DeleteTOTP(ctx context.Context, tgHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.totp[tgHash]; !ok {
+		return fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+	}
+	delete(s.totp, tgHash)
+	delete(s.recoveryCodes, tgHash)
+	return nil
+}
+
+func (s *Storage) ReplaceRecoveryCodes(ctx context.Context, tgHash string, hashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[tgHash]; !ok {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	s.replaceRecoveryCodes(tgHash, hashes)
+	return nil
+}
+
+func (s *Storage) UseRecoveryCode(ctx context.Context, tgHash, hash string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := s.recoveryCodes[tgHash]
+	if used, ok := codes[hash]; !ok || used {
+		return false, nil
+	}
+	codes[hash] = true
+	if factor, ok := s.totp[tgHash]; ok {
+		factor.LastVerifiedAt = &at
+		factor.FailedAttempts, factor.LockedUntil = 0, nil
+		s.totp[tgHash] = factor
+	}
+	return true, nil
+}
+
+func (s *Storage) RecoveryCodesLeft(ctx context.Context, tgHash string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, used := range s.recoveryCodes[tgHash] {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// replaceRecoveryCodes хранит для каждого хэша кода, использован ли он.
+func (s *Storage) replaceRecoveryCodes(tgHash string, hashes []string) {
+	codes := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		codes[hash] = false
+	}
+	s.recoveryCodes[tgHash] = codes
+}
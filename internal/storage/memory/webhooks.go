@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+type outboxEntry struct {
+	event      models.OutboxEvent
+	dispatched bool
+}
+
+// appendEvents добавляет события в outbox. Вызывается под s.mu.
+func (s *Storage) appendEvents(events []models.OutboxEvent) {
+	for _, e := range events {
+		e.ID = int64(len(s.outbox) + 1)
+		s.outbox = append(s.outbox, outboxEntry{event: e})
+	}
+}
+
+func (s *Storage) Events(ctx context.Context, appID, afterID int64, limit int) ([]models.OutboxEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := []models.OutboxEvent{}
+	for _, entry := range s.outbox {
+		if len(events) >= limit {
+			break
+		}
+		if entry.event.ID > afterID && s.subscribed(appID, entry.event) {
+			events = append(events, entry.event)
+		}
+	}
+	return events, nil
+}
+
+func (s *Storage) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apps[webhook.AppID]; !ok {
+		return models.Webhook{}, fmt.Errorf("Клиент сервис не найден: %w", storage.ErrAppNotFound)
+	}
+	s.nextWebhookID++
+	webhook.ID = s.nextWebhookID
+	webhook.Events = slices.Clone(webhook.Events)
+	s.webhooks[webhook.ID] = webhook
+	return webhook, nil
+}
+
+func (s *Storage) Webhooks(ctx context.Context, appID int64) ([]models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhooks := []models.Webhook{}
+	for _, w := range s.webhooks {
+		if w.AppID == appID {
+			webhooks = append(webhooks, w)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, appID, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.webhooks[id]
+	if !ok || w.AppID != appID {
+		return fmt.Errorf("Вебхук не найден: %w", storage.ErrWebhookNotFound)
+	}
+	delete(s.webhooks, id)
+	s.deliveries = slices.DeleteFunc(s.deliveries, func(d models.Delivery) bool { return d.WebhookID == id })
+	return nil
+}
+
+func (s *Storage) DispatchEvents(ctx context.Context, now time.Time, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]int64, 0, len(s.webhooks))
+	for id := range s.webhooks {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	n := 0
+	for i := range s.outbox {
+		if n >= limit {
+			break
+		}
+		entry := &s.outbox[i]
+		if entry.dispatched {
+			continue
+		}
+		for _, id := range ids {
+			w := s.webhooks[id]
+			if !slices.Contains(w.Events, entry.event.Type) || !s.subscribed(w.AppID, entry.event) {
+				continue
+			}
+			s.nextDeliveryID++
+			s.deliveries = append(s.deliveries, models.Delivery{
+				ID:            s.nextDeliveryID,
+				WebhookID:     w.ID,
+				EventID:       entry.event.ID,
+				Status:        models.DeliveryPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			})
+		}
+		entry.dispatched = true
+		n++
+	}
+	return n, nil
+}
+
+// subscribed сообщает, видит ли приложение appID событие: оно произошло в нем
+// или пользователь входил в это приложение, даже если потом вышел.
+func (s *Storage) subscribed(appID int64, e models.OutboxEvent) bool {
+	_, member := s.members[e.Subject][appID]
+	return e.AppID == appID || member
+}
+
+func (s *Storage) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []int
+	for i, d := range s.deliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, i)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return s.deliveries[due[i]].NextAttemptAt.Before(s.deliveries[due[j]].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	var deliveries []models.Delivery
+	for _, i := range due {
+		s.deliveries[i].NextAttemptAt = leaseUntil
+		deliveries = append(deliveries, s.fill(s.deliveries[i], true))
+	}
+	return deliveries, nil
+}
+
+// fill дописывает в доставку данные вебхука и события, а при withSecret - секрет приложения.
+func (s *Storage) fill(d models.Delivery, withSecret bool) models.Delivery {
+	w := s.webhooks[d.WebhookID]
+	event := s.outbox[d.EventID-1].event
+	d.URL, d.EventType = w.URL, event.Type
+	if withSecret {
+		d.Event = event
+		d.Secret = s.apps[w.AppID].Secret
+	}
+	return d
+}
+
+func (s *Storage) UpdateDelivery(ctx context.Context, d models.Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.deliveries {
+		if s.deliveries[i].ID == d.ID {
+			stored := &s.deliveries[i]
+			stored.Status, stored.Attempts, stored.NextAttemptAt = d.Status, d.Attempts, d.NextAttemptAt
+			stored.LastError, stored.ResponseStatus, stored.UpdatedAt = d.LastError, d.ResponseStatus, d.UpdatedAt
+			break
+		}
+	}
+	return nil
+}
+
+func (s *Storage) Deliveries(ctx context.Context, appID int64, status string, afterID int64, limit int) ([]models.Delivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := []models.Delivery{}
+	for _, d := range s.deliveries {
+		if len(deliveries) >= limit {
+			break
+		}
+		if d.ID <= afterID || d.Status != status || s.webhooks[d.WebhookID].AppID != appID {
+			continue
+		}
+		deliveries = append(deliveries, s.fill(d, false))
+	}
+	return deliveries, nil
+}
+
+func (s *Storage) RedeliverDelivery(ctx context.Context, appID, id int64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.deliveries {
+		d := &s.deliveries[i]
+		if d.ID != id || s.webhooks[d.WebhookID].AppID != appID {
+			continue
+		}
+		d.Status, d.Attempts, d.NextAttemptAt = models.DeliveryPending, 0, now
+		d.LastError, d.ResponseStatus, d.UpdatedAt = "", 0, now
+		return nil
+	}
+	return fmt.Errorf("Доставка не найдена: %w", storage.ErrDeliveryNotFound)
+}
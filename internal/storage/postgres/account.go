@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SessionHistory возвращает все сессии пользователя, включая завершенные, новые первыми.
+func (s *Storage) SessionHistory(ctx context.Context, tgHash string) ([]models.Session, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE user_tgid = $1 ORDER BY created_at DESC`, tgHash)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения сессии: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// AppMemberships возвращает приложения из app_members, в которые входил
+// пользователь, с временем первого входа, по возрастанию id приложения.
+func (s *Storage) AppMemberships(ctx context.Context, tgHash string) ([]models.Membership, error) {
+	rows, err := s.db.Query(ctx, `SELECT app_id, joined_at FROM app_members WHERE user_tgid = $1 ORDER BY app_id`, tgHash)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	memberships := []models.Membership{}
+	for rows.Next() {
+		var m models.Membership
+		if err := rows.Scan(&m.AppID, &m.FirstSeenAt); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения участника приложения: %w", err)
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+// ScheduleErasure назначает удаление аккаунта на eraseAt, nil отменяет удаление.
+func (s *Storage) ScheduleErasure(ctx context.Context, tgHash string, eraseAt *time.Time) error {
+	tag, err := s.db.Exec(ctx, `UPDATE users SET erase_at = $2, erase_attempt_at = NULL WHERE tgid = $1`, tgHash, eraseAt)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// ClaimErasures возвращает до limit пользователей, срок удаления которых
+// наступил, и отмечает попытку удаления временем now. Сначала идут те, кого еще
+// не пытались удалить, затем - по давности последней попытки, поэтому аккаунт,
+// удаление которого падает, не задерживает остальные.
+func (s *Storage) ClaimErasures(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	rows, err := s.db.Query(ctx, `
+		UPDATE users SET erase_attempt_at = $1
+		WHERE tgid IN (
+			SELECT tgid FROM users WHERE erase_at <= $1
+			ORDER BY erase_attempt_at NULLS FIRST, erase_at
+			LIMIT $2 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING tgid`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []string{}
+	for rows.Next() {
+		var sub string
+		if err := rows.Scan(&sub); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения пользователя: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// EraseUser удаляет пользователя, срок удаления которого наступил к now, вместе
+// с сессиями. В журнале аудита, app_members и событиях outbox его sub
+// заменяется псевдонимом, из событий outbox стирается профиль. events пишутся
+// после замены и сохраняют свои данные. Если удаление отменили или аккаунта уже
+// нет - ErrUserNotFound.
+func (s *Storage) EraseUser(ctx context.Context, tgHash, pseudonym string, now time.Time, events ...models.OutboxEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `SELECT id FROM users WHERE tgid = $1 AND erase_at <= $2 FOR UPDATE`, tgHash, now).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE audit_events SET
+    actor = CASE WHEN actor = $1 THEN $2 ELSE actor END,
+    subject = CASE WHEN subject = $1 THEN $2 ELSE subject END,
+    ip = ''
+WHERE actor = $1 OR subject = $1`, tgHash, pseudonym)
+	if err != nil {
+		return fmt.Errorf("Ошибка обезличивания журнала аудита: %w", err)
+	}
+	_, err = tx.Exec(ctx, `UPDATE outbox_events SET subject = $2,
+    data = CASE WHEN type = ANY ($3) THEN jsonb_build_object('sub', $2::text)
+        ELSE jsonb_set(data, '{sub}', to_jsonb($2::text)) END
+WHERE subject = $1`, tgHash, pseudonym, models.ProfileEventTypes)
+	if err != nil {
+		return fmt.Errorf("Ошибка очистки outbox: %w", err)
+	}
+	_, err = tx.Exec(ctx, `UPDATE app_members SET user_tgid = $2 WHERE user_tgid = $1`, tgHash, pseudonym)
+	if err != nil {
+		return fmt.Errorf("Ошибка обезличивания участников приложений: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("Ошибка удаления пользователя: %w", err)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"auth-service/internal/domains/models"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func (s *Storage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	_, err := s.db.Exec(ctx, `INSERT INTO audit_events (type, actor, subject, app_id, ip, outcome, reason, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		event.Type, event.Actor, event.Subject, event.AppID, event.IP, event.Outcome, event.Reason, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("Ошибка записи события аудита: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var (
+		where []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if filter.User != "" {
+		p := arg(filter.User)
+		where = append(where, "(subject = "+p+" OR actor = "+p+")")
+	}
+	if filter.AppID != 0 {
+		where = append(where, "app_id = "+arg(filter.AppID))
+	}
+	if filter.Type != "" {
+		where = append(where, "type = "+arg(filter.Type))
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= "+arg(filter.Since))
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "created_at < "+arg(filter.Until))
+	}
+	where = append(where, "id > "+arg(filter.AfterID))
+
+	query := `SELECT id, type, actor, subject, app_id, ip, outcome, reason, created_at FROM audit_events
+WHERE ` + strings.Join(where, " AND ") + ` ORDER BY id LIMIT ` + arg(filter.Limit)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.Actor, &e.Subject, &e.AppID, &e.IP, &e.Outcome, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения события аудита: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const handoffColumns = `code, device_hash, app_id, COALESCE(user_tgid, ''), created_at, expires_at, approved_at, ip, user_agent, platform`
+
+// CreateHandoff сохраняет новый код входа и заодно удаляет истекшие.
+func (s *Storage) CreateHandoff(ctx context.Context, handoff models.Handoff) error {
+	if _, err := s.db.Exec(ctx, `DELETE FROM login_handoffs WHERE expires_at <= $1`, handoff.CreatedAt); err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	_, err := s.db.Exec(ctx, `INSERT INTO login_handoffs (code, device_hash, app_id, created_at, expires_at, ip, user_agent, platform)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		handoff.Code, handoff.DeviceHash, handoff.AppID, handoff.CreatedAt, handoff.ExpiresAt, handoff.IP, handoff.UserAgent, handoff.Platform)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return fmt.Errorf("Клиент сервис не найден: %w", storage.ErrAppNotFound)
+		}
+		return fmt.Errorf("Ошибка сохранения кода входа: %w", err)
+	}
+	return nil
+}
+
+// Handoff возвращает неистекший и еще не подтвержденный код входа.
+func (s *Storage) Handoff(ctx context.Context, code string, now time.Time) (models.Handoff, error) {
+	handoff, err := scanHandoff(s.db.QueryRow(ctx, `SELECT `+handoffColumns+` FROM login_handoffs
+WHERE code = $1 AND user_tgid IS NULL AND expires_at > $2`, code, now))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Handoff{}, fmt.Errorf("Код входа не найден: %w", storage.ErrHandoffNotFound)
+		}
+		return models.Handoff{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return handoff, nil
+}
+
+// ApproveHandoff привязывает неистекший и еще не подтвержденный код к пользователю.
+func (s *Storage) ApproveHandoff(ctx context.Context, code, tgHash string, now time.Time) (models.Handoff, error) {
+	handoff, err := scanHandoff(s.db.QueryRow(ctx, `UPDATE login_handoffs SET user_tgid = $2, approved_at = $3
+WHERE code = $1 AND user_tgid IS NULL AND expires_at > $3
+RETURNING `+handoffColumns, code, tgHash, now))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return models.Handoff{}, fmt.Errorf("Код входа не найден: %w", storage.ErrHandoffNotFound)
+		case errors.As(err, &pgErr) && pgErr.Code == "23503":
+			return models.Handoff{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return models.Handoff{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return handoff, nil
+}
+
+// ClaimHandoff отдает код по хэшу device_code. Подтвержденный код удаляется в
+// том же запросе, поэтому токены по нему получит только один опрос.
+func (s *Storage) ClaimHandoff(ctx context.Context, deviceHash string, now time.Time) (models.Handoff, error) {
+	handoff, err := scanHandoff(s.db.QueryRow(ctx, `DELETE FROM login_handoffs
+WHERE device_hash = $1 AND user_tgid IS NOT NULL AND expires_at > $2
+RETURNING `+handoffColumns, deviceHash, now))
+	if err == nil {
+		return handoff, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return models.Handoff{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+
+	handoff, err = scanHandoff(s.db.QueryRow(ctx, `SELECT `+handoffColumns+` FROM login_handoffs
+WHERE device_hash = $1 AND expires_at > $2`, deviceHash, now))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Handoff{}, fmt.Errorf("Код входа не найден: %w", storage.ErrHandoffNotFound)
+		}
+		return models.Handoff{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return handoff, nil
+}
+
+func scanHandoff(row pgx.Row) (models.Handoff, error) {
+	var h models.Handoff
+	err := row.Scan(&h.Code, &h.DeviceHash, &h.AppID, &h.UserID, &h.CreatedAt, &h.ExpiresAt, &h.ApprovedAt, &h.IP, &h.UserAgent, &h.Platform)
+	return h, err
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// LinkIdentity привязывает учетную запись провайдера к пользователю tgHash.
+func (s *Storage) LinkIdentity(ctx context.Context, tgHash string, identity models.Identity) (models.Identity, error) {
+	err := s.db.QueryRow(ctx, `INSERT INTO identities (user_id, provider, subject, email, created_at, password_hash, verified_at)
+SELECT id, $2, $3, $4, $5, $6, $7 FROM users WHERE tgid = $1
+RETURNING id`, tgHash, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt, identity.PasswordHash, identity.VerifiedAt).Scan(&identity.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return models.Identity{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			return models.Identity{}, fmt.Errorf("Учетная запись уже привязана: %w", storage.ErrIdentityExists)
+		}
+		return models.Identity{}, fmt.Errorf("Ошибка привязки учетной записи: %w", err)
+	}
+	identity.UserID = tgHash
+	return identity, nil
+}
+
+const identityColumns = `i.id, u.tgid, i.provider, i.subject, i.email, i.created_at, i.password_hash, i.verified_at, i.failed_logins, i.locked_until`
+
+func scanIdentity(row pgx.Row) (models.Identity, error) {
+	var i models.Identity
+	err := row.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt,
+		&i.PasswordHash, &i.VerifiedAt, &i.FailedLogins, &i.LockedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Identity{}, fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+		}
+		return models.Identity{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return i, nil
+}
+
+func (s *Storage) Identities(ctx context.Context, tgHash string) ([]models.Identity, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+identityColumns+`
+FROM identities i JOIN users u ON u.id = i.user_id
+WHERE u.tgid = $1 ORDER BY i.id`, tgHash)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []models.Identity{}
+	for rows.Next() {
+		i, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return identities, nil
+}
+
+func (s *Storage) UnlinkIdentity(ctx context.Context, tgHash, provider string) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM identities
+WHERE provider = $2 AND user_id = (SELECT id FROM users WHERE tgid = $1)`, tgHash, provider)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+	}
+	return nil
+}
+
+// UserByIdentity находит пользователя, к которому привязана учетная запись провайдера.
+func (s *Storage) UserByIdentity(ctx context.Context, provider, subject string) (models.User, error) {
+	user, err := scanUser(s.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users
+WHERE id = (SELECT user_id FROM identities WHERE provider = $1 AND subject = $2)`, provider, subject))
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return models.User{}, fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+	}
+	return user, err
+}
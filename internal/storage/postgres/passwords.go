@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// linkedIdentity - условие SQL: учетная запись i привязана к аккаунту u, у
+// которого есть другой способ входа, а не создана вместе с ним при регистрации
+// по email. Неподтвержденная такая учетная запись не доказывает, что адрес
+// принадлежит владельцу аккаунта.
+const linkedIdentity = `(u.tgid NOT LIKE '` + models.AccountPrefix + `%' OR EXISTS (SELECT 1 FROM identities o WHERE o.user_id = i.user_id AND o.id <> i.id))`
+
+// SaveEmailUser создает пользователя tgHash вместе с учетной записью email и в
+// той же транзакции пишет события outbox. Неподтвержденная учетная запись с тем
+// же адресом, привязанная к другому аккаунту, адрес не занимает и удаляется:
+// иначе ее владелец перехватил бы регистрацию настоящего владельца адреса.
+func (s *Storage) SaveEmailUser(ctx context.Context, tgHash string, user models.User, identity models.Identity, events ...models.OutboxEvent) (models.Identity, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int64
+	err = tx.QueryRow(ctx, `INSERT INTO users (tgid, first_name, last_name, user_name, user_name_locale, photo_url, is_admin)
+VALUES ($1, $2, '', '', '', '', false)
+RETURNING id`, tgHash, user.FirstName).Scan(&userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.Identity{}, storage.ErrUserExist
+		}
+		return models.Identity{}, fmt.Errorf("Ошибка транзакции: %w", err)
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM identities i USING users u
+WHERE u.id = i.user_id AND i.provider = $1 AND i.subject = $2 AND i.verified_at IS NULL AND `+linkedIdentity, identity.Provider, identity.Subject)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("Ошибка транзакции: %w", err)
+	}
+	err = tx.QueryRow(ctx, `INSERT INTO identities (user_id, provider, subject, email, created_at, password_hash, verified_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id`, userID, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt, identity.PasswordHash, identity.VerifiedAt).Scan(&identity.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.Identity{}, fmt.Errorf("Учетная запись уже привязана: %w", storage.ErrIdentityExists)
+		}
+		return models.Identity{}, fmt.Errorf("Ошибка транзакции: %w", err)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return models.Identity{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.Identity{}, fmt.Errorf("Ошибка комита: %w", err)
+	}
+	identity.UserID = tgHash
+	return identity, nil
+}
+
+// EmailIdentity находит учетную запись email по адресу в нижнем регистре.
+func (s *Storage) EmailIdentity(ctx context.Context, email string) (models.Identity, error) {
+	return scanIdentity(s.db.QueryRow(ctx, `SELECT `+identityColumns+`
+FROM identities i JOIN users u ON u.id = i.user_id
+WHERE i.provider = $1 AND i.subject = $2`, models.ProviderEmail, email))
+}
+
+// RecordLoginFailure увеличивает счетчик неудачных входов. На maxFailures-й
+// неудаче учетная запись блокируется до lockUntil, а счетчик обнуляется.
+func (s *Storage) RecordLoginFailure(ctx context.Context, id int64, maxFailures int, lockUntil time.Time) (bool, error) {
+	var locked bool
+	err := s.db.QueryRow(ctx, `UPDATE identities SET
+    failed_logins = CASE WHEN failed_logins + 1 >= $2 THEN 0 ELSE failed_logins + 1 END,
+    locked_until  = CASE WHEN failed_logins + 1 >= $2 THEN $3 ELSE locked_until END
+WHERE id = $1
+RETURNING failed_logins = 0`, id, maxFailures, lockUntil).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+		}
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return locked, nil
+}
+
+func (s *Storage) ResetLoginFailures(ctx context.Context, id int64) error {
+	_, err := s.db.Exec(ctx, `UPDATE identities SET failed_logins = 0, locked_until = NULL WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return nil
+}
+
+// CreateEmailToken сохраняет токен из письма. Прежние токены того же назначения
+// для этой учетной записи и все истекшие токены удаляются.
+func (s *Storage) CreateEmailToken(ctx context.Context, token models.EmailToken) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM email_tokens
+WHERE expires_at <= $3 OR (identity_id = $1 AND purpose = $2)`, token.IdentityID, token.Purpose, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO email_tokens (token_hash, identity_id, purpose, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5)`, token.Hash, token.IdentityID, token.Purpose, token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+		}
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// VerifyEmail погашает токен подтверждения и отмечает адрес подтвержденным.
+func (s *Storage) VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (models.Identity, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	id, err := consumeEmailToken(ctx, tx, tokenHash, models.EmailTokenVerify, now)
+	if err != nil {
+		return models.Identity{}, err
+	}
+	_, err = tx.Exec(ctx, `UPDATE identities SET verified_at = COALESCE(verified_at, $2) WHERE id = $1`, id, now)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return commitIdentity(ctx, tx, id)
+}
+
+// ResetPassword погашает токен сброса, меняет пароль и снимает блокировку входа.
+// Письмо со сбросом доказывает владение адресом, поэтому адрес заодно
+// подтверждается - кроме учетной записи, привязанной к аккаунту с другим
+// способом входа: владелец адреса не обязательно владелец этого аккаунта.
+func (s *Storage) ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (models.Identity, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	id, err := consumeEmailToken(ctx, tx, tokenHash, models.EmailTokenReset, now)
+	if err != nil {
+		return models.Identity{}, err
+	}
+	var linked bool
+	err = tx.QueryRow(ctx, `SELECT `+linkedIdentity+` FROM identities i JOIN users u ON u.id = i.user_id WHERE i.id = $1`, id).Scan(&linked)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	_, err = tx.Exec(ctx, `UPDATE identities
+SET password_hash = $2, verified_at = CASE WHEN $4 THEN verified_at ELSE COALESCE(verified_at, $3) END, failed_logins = 0, locked_until = NULL
+WHERE id = $1`, id, passwordHash, now, linked)
+	if err != nil {
+		return models.Identity{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return commitIdentity(ctx, tx, id)
+}
+
+func consumeEmailToken(ctx context.Context, tx pgx.Tx, tokenHash, purpose string, now time.Time) (int64, error) {
+	var id int64
+	err := tx.QueryRow(ctx, `DELETE FROM email_tokens
+WHERE token_hash = $1 AND purpose = $2 AND expires_at > $3
+RETURNING identity_id`, tokenHash, purpose, now).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("Токен не найден: %w", storage.ErrEmailTokenNotFound)
+		}
+		return 0, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return id, nil
+}
+
+func commitIdentity(ctx context.Context, tx pgx.Tx, id int64) (models.Identity, error) {
+	identity, err := scanIdentity(tx.QueryRow(ctx, `SELECT `+identityColumns+`
+FROM identities i JOIN users u ON u.id = i.user_id
+WHERE i.id = $1`, id))
+	if err != nil {
+		return models.Identity{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return models.Identity{}, fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return identity, nil
+}
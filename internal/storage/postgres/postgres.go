@@ -4,13 +4,14 @@ import (
 	"auth-service/internal/domains/models"
 	"auth-service/internal/storage"
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
 	"log"
+	"strconv"
 	"time"
 )
 
@@ -32,14 +33,17 @@ func (s *Storage) ValidateUser(ctx context.Context, tgHash string) (models.UserR
 	err = tx.QueryRow(ctx, `UPDATE users 
 SET last_login = NOW() 
 WHERE tgId = $1 
-RETURNING tgid, id, first_name, last_name, user_name, user_name_locale, photo_url`, tgHash).Scan(&user.TgId, &user.ID, &user.FirstName, &user.LastName, &user.Username, &user.UserNameLocale, &user.PhotoURL)
-	if errors.Is(err, sql.ErrNoRows) {
-		log.Println("Пользователь не найден")
-		return models.UserResponse{}, fmt.Errorf(" Пользователь не найден %s ", storage.ErrUserNotFound)
+RETURNING tgid, id::text, first_name, last_name, user_name, user_name_locale, photo_url, is_banned`, tgHash).Scan(&user.TgId, &user.ID, &user.FirstName, &user.LastName, &user.Username, &user.UserNameLocale, &user.PhotoURL, &user.IsBanned)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Println("Пользователь не найден")
+			return models.UserResponse{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return models.UserResponse{}, fmt.Errorf("Ошибка базы данных: %w", err)
 	}
 	if user.IsBanned {
 		log.Println("Пользователь забанен")
-		return models.UserResponse{}, fmt.Errorf("Пользователь забанен")
+		return models.UserResponse{}, fmt.Errorf("Пользователь забанен: %w", storage.ErrUserBanned)
 	}
 	if err := tx.Commit(ctx); err != nil {
 		return models.UserResponse{}, fmt.Errorf("Ошибка комита")
@@ -51,13 +55,16 @@ RETURNING tgid, id, first_name, last_name, user_name, user_name_locale, photo_ur
 func (s *Storage) IsAdmin(ctx context.Context, tgHash string) (bool, error) {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
-		return false, fmt.Errorf("Transaction Error", err)
+		return false, fmt.Errorf("Transaction Error: %w", err)
 	}
 	defer tx.Rollback(ctx)
 	var isAdmin bool
 	err = tx.QueryRow(ctx, `SELECT is_admin FROM users where tgid = $1`, tgHash).Scan(&isAdmin)
 	if err != nil {
-		return false, fmt.Errorf("IsAdmin Error: %v", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return false, fmt.Errorf("IsAdmin Error: %w", err)
 	}
 	if err := tx.Commit(ctx); err != nil {
 		fmt.Println("SaveUser", err)
@@ -67,6 +74,38 @@ func (s *Storage) IsAdmin(ctx context.Context, tgHash string) (bool, error) {
 	return isAdmin, nil
 }
 
+// User читает пользователя без обновления last_login и без проверки бана.
+func (s *Storage) User(ctx context.Context, tgHash string) (models.User, error) {
+	return scanUser(s.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE tgid = $1`, tgHash))
+}
+
+// UserByUsername ищет пользователя по username из Telegram без учета регистра.
+// Username мог перейти к другому аккаунту, поэтому берется последний входивший.
+func (s *Storage) UserByUsername(ctx context.Context, username string) (models.User, error) {
+	return scanUser(s.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users
+WHERE lower(user_name) = lower($1) ORDER BY last_login DESC NULLS LAST LIMIT 1`, username))
+}
+
+const userColumns = `tgid, id::text, first_name, last_name, user_name, user_name_locale, photo_url, is_admin, is_banned, last_login, erase_at`
+
+func scanUser(row pgx.Row) (models.User, error) {
+	var (
+		user      models.User
+		lastLogin *time.Time
+	)
+	err := row.Scan(&user.TgId, &user.ID, &user.FirstName, &user.LastName, &user.Username, &user.UserNameLocale, &user.PhotoURL, &user.IsAdmin, &user.IsBanned, &lastLogin, &user.EraseAt)
+	if lastLogin != nil {
+		user.LastLogin = *lastLogin
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.User{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return user, nil
+}
+
 func (s *Storage) App(ctx context.Context, serviceId int64) (models.App, error) {
 	tx, err := s.db.Begin(ctx)
 
@@ -75,14 +114,14 @@ func (s *Storage) App(ctx context.Context, serviceId int64) (models.App, error)
 	}
 	defer tx.Rollback(ctx)
 	var app models.App
-	err = tx.QueryRow(ctx, `SELECT * FROM apps WHERE id = $1`, serviceId).Scan(&app.ID, &app.Name, &app.Secret)
+	err = tx.QueryRow(ctx, `SELECT id, name, secret, allowed_origins, scopes FROM apps WHERE id = $1`, serviceId).Scan(&app.ID, &app.Name, &app.Secret, &app.AllowedOrigins, &app.Scopes)
 
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.App{}, fmt.Errorf(" Клиент сервис не найден %s ", storage.ErrAppNotFound)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.App{}, fmt.Errorf("Клиент сервис не найден: %w", storage.ErrAppNotFound)
 		}
 
-		return models.App{}, fmt.Errorf(" Ошибка", err)
+		return models.App{}, fmt.Errorf("Ошибка: %w", err)
 	}
 	if err := tx.Commit(ctx); err != nil {
 		fmt.Println("SaveUser", err)
@@ -92,33 +131,116 @@ func (s *Storage) App(ctx context.Context, serviceId int64) (models.App, error)
 	return app, nil
 }
 
-func InitDB(storagPath string) (*Storage, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// PoolConfig задает параметры пула соединений и повторов подключения при старте.
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnIdleTime   time.Duration
+	MaxConnLifetime   time.Duration
+	HealthCheckPeriod time.Duration
+	StatementTimeout  time.Duration
+	ConnectTimeout    time.Duration
+	ConnectRetries    int
+	RetryBackoff      time.Duration
+}
+
+// maxRetryBackoff ограничивает рост паузы между попытками подключения.
+const maxRetryBackoff = 30 * time.Second
+
+// Apps возвращает все зарегистрированные приложения.
+func (s *Storage) Apps(ctx context.Context) ([]models.App, error) {
+	rows, err := s.db.Query(ctx, `SELECT id, name, secret, allowed_origins, scopes FROM apps ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	var apps []models.App
+	for rows.Next() {
+		var app models.App
+		if err := rows.Scan(&app.ID, &app.Name, &app.Secret, &app.AllowedOrigins, &app.Scopes); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения приложения: %w", err)
+		}
+		apps = append(apps, app)
+	}
+	return apps, rows.Err()
+}
 
+func InitDB(storagPath string, poolCfg PoolConfig) (*Storage, error) {
 	pgxCfg, err := pgxpool.ParseConfig(storagPath)
 	if err != nil {
-		log.Fatal(" Ошибка парсинга строки подключения:", err)
+		return nil, fmt.Errorf("Ошибка парсинга строки подключения: %w", err)
 	}
-	pgxCfg.MaxConns = 1
-	pgxCfg.MinConns = 1
+	if poolCfg.MaxConns > 0 {
+		pgxCfg.MaxConns = poolCfg.MaxConns
+	}
+	if poolCfg.MinConns > 0 {
+		pgxCfg.MinConns = poolCfg.MinConns
+	}
+	if poolCfg.MaxConnIdleTime > 0 {
+		pgxCfg.MaxConnIdleTime = poolCfg.MaxConnIdleTime
+	}
+	if poolCfg.MaxConnLifetime > 0 {
+		pgxCfg.MaxConnLifetime = poolCfg.MaxConnLifetime
+	}
+	if poolCfg.HealthCheckPeriod > 0 {
+		pgxCfg.HealthCheckPeriod = poolCfg.HealthCheckPeriod
+	}
+	if poolCfg.StatementTimeout > 0 {
+		pgxCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(poolCfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	connectTimeout := poolCfg.ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = 5 * time.Second
+	}
+	backoff := poolCfg.RetryBackoff
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+
+	for attempt := 0; ; attempt++ {
+		pool, err := connect(pgxCfg, connectTimeout)
+		if err == nil {
+			log.Printf("Подключение к PostgresSQL успешно (max_conns=%d, min_conns=%d)", pgxCfg.MaxConns, pgxCfg.MinConns)
+			return &Storage{db: pool}, nil
+		}
+		if attempt >= poolCfg.ConnectRetries {
+			return nil, fmt.Errorf("Ошибка подключения к базе данных после %d попыток: %w", attempt+1, err)
+		}
+
+		log.Printf("Ошибка подключения к базе данных, повтор через %s: %v", backoff, err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+func connect(pgxCfg *pgxpool.Config, timeout time.Duration) (*pgxpool.Pool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
 	pool, err := pgxpool.NewWithConfig(ctx, pgxCfg)
+	if err != nil {
+		return nil, err
+	}
 	if err := pool.Ping(ctx); err != nil {
-		fmt.Println("Ошибка подключения к базе данных ")
+		pool.Close()
 		return nil, err
 	}
+	return pool, nil
+}
 
-	log.Println("Подключение к PostgresSQL успешно")
-	return &Storage{db: pool}, nil
-
+// Stat возвращает снимок статистики пула соединений для мониторинга.
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.db.Stat()
 }
 
 func (s *Storage) Close() {
 	s.db.Close()
 }
 
-func (s *Storage) SaveUser(ctx context.Context, tgId string, User models.User) error {
+// SaveUser создает пользователя и в той же транзакции пишет события outbox.
+func (s *Storage) SaveUser(ctx context.Context, tgId string, User models.User, events ...models.OutboxEvent) error {
 	tx, err := s.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("Ошибка начала транзакции")
@@ -136,9 +258,109 @@ func (s *Storage) SaveUser(ctx context.Context, tgId string, User models.User) e
 		}
 		return fmt.Errorf("Ошибка транзакции")
 	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("Ошибка базы данных")
 
 	}
 	return nil
 }
+
+func (s *Storage) SetBanned(ctx context.Context, tgHash string, banned bool, events ...models.OutboxEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET is_banned = $2 WHERE tgid = $1`, tgHash, banned)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) SetAdmin(ctx context.Context, tgHash string, admin bool, events ...models.OutboxEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET is_admin = $2 WHERE tgid = $1`, tgHash, admin)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// UpdateProfile обновляет имя, username и фото из Telegram. События outbox
+// пишутся, только если профиль действительно изменился.
+func (s *Storage) UpdateProfile(ctx context.Context, tgHash string, profile models.User, events ...models.OutboxEvent) (bool, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET first_name = $2, last_name = $3, user_name = $4, photo_url = $5
+WHERE tgid = $1 AND (first_name IS DISTINCT FROM $2 OR last_name IS DISTINCT FROM $3
+    OR user_name IS DISTINCT FROM $4 OR photo_url IS DISTINCT FROM $5)`,
+		tgHash, profile.FirstName, profile.LastName, profile.Username, profile.PhotoURL)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return true, nil
+}
+
+// SchemaVersion - номер последней миграции из migrations/postgres, с которой совместим код.
+const SchemaVersion = 16
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+// MigrationVersion читает состояние таблицы schema_migrations, которую ведет cmd/migrator.
+func (s *Storage) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := s.db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("Ошибка чтения версии миграций: %w", err)
+	}
+	return uint(version), dirty, nil
+}
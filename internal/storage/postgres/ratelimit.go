@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"auth-service/internal/lib/ratelimit"
+	"context"
+	"fmt"
+	"time"
+)
+
+// TakeToken реализует ratelimit.Store поверх таблицы rate_limits, чтобы
+// лимиты были общими для всех инстансов сервиса. Строка бакета блокируется
+// на время пересчета, поэтому параллельные запросы не списывают один токен дважды.
+func (s *Storage) TakeToken(ctx context.Context, key string, rate float64, burst int, now time.Time) (bool, time.Duration, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, 0, fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `INSERT INTO rate_limits (key, tokens, updated_at) VALUES ($1, $2, $3)
+ON CONFLICT (key) DO NOTHING`, key, float64(burst), now)
+	if err != nil {
+		return false, 0, fmt.Errorf("Ошибка создания бакета: %w", err)
+	}
+
+	var (
+		tokens    float64
+		updatedAt time.Time
+	)
+	err = tx.QueryRow(ctx, `SELECT tokens, updated_at FROM rate_limits WHERE key = $1 FOR UPDATE`, key).Scan(&tokens, &updatedAt)
+	if err != nil {
+		return false, 0, fmt.Errorf("Ошибка чтения бакета: %w", err)
+	}
+
+	tokens, allowed, retryAfter := ratelimit.Take(tokens, updatedAt, rate, burst, now)
+
+	_, err = tx.Exec(ctx, `UPDATE rate_limits SET tokens = $2, updated_at = $3 WHERE key = $1`, key, tokens, now)
+	if err != nil {
+		return false, 0, fmt.Errorf("Ошибка обновления бакета: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, 0, fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return allowed, retryAfter, nil
+}
+
+// DeleteIdleRateLimits удаляет бакеты, которые не трогали дольше maxIdle.
+func (s *Storage) DeleteIdleRateLimits(ctx context.Context, maxIdle time.Duration) (int64, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM rate_limits WHERE updated_at < $1`, time.Now().Add(-maxIdle))
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка очистки лимитов: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const sessionColumns = `id, user_tgid, app_id, platform, user_agent, ip, created_at, last_seen_at, revoked_at, mfa_at`
+
+// CreateSession сохраняет сессию и отмечает пользователя участником приложения.
+func (s *Storage) CreateSession(ctx context.Context, session models.Session) error {
+	_, err := s.db.Exec(ctx, `WITH session AS (
+    INSERT INTO sessions (`+sessionColumns+`)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULL, NULL)
+)
+INSERT INTO app_members (user_tgid, app_id, joined_at) VALUES ($2, $3, $7)
+ON CONFLICT (user_tgid, app_id) DO NOTHING`,
+		session.ID, session.UserID, session.AppID, session.Platform, session.UserAgent, session.IP, session.CreatedAt, session.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("Ошибка сохранения сессии: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) Session(ctx context.Context, id string) (models.Session, error) {
+	session, err := scanSession(s.db.QueryRow(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Session{}, fmt.Errorf("Сессия не найдена: %w", storage.ErrSessionNotFound)
+		}
+		return models.Session{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return session, nil
+}
+
+// Sessions возвращает активные сессии пользователя, новые первыми.
+func (s *Storage) Sessions(ctx context.Context, tgHash string) ([]models.Session, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+sessionColumns+` FROM sessions
+WHERE user_tgid = $1 AND revoked_at IS NULL ORDER BY created_at DESC`, tgHash)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения сессии: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *Storage) TouchSession(ctx context.Context, id string, at time.Time) error {
+	_, err := s.db.Exec(ctx, `UPDATE sessions SET last_seen_at = $2 WHERE id = $1 AND last_seen_at < $2`, id, at)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return nil
+}
+
+// RevokeSession завершает активную сессию пользователя tgHash. Чужая или уже
+// завершенная сессия дает ErrSessionNotFound.
+func (s *Storage) RevokeSession(ctx context.Context, tgHash, id string, at time.Time, events ...models.OutboxEvent) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE sessions SET revoked_at = $3
+WHERE id = $1 AND user_tgid = $2 AND revoked_at IS NULL`, id, tgHash, at)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Сессия не найдена: %w", storage.ErrSessionNotFound)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// StepUpSession отмечает, что в сессии id только что подтвержден второй фактор.
+func (s *Storage) StepUpSession(ctx context.Context, id string, at time.Time) error {
+	tag, err := s.db.Exec(ctx, `UPDATE sessions SET mfa_at = $2 WHERE id = $1 AND revoked_at IS NULL`, id, at)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Сессия не найдена: %w", storage.ErrSessionNotFound)
+	}
+	return nil
+}
+
+func scanSession(row pgx.Row) (models.Session, error) {
+	var session models.Session
+	err := row.Scan(&session.ID, &session.UserID, &session.AppID, &session.Platform, &session.UserAgent, &session.IP,
+		&session.CreatedAt, &session.LastSeenAt, &session.RevokedAt, &session.MFAAt)
+	return session, err
+}
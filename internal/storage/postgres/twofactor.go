@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const totpColumns = `user_tgid, secret, created_at, confirmed_at, last_step, last_verified_at, failed_attempts, locked_until`
+
+// SaveTOTP заводит неподтвержденный фактор или заменяет прежний
+// неподтвержденный. Включенный фактор не заменяется: ErrTOTPExists.
+func (s *Storage) SaveTOTP(ctx context.Context, factor models.TOTPFactor) error {
+	tag, err := s.db.Exec(ctx, `INSERT INTO totp_factors (user_tgid, secret, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_tgid) DO UPDATE SET
+    secret = EXCLUDED.secret, created_at = EXCLUDED.created_at,
+    last_step = 0, failed_attempts = 0, locked_until = NULL
+WHERE totp_factors.confirmed_at IS NULL`, factor.UserID, factor.Secret, factor.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrTOTPExists
+	}
+	return nil
+}
+
+func (s *Storage) TOTP(ctx context.Context, tgHash string) (models.TOTPFactor, error) {
+	var f models.TOTPFactor
+	err := s.db.QueryRow(ctx, `SELECT `+totpColumns+` FROM totp_factors WHERE user_tgid = $1`, tgHash).Scan(
+		&f.UserID, &f.Secret, &f.CreatedAt, &f.ConfirmedAt, &f.LastStep, &f.LastVerifiedAt, &f.FailedAttempts, &f.LockedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.TOTPFactor{}, fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+		}
+		return models.TOTPFactor{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return f, nil
+}
+
+// ConfirmTOTP включает заведенный фактор кодом шага step и сохраняет хэши
+// кодов восстановления.
+func (s *Storage) ConfirmTOTP(ctx context.Context, tgHash string, step int64, at time.Time, recoveryHashes []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE totp_factors SET
+    confirmed_at = $3, last_step = $2, last_verified_at = $3, failed_attempts = 0, locked_until = NULL
+WHERE user_tgid = $1 AND confirmed_at IS NULL`, tgHash, step, at)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+	}
+	if err := replaceRecoveryCodes(ctx, tx, tgHash, recoveryHashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// UseTOTPStep принимает код шага step, если коды этого и более поздних шагов
+// еще не использовались, и снимает счетчик неудач. false - код уже был.
+func (s *Storage) UseTOTPStep(ctx context.Context, tgHash string, step int64, at time.Time) (bool, error) {
+	tag, err := s.db.Exec(ctx, `UPDATE totp_factors SET
+    last_step = $2, last_verified_at = $3, failed_attempts = 0, locked_until = NULL
+WHERE user_tgid = $1 AND last_step < $2`, tgHash, step, at)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RecordOTPFailure увеличивает счетчик неверных кодов. На maxFailures-й
+// неудаче фактор блокируется до lockUntil, а счетчик обнуляется.
+func (s *Storage) RecordOTPFailure(ctx context.Context, tgHash string, maxFailures int, lockUntil time.Time) (bool, error) {
+	var locked bool
+	err := s.db.QueryRow(ctx, `UPDATE totp_factors SET
+    failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+    locked_until    = CASE WHEN failed_attempts + 1 >= $2 THEN $3 ELSE locked_until END
+WHERE user_tgid = $1
+RETURNING failed_attempts = 0`, tgHash, maxFailures, lockUntil).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+		}
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return locked, nil
+}
+
+// DeleteTOTP удаляет фактор вместе с кодами восстановления.
+func (s *Storage) DeleteTOTP(ctx context.Context, tgHash string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM totp_factors WHERE user_tgid = $1`, tgHash)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_tgid = $1`, tgHash); err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новыми.
+func (s *Storage) ReplaceRecoveryCodes(ctx context.Context, tgHash string, hashes []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, tgHash, hashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode погашает неиспользованный код восстановления и, как и
+// UseTOTPStep, отмечает подтверждение второго фактора. false - кода нет или
+// он уже использован.
+func (s *Storage) UseRecoveryCode(ctx context.Context, tgHash, hash string, at time.Time) (bool, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE recovery_codes SET used_at = $3
+WHERE user_tgid = $1 AND code_hash = $2 AND used_at IS NULL`, tgHash, hash, at)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	_, err = tx.Exec(ctx, `UPDATE totp_factors SET last_verified_at = $2, failed_attempts = 0, locked_until = NULL
+WHERE user_tgid = $1`, tgHash, at)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return true, nil
+}
+
+func (s *Storage) RecoveryCodesLeft(ctx context.Context, tgHash string) (int, error) {
+	var n int
+	err := s.db.QueryRow(ctx, `SELECT count(*) FROM recovery_codes WHERE user_tgid = $1 AND used_at IS NULL`, tgHash).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return n, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, tgHash string, hashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_tgid = $1`, tgHash); err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	for _, hash := range hashes {
+		_, err := tx.Exec(ctx, `INSERT INTO recovery_codes (user_tgid, code_hash) VALUES ($1, $2)
+ON CONFLICT DO NOTHING`, tgHash, hash)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+			}
+			return fmt.Errorf("Ошибка базы данных: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// outboxLock - ключ advisory-блокировки, которая упорядочивает запись в outbox.
+const outboxLock = 7_001_001
+
+// insertEvents пишет события outbox в транзакции изменения, которое они описывают.
+// Транзакции с событиями сериализуются блокировкой до коммита, поэтому id событий
+// видны читателям строго по возрастанию и курсор /v1/events ничего не пропускает.
+func insertEvents(ctx context.Context, tx pgx.Tx, events []models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, outboxLock); err != nil {
+		return fmt.Errorf("Ошибка блокировки outbox: %w", err)
+	}
+	for _, e := range events {
+		_, err := tx.Exec(ctx, `INSERT INTO outbox_events (type, subject, app_id, data, created_at) VALUES ($1, $2, $3, $4, $5)`,
+			e.Type, e.Subject, e.AppID, string(e.Data), e.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("Ошибка записи события outbox: %w", err)
+		}
+	}
+	return nil
+}
+
+// Events возвращает события после afterID, видимые приложению appID: произошедшие
+// в нем и события пользователей, входивших в это приложение (app_members).
+func (s *Storage) Events(ctx context.Context, appID, afterID int64, limit int) ([]models.OutboxEvent, error) {
+	rows, err := s.db.Query(ctx, `SELECT e.id, e.type, e.data, e.created_at FROM outbox_events e
+WHERE e.id > $2 AND (e.app_id = $1
+    OR EXISTS (SELECT 1 FROM app_members m WHERE m.user_tgid = e.subject AND m.app_id = $1))
+ORDER BY e.id LIMIT $3`, appID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var (
+			e    models.OutboxEvent
+			data []byte
+		)
+		if err := rows.Scan(&e.ID, &e.Type, &data, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения события: %w", err)
+		}
+		e.Data = data
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *Storage) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	err := s.db.QueryRow(ctx, `INSERT INTO webhooks (app_id, url, events, created_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+		webhook.AppID, webhook.URL, webhook.Events, webhook.CreatedAt).Scan(&webhook.ID)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("Ошибка сохранения вебхука: %w", err)
+	}
+	return webhook, nil
+}
+
+func (s *Storage) Webhooks(ctx context.Context, appID int64) ([]models.Webhook, error) {
+	rows, err := s.db.Query(ctx, `SELECT id, app_id, url, events, created_at FROM webhooks WHERE app_id = $1 ORDER BY id`, appID)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var w models.Webhook
+		if err := rows.Scan(&w.ID, &w.AppID, &w.URL, &w.Events, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения вебхука: %w", err)
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, appID, id int64) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND app_id = $2`, id, appID)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Вебхук не найден: %w", storage.ErrWebhookNotFound)
+	}
+	return nil
+}
+
+// DispatchEvents раскладывает еще не обработанные события outbox по доставкам.
+// Событие получают вебхуки приложения, где оно произошло, и приложений, в которые
+// пользователь входил, даже если он уже вышел. SKIP LOCKED позволяет нескольким инстансам не мешать друг другу.
+func (s *Storage) DispatchEvents(ctx context.Context, now time.Time, limit int) (int, error) {
+	tag, err := s.db.Exec(ctx, `WITH batch AS (
+    SELECT id, type, subject, app_id FROM outbox_events
+    WHERE dispatched_at IS NULL ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED
+), deliveries AS (
+    INSERT INTO webhook_deliveries (webhook_id, event_id, status, next_attempt_at, created_at, updated_at)
+    SELECT w.id, b.id, 'pending', $1, $1, $1
+    FROM batch b JOIN webhooks w ON b.type = ANY (w.events)
+    WHERE w.app_id = b.app_id
+       OR EXISTS (SELECT 1 FROM app_members m WHERE m.user_tgid = b.subject AND m.app_id = w.app_id)
+    ON CONFLICT (webhook_id, event_id) DO NOTHING
+)
+UPDATE outbox_events SET dispatched_at = $1 WHERE id IN (SELECT id FROM batch)`, now, limit)
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка раскладки событий: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// ClaimDeliveries забирает доставки, время которых пришло, и откладывает их до
+// leaseUntil: если инстанс упадет посреди отправки, доставку повторит другой.
+func (s *Storage) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.Delivery, error) {
+	rows, err := s.db.Query(ctx, `UPDATE webhook_deliveries d SET next_attempt_at = $2
+FROM webhooks w, outbox_events e, apps a
+WHERE d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= $1
+    ORDER BY next_attempt_at, id LIMIT $3 FOR UPDATE SKIP LOCKED
+) AND w.id = d.webhook_id AND e.id = d.event_id AND a.id = w.app_id
+RETURNING d.id, d.webhook_id, d.event_id, d.status, d.attempts, d.created_at,
+    w.url, a.secret, e.type, e.data, e.created_at`, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []models.Delivery
+	for rows.Next() {
+		var (
+			d    models.Delivery
+			data []byte
+		)
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Status, &d.Attempts, &d.CreatedAt,
+			&d.URL, &d.Secret, &d.EventType, &data, &d.Event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения доставки: %w", err)
+		}
+		d.Event.ID, d.Event.Type, d.Event.Data = d.EventID, d.EventType, data
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// UpdateDelivery сохраняет результат попытки доставки.
+func (s *Storage) UpdateDelivery(ctx context.Context, d models.Delivery) error {
+	_, err := s.db.Exec(ctx, `UPDATE webhook_deliveries
+SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, response_status = $6, updated_at = $7
+WHERE id = $1`, d.ID, d.Status, d.Attempts, d.NextAttemptAt, d.LastError, d.ResponseStatus, d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return nil
+}
+
+// Deliveries возвращает доставки вебхуков приложения в статусе status по возрастанию id.
+func (s *Storage) Deliveries(ctx context.Context, appID int64, status string, afterID int64, limit int) ([]models.Delivery, error) {
+	rows, err := s.db.Query(ctx, `SELECT d.id, d.webhook_id, d.event_id, e.type, w.url, d.status, d.attempts,
+    d.next_attempt_at, d.last_error, d.response_status, d.created_at, d.updated_at
+FROM webhook_deliveries d
+JOIN webhooks w ON w.id = d.webhook_id
+JOIN outbox_events e ON e.id = d.event_id
+WHERE w.app_id = $1 AND d.status = $2 AND d.id > $3
+ORDER BY d.id LIMIT $4`, appID, status, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.Delivery{}
+	for rows.Next() {
+		var d models.Delivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.URL, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastError, &d.ResponseStatus, &d.CreatedAt, &d.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения доставки: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RedeliverDelivery возвращает доставку приложения appID в очередь с обнуленным
+// счетчиком попыток.
+func (s *Storage) RedeliverDelivery(ctx context.Context, appID, id int64, now time.Time) error {
+	tag, err := s.db.Exec(ctx, `UPDATE webhook_deliveries d
+SET status = 'pending', attempts = 0, next_attempt_at = $3, last_error = '', response_status = 0, updated_at = $3
+FROM webhooks w
+WHERE d.id = $1 AND w.id = d.webhook_id AND w.app_id = $2`, id, appID, now)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("Доставка не найдена: %w", storage.ErrDeliveryNotFound)
+	}
+	return nil
+}
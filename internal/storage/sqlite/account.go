@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionHistory возвращает все сессии пользователя, включая завершенные, новые первыми.
+func (s *Storage) SessionHistory(ctx context.Context, tgHash string) ([]models.Session, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE user_tgid = ? ORDER BY created_at DESC`, tgHash)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения сессии: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// AppMemberships возвращает приложения из app_members, в которые входил
+// пользователь, с временем первого входа, по возрастанию id приложения.
+func (s *Storage) AppMemberships(ctx context.Context, tgHash string) ([]models.Membership, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT app_id, joined_at FROM app_members WHERE user_tgid = ? ORDER BY app_id`, tgHash)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	memberships := []models.Membership{}
+	for rows.Next() {
+		var m models.Membership
+		if err := rows.Scan(&m.AppID, &m.FirstSeenAt); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения участника приложения: %w", err)
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+// ScheduleErasure назначает удаление аккаунта на eraseAt, nil отменяет удаление.
+func (s *Storage) ScheduleErasure(ctx context.Context, tgHash string, eraseAt *time.Time) error {
+	var at sql.NullTime
+	if eraseAt != nil {
+		at = sql.NullTime{Time: eraseAt.UTC(), Valid: true}
+	}
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET erase_at = ?, erase_attempt_at = NULL WHERE tgid = ?`, at, tgHash)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+// ClaimErasures возвращает до limit пользователей, срок удаления которых
+// наступил, и отмечает попытку удаления временем now. Сначала идут те, кого еще
+// не пытались удалить, затем - по давности последней попытки, поэтому аккаунт,
+// удаление которого падает, не задерживает остальные.
+func (s *Storage) ClaimErasures(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE users SET erase_attempt_at = ?1
+		WHERE tgid IN (
+			SELECT tgid FROM users WHERE erase_at <= ?1
+			ORDER BY erase_attempt_at NULLS FIRST, erase_at
+			LIMIT ?2
+		)
+		RETURNING tgid`, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []string{}
+	for rows.Next() {
+		var sub string
+		if err := rows.Scan(&sub); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения пользователя: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// EraseUser удаляет пользователя, срок удаления которого наступил к now, вместе
+// с сессиями. В журнале аудита, app_members и событиях outbox его sub
+// заменяется псевдонимом, из событий outbox стирается профиль. events пишутся
+// после замены и сохраняют свои данные. Если удаление отменили или аккаунта уже
+// нет - ErrUserNotFound.
+func (s *Storage) EraseUser(ctx context.Context, tgHash, pseudonym string, now time.Time, events ...models.OutboxEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM users WHERE tgid = ? AND erase_at <= ?`, tgHash, now.UTC()).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE audit_events SET
+    actor = CASE WHEN actor = ?1 THEN ?2 ELSE actor END,
+    subject = CASE WHEN subject = ?1 THEN ?2 ELSE subject END,
+    ip = ''
+WHERE actor = ?1 OR subject = ?1`, tgHash, pseudonym)
+	if err != nil {
+		return fmt.Errorf("Ошибка обезличивания журнала аудита: %w", err)
+	}
+
+	args := []any{tgHash, pseudonym}
+	placeholders := make([]string, len(models.ProfileEventTypes))
+	for i, t := range models.ProfileEventTypes {
+		args = append(args, t)
+		placeholders[i] = "?" + strconv.Itoa(i+3)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE outbox_events SET subject = ?2,
+    data = CASE WHEN type IN (`+strings.Join(placeholders, ", ")+`) THEN json_object('sub', ?2)
+        ELSE json_set(data, '$.sub', ?2) END
+WHERE subject = ?1`, args...)
+	if err != nil {
+		return fmt.Errorf("Ошибка очистки outbox: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE app_members SET user_tgid = ? WHERE user_tgid = ?`, pseudonym, tgHash)
+	if err != nil {
+		return fmt.Errorf("Ошибка обезличивания участников приложений: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("Ошибка удаления пользователя: %w", err)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
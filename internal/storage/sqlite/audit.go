@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"auth-service/internal/domains/models"
+	"context"
+	"fmt"
+	"strings"
+)
+
+func (s *Storage) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO audit_events (type, actor, subject, app_id, ip, outcome, reason, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.Type, event.Actor, event.Subject, event.AppID, event.IP, event.Outcome, event.Reason, event.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("Ошибка записи события аудита: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if filter.User != "" {
+		where = append(where, "(subject = ? OR actor = ?)")
+		args = append(args, filter.User, filter.User)
+	}
+	if filter.AppID != 0 {
+		where = append(where, "app_id = ?")
+		args = append(args, filter.AppID)
+	}
+	if filter.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, filter.Type)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+	where = append(where, "id > ?")
+	args = append(args, filter.AfterID, filter.Limit)
+
+	query := `SELECT id, type, actor, subject, app_id, ip, outcome, reason, created_at FROM audit_events
+WHERE ` + strings.Join(where, " AND ") + ` ORDER BY id LIMIT ?`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.Actor, &e.Subject, &e.AppID, &e.IP, &e.Outcome, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения события аудита: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const handoffColumns = `code, device_hash, app_id, COALESCE(user_tgid, ''), created_at, expires_at, approved_at, ip, user_agent, platform`
+
+// CreateHandoff сохраняет новый код входа и заодно удаляет истекшие.
+func (s *Storage) CreateHandoff(ctx context.Context, handoff models.Handoff) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM login_handoffs WHERE expires_at <= ?`, handoff.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	_, err := s.db.ExecContext(ctx, `INSERT INTO login_handoffs (code, device_hash, app_id, created_at, expires_at, ip, user_agent, platform)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		handoff.Code, handoff.DeviceHash, handoff.AppID, handoff.CreatedAt.UTC(), handoff.ExpiresAt.UTC(), handoff.IP, handoff.UserAgent, handoff.Platform)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("Клиент сервис не найден: %w", storage.ErrAppNotFound)
+		}
+		return fmt.Errorf("Ошибка сохранения кода входа: %w", err)
+	}
+	return nil
+}
+
+// Handoff возвращает неистекший и еще не подтвержденный код входа.
+func (s *Storage) Handoff(ctx context.Context, code string, now time.Time) (models.Handoff, error) {
+	handoff, err := scanHandoff(s.db.QueryRowContext(ctx, `SELECT `+handoffColumns+` FROM login_handoffs
+WHERE code = ? AND user_tgid IS NULL AND expires_at > ?`, code, now.UTC()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Handoff{}, fmt.Errorf("Код входа не найден: %w", storage.ErrHandoffNotFound)
+		}
+		return models.Handoff{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return handoff, nil
+}
+
+// ApproveHandoff привязывает неистекший и еще не подтвержденный код к пользователю.
+func (s *Storage) ApproveHandoff(ctx context.Context, code, tgHash string, now time.Time) (models.Handoff, error) {
+	handoff, err := scanHandoff(s.db.QueryRowContext(ctx, `UPDATE login_handoffs SET user_tgid = ?2, approved_at = ?3
+WHERE code = ?1 AND user_tgid IS NULL AND expires_at > ?3
+RETURNING `+handoffColumns, code, tgHash, now.UTC()))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return models.Handoff{}, fmt.Errorf("Код входа не найден: %w", storage.ErrHandoffNotFound)
+		case isForeignKeyViolation(err):
+			return models.Handoff{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return models.Handoff{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return handoff, nil
+}
+
+// ClaimHandoff отдает код по хэшу device_code. Подтвержденный код удаляется в
+// том же запросе, поэтому токены по нему получит только один опрос.
+func (s *Storage) ClaimHandoff(ctx context.Context, deviceHash string, now time.Time) (models.Handoff, error) {
+	handoff, err := scanHandoff(s.db.QueryRowContext(ctx, `DELETE FROM login_handoffs
+WHERE device_hash = ? AND user_tgid IS NOT NULL AND expires_at > ?
+RETURNING `+handoffColumns, deviceHash, now.UTC()))
+	if err == nil {
+		return handoff, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.Handoff{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+
+	handoff, err = scanHandoff(s.db.QueryRowContext(ctx, `SELECT `+handoffColumns+` FROM login_handoffs
+WHERE device_hash = ? AND expires_at > ?`, deviceHash, now.UTC()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Handoff{}, fmt.Errorf("Код входа не найден: %w", storage.ErrHandoffNotFound)
+		}
+		return models.Handoff{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return handoff, nil
+}
+
+func scanHandoff(row *sql.Row) (models.Handoff, error) {
+	var (
+		h          models.Handoff
+		approvedAt sql.NullTime
+	)
+	if err := row.Scan(&h.Code, &h.DeviceHash, &h.AppID, &h.UserID, &h.CreatedAt, &h.ExpiresAt, &approvedAt, &h.IP, &h.UserAgent, &h.Platform); err != nil {
+		return models.Handoff{}, err
+	}
+	if approvedAt.Valid {
+		h.ApprovedAt = &approvedAt.Time
+	}
+	return h, nil
+}
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// LinkIdentity привязывает учетную запись провайдера к пользователю tgHash.
+func (s *Storage) LinkIdentity(ctx context.Context, tgHash string, identity models.Identity) (models.Identity, error) {
+	err := s.db.QueryRowContext(ctx, `INSERT INTO identities (user_id, provider, subject, email, created_at, password_hash, verified_at)
+SELECT id, ?, ?, ?, ?, ?, ? FROM users WHERE tgid = ?
+RETURNING id`, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt.UTC(), identity.PasswordHash, nullTime(identity.VerifiedAt), tgHash).Scan(&identity.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return models.Identity{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		case isUniqueViolation(err):
+			return models.Identity{}, fmt.Errorf("Учетная запись уже привязана: %w", storage.ErrIdentityExists)
+		}
+		return models.Identity{}, fmt.Errorf("Ошибка привязки учетной записи: %w", err)
+	}
+	identity.UserID = tgHash
+	return identity, nil
+}
+
+const identityColumns = `i.id, u.tgid, i.provider, i.subject, i.email, i.created_at, i.password_hash, i.verified_at, i.failed_logins, i.locked_until`
+
+// scanIdentity читает строку identityColumns из *sql.Row или *sql.Rows.
+func scanIdentity(row interface{ Scan(...any) error }) (models.Identity, error) {
+	var (
+		i                       models.Identity
+		verifiedAt, lockedUntil sql.NullTime
+	)
+	err := row.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt,
+		&i.PasswordHash, &verifiedAt, &i.FailedLogins, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Identity{}, fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+		}
+		return models.Identity{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if verifiedAt.Valid {
+		i.VerifiedAt = &verifiedAt.Time
+	}
+	if lockedUntil.Valid {
+		i.LockedUntil = &lockedUntil.Time
+	}
+	return i, nil
+}
+
+func (s *Storage) Identities(ctx context.Context, tgHash string) ([]models.Identity, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+identityColumns+`
+FROM identities i JOIN users u ON u.id = i.user_id
+WHERE u.tgid = ? ORDER BY i.id`, tgHash)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []models.Identity{}
+	for rows.Next() {
+		i, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return identities, nil
+}
+
+func (s *Storage) UnlinkIdentity(ctx context.Context, tgHash, provider string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM identities
+WHERE provider = ? AND user_id = (SELECT id FROM users WHERE tgid = ?)`, provider, tgHash)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+	}
+	return nil
+}
+
+// UserByIdentity находит пользователя, к которому привязана учетная запись провайдера.
+func (s *Storage) UserByIdentity(ctx context.Context, provider, subject string) (models.User, error) {
+	user, err := scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users
+WHERE id = (SELECT user_id FROM identities WHERE provider = ? AND subject = ?)`, provider, subject))
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return models.User{}, fmt.Errorf("Учетная запись не найдена: %w", storage.ErrIdentityNotFound)
+	}
+	return user, err
+}
@@ -0,0 +1,132 @@
+package sqlite
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const sessionColumns = `id, user_tgid, app_id, platform, user_agent, ip, created_at, last_seen_at, revoked_at, mfa_at`
+
+// CreateSession сохраняет сессию и отмечает пользователя участником приложения.
+func (s *Storage) CreateSession(ctx context.Context, session models.Session) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO sessions (`+sessionColumns+`)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULL, NULL)`,
+		session.ID, session.UserID, session.AppID, session.Platform, session.UserAgent, session.IP, session.CreatedAt.UTC(), session.LastSeenAt.UTC())
+	if err != nil {
+		return fmt.Errorf("Ошибка сохранения сессии: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO app_members (user_tgid, app_id, joined_at) VALUES (?, ?, ?)`,
+		session.UserID, session.AppID, session.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("Ошибка сохранения участника приложения: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) Session(ctx context.Context, id string) (models.Session, error) {
+	session, err := scanSession(s.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, fmt.Errorf("Сессия не найдена: %w", storage.ErrSessionNotFound)
+		}
+		return models.Session{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return session, nil
+}
+
+// Sessions возвращает активные сессии пользователя, новые первыми.
+func (s *Storage) Sessions(ctx context.Context, tgHash string) ([]models.Session, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+sessionColumns+` FROM sessions
+WHERE user_tgid = ? AND revoked_at IS NULL ORDER BY created_at DESC`, tgHash)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения сессии: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *Storage) TouchSession(ctx context.Context, id string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE sessions SET last_seen_at = ? WHERE id = ? AND last_seen_at < ?`, at.UTC(), id, at.UTC())
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return nil
+}
+
+// RevokeSession завершает активную сессию пользователя tgHash. Чужая или уже
+// завершенная сессия дает ErrSessionNotFound.
+func (s *Storage) RevokeSession(ctx context.Context, tgHash, id string, at time.Time, events ...models.OutboxEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = ?
+WHERE id = ? AND user_tgid = ? AND revoked_at IS NULL`, at.UTC(), id, tgHash)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Сессия не найдена: %w", storage.ErrSessionNotFound)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// StepUpSession отмечает, что в сессии id только что подтвержден второй фактор.
+func (s *Storage) StepUpSession(ctx context.Context, id string, at time.Time) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE sessions SET mfa_at = ? WHERE id = ? AND revoked_at IS NULL`, at.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Сессия не найдена: %w", storage.ErrSessionNotFound)
+	}
+	return nil
+}
+
+func scanSession(row rowScanner) (models.Session, error) {
+	var (
+		session models.Session
+		revoked sql.NullTime
+		mfaAt   sql.NullTime
+	)
+	err := row.Scan(&session.ID, &session.UserID, &session.AppID, &session.Platform, &session.UserAgent, &session.IP,
+		&session.CreatedAt, &session.LastSeenAt, &revoked, &mfaAt)
+	if revoked.Valid {
+		session.RevokedAt = &revoked.Time
+	}
+	if mfaAt.Valid {
+		session.MFAAt = &mfaAt.Time
+	}
+	return session, err
+}
@@ -0,0 +1,307 @@
+package sqlite
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type Storage struct {
+	db *sql.DB
+}
+
+// New открывает файл базы SQLite. Схему создает cmd/migrator с -driver=sqlite.
+func New(storagePath string) (*Storage, error) {
+	db, err := sql.Open("sqlite", storagePath)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка открытия базы: %w", err)
+	}
+	// SQLite сериализует запись, одно соединение избавляет от SQLITE_BUSY
+	// и сохраняет PRAGMA между запросами.
+	db.SetMaxOpenConns(1)
+
+	for _, pragma := range []string{
+		`PRAGMA foreign_keys = ON`,
+		`PRAGMA busy_timeout = 5000`,
+		`PRAGMA journal_mode = WAL`,
+	} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("Ошибка настройки базы: %w", err)
+		}
+	}
+
+	log.Println("Подключение к SQLite успешно")
+	return &Storage{db: db}, nil
+}
+
+func (s *Storage) Close() {
+	s.db.Close()
+}
+
+// SaveUser создает пользователя и в той же транзакции пишет события outbox.
+func (s *Storage) SaveUser(ctx context.Context, tgId string, User models.User, events ...models.OutboxEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO users (tgid, first_name, last_name, user_name, user_name_locale, last_login, photo_url, is_admin)
+         VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?, ?)`, tgId, User.FirstName, User.LastName, User.Username, User.UserNameLocale, User.PhotoURL, User.IsAdmin)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return storage.ErrUserExist
+		}
+		return fmt.Errorf("Ошибка транзакции: %w", err)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) ValidateUser(ctx context.Context, tgHash string) (models.UserResponse, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.UserResponse{}, fmt.Errorf("Ошибка сервера: %w", err)
+	}
+	defer tx.Rollback()
+
+	var user models.UserResponse
+	err = tx.QueryRowContext(ctx, `UPDATE users
+SET last_login = CURRENT_TIMESTAMP
+WHERE tgid = ?
+RETURNING tgid, CAST(id AS TEXT), first_name, last_name, user_name, user_name_locale, photo_url, is_banned`, tgHash).Scan(&user.TgId, &user.ID, &user.FirstName, &user.LastName, &user.Username, &user.UserNameLocale, &user.PhotoURL, &user.IsBanned)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserResponse{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return models.UserResponse{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if user.IsBanned {
+		return models.UserResponse{}, fmt.Errorf("Пользователь забанен: %w", storage.ErrUserBanned)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.UserResponse{}, fmt.Errorf("Ошибка комита: %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) IsAdmin(ctx context.Context, tgHash string) (bool, error) {
+	var isAdmin bool
+	err := s.db.QueryRowContext(ctx, `SELECT is_admin FROM users WHERE tgid = ?`, tgHash).Scan(&isAdmin)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return false, fmt.Errorf("IsAdmin Error: %w", err)
+	}
+	return isAdmin, nil
+}
+
+// User читает пользователя без обновления last_login и без проверки бана.
+func (s *Storage) User(ctx context.Context, tgHash string) (models.User, error) {
+	return scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE tgid = ?`, tgHash))
+}
+
+// UserByUsername ищет пользователя по username из Telegram без учета регистра.
+// Username мог перейти к другому аккаунту, поэтому берется последний входивший.
+func (s *Storage) UserByUsername(ctx context.Context, username string) (models.User, error) {
+	return scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users
+WHERE user_name = ? COLLATE NOCASE ORDER BY last_login DESC LIMIT 1`, username))
+}
+
+const userColumns = `tgid, CAST(id AS TEXT), first_name, last_name, user_name, user_name_locale, photo_url, is_admin, is_banned, last_login, erase_at`
+
+func scanUser(row *sql.Row) (models.User, error) {
+	var (
+		user               models.User
+		lastLogin, eraseAt sql.NullTime
+	)
+	err := row.Scan(&user.TgId, &user.ID, &user.FirstName, &user.LastName, &user.Username, &user.UserNameLocale, &user.PhotoURL, &user.IsAdmin, &user.IsBanned, &lastLogin, &eraseAt)
+	user.LastLogin = lastLogin.Time
+	if eraseAt.Valid {
+		user.EraseAt = &eraseAt.Time
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return user, nil
+}
+
+func (s *Storage) SetBanned(ctx context.Context, tgHash string, banned bool, events ...models.OutboxEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET is_banned = ? WHERE tgid = ?`, banned, tgHash)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) SetAdmin(ctx context.Context, tgHash string, admin bool, events ...models.OutboxEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET is_admin = ? WHERE tgid = ?`, admin, tgHash)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// UpdateProfile обновляет имя, username и фото из Telegram. События outbox
+// пишутся, только если профиль действительно изменился.
+func (s *Storage) UpdateProfile(ctx context.Context, tgHash string, profile models.User, events ...models.OutboxEvent) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET first_name = ?2, last_name = ?3, user_name = ?4, photo_url = ?5
+WHERE tgid = ?1 AND (first_name IS NOT ?2 OR last_name IS NOT ?3 OR user_name IS NOT ?4 OR photo_url IS NOT ?5)`,
+		tgHash, profile.FirstName, profile.LastName, profile.Username, profile.PhotoURL)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if err := insertEvents(ctx, tx, events); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return true, nil
+}
+
+func (s *Storage) App(ctx context.Context, serviceId int64) (models.App, error) {
+	app, err := scanApp(s.db.QueryRowContext(ctx, `SELECT id, name, secret, allowed_origins, scopes FROM apps WHERE id = ?`, serviceId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.App{}, fmt.Errorf("Клиент сервис не найден: %w", storage.ErrAppNotFound)
+		}
+		return models.App{}, fmt.Errorf("Ошибка: %w", err)
+	}
+	return app, nil
+}
+
+// Apps возвращает все зарегистрированные приложения.
+func (s *Storage) Apps(ctx context.Context) ([]models.App, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, secret, allowed_origins, scopes FROM apps ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	var apps []models.App
+	for rows.Next() {
+		app, err := scanApp(rows)
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения приложения: %w", err)
+		}
+		apps = append(apps, app)
+	}
+	return apps, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanApp читает приложение. allowed_origins и scopes хранятся в SQLite как JSON-массивы.
+func scanApp(row rowScanner) (models.App, error) {
+	var (
+		app     models.App
+		origins string
+		scopes  string
+	)
+	if err := row.Scan(&app.ID, &app.Name, &app.Secret, &origins, &scopes); err != nil {
+		return models.App{}, err
+	}
+	if err := json.Unmarshal([]byte(origins), &app.AllowedOrigins); err != nil {
+		return models.App{}, fmt.Errorf("allowed_origins: %w", err)
+	}
+	if err := json.Unmarshal([]byte(scopes), &app.Scopes); err != nil {
+		return models.App{}, fmt.Errorf("scopes: %w", err)
+	}
+	return app, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+func isForeignKeyViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+}
+
+// SchemaVersion - номер последней миграции из migrations/sqlite, с которой совместим код.
+const SchemaVersion = 15
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// MigrationVersion читает состояние таблицы schema_migrations, которую ведет cmd/migrator.
+func (s *Storage) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("Ошибка чтения версии миграций: %w", err)
+	}
+	return uint(version), dirty, nil
+}
@@ -0,0 +1,49 @@
+package sqlite_test
+
+import (
+	"auth-service/internal/storage/sqlite"
+	"auth-service/internal/storage/storagetest"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// newStorage создает файл базы во временном каталоге и применяет migrations/sqlite.
+func newStorage(t *testing.T) *sqlite.Storage {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "sso.db")
+	m, err := migrate.New("file://../../../migrations/sqlite", "sqlite://"+path)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	m.Close()
+
+	st, err := sqlite.New(path)
+	if err != nil {
+		t.Fatalf("sqlite.New: %v", err)
+	}
+	t.Cleanup(st.Close)
+	return st
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage { return newStorage(t) })
+}
+
+func TestMigrationVersion(t *testing.T) {
+	version, dirty, err := newStorage(t).MigrationVersion(context.Background())
+	if err != nil {
+		t.Fatalf("MigrationVersion: %v", err)
+	}
+	if dirty || version != sqlite.SchemaVersion {
+		t.Errorf("MigrationVersion = %d (dirty %v), want %d: добавьте миграцию в SchemaVersion", version, dirty, sqlite.SchemaVersion)
+	}
+}
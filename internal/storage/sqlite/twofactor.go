@@ -0,0 +1,213 @@
+package sqlite
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const totpColumns = `user_tgid, secret, created_at, confirmed_at, last_step, last_verified_at, failed_attempts, locked_until`
+
+// SaveTOTP заводит неподтвержденный фактор или заменяет прежний
+// неподтвержденный. Включенный фактор не заменяется: ErrTOTPExists.
+func (s *Storage) SaveTOTP(ctx context.Context, factor models.TOTPFactor) error {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO totp_factors (user_tgid, secret, created_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_tgid) DO UPDATE SET
+    secret = excluded.secret, created_at = excluded.created_at,
+    last_step = 0, failed_attempts = 0, locked_until = NULL
+WHERE totp_factors.confirmed_at IS NULL`, factor.UserID, factor.Secret, factor.CreatedAt.UTC())
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+		}
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return storage.ErrTOTPExists
+	}
+	return nil
+}
+
+func (s *Storage) TOTP(ctx context.Context, tgHash string) (models.TOTPFactor, error) {
+	var (
+		f                                    models.TOTPFactor
+		confirmedAt, verifiedAt, lockedUntil sql.NullTime
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT `+totpColumns+` FROM totp_factors WHERE user_tgid = ?`, tgHash).Scan(
+		&f.UserID, &f.Secret, &f.CreatedAt, &confirmedAt, &f.LastStep, &verifiedAt, &f.FailedAttempts, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTPFactor{}, fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+		}
+		return models.TOTPFactor{}, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if confirmedAt.Valid {
+		f.ConfirmedAt = &confirmedAt.Time
+	}
+	if verifiedAt.Valid {
+		f.LastVerifiedAt = &verifiedAt.Time
+	}
+	if lockedUntil.Valid {
+		f.LockedUntil = &lockedUntil.Time
+	}
+	return f, nil
+}
+
+// ConfirmTOTP включает заведенный фактор кодом шага step и сохраняет хэши
+// кодов восстановления.
+func (s *Storage) ConfirmTOTP(ctx context.Context, tgHash string, step int64, at time.Time, recoveryHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE totp_factors SET
+    confirmed_at = ?2, last_step = ?3, last_verified_at = ?2, failed_attempts = 0, locked_until = NULL
+WHERE user_tgid = ?1 AND confirmed_at IS NULL`, tgHash, at.UTC(), step)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+	}
+	if err := replaceRecoveryCodes(ctx, tx, tgHash, recoveryHashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// UseTOTPStep принимает код шага step, если коды этого и более поздних шагов
+// еще не использовались, и снимает счетчик неудач. false - код уже был.
+func (s *Storage) UseTOTPStep(ctx context.Context, tgHash string, step int64, at time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE totp_factors SET
+    last_step = ?2, last_verified_at = ?3, failed_attempts = 0, locked_until = NULL
+WHERE user_tgid = ?1 AND last_step < ?2`, tgHash, step, at.UTC())
+	if err != nil {
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// RecordOTPFailure увеличивает счетчик неверных кодов. На maxFailures-й
+// неудаче фактор блокируется до lockUntil, а счетчик обнуляется.
+func (s *Storage) RecordOTPFailure(ctx context.Context, tgHash string, maxFailures int, lockUntil time.Time) (bool, error) {
+	var locked bool
+	err := s.db.QueryRowContext(ctx, `UPDATE totp_factors SET
+    failed_attempts = CASE WHEN failed_attempts + 1 >= ?1 THEN 0 ELSE failed_attempts + 1 END,
+    locked_until    = CASE WHEN failed_attempts + 1 >= ?1 THEN ?2 ELSE locked_until END
+WHERE user_tgid = ?3
+RETURNING failed_attempts = 0`, maxFailures, lockUntil.UTC(), tgHash).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+		}
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return locked, nil
+}
+
+// DeleteTOTP удаляет фактор вместе с кодами восстановления.
+func (s *Storage) DeleteTOTP(ctx context.Context, tgHash string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM totp_factors WHERE user_tgid = ?`, tgHash)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Второй фактор не найден: %w", storage.ErrTOTPNotFound)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_tgid = ?`, tgHash); err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новыми.
+func (s *Storage) ReplaceRecoveryCodes(ctx context.Context, tgHash string, hashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, tgHash, hashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode погашает неиспользованный код восстановления и, как и
+// UseTOTPStep, отмечает подтверждение второго фактора. false - кода нет или
+// он уже использован.
+func (s *Storage) UseRecoveryCode(ctx context.Context, tgHash, hash string, at time.Time) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE recovery_codes SET used_at = ?
+WHERE user_tgid = ? AND code_hash = ? AND used_at IS NULL`, at.UTC(), tgHash, hash)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE totp_factors SET last_verified_at = ?, failed_attempts = 0, locked_until = NULL
+WHERE user_tgid = ?`, at.UTC(), tgHash)
+	if err != nil {
+		return false, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return true, nil
+}
+
+func (s *Storage) RecoveryCodesLeft(ctx context.Context, tgHash string) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM recovery_codes WHERE user_tgid = ? AND used_at IS NULL`, tgHash).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return n, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, tgHash string, hashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_tgid = ?`, tgHash); err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	for _, hash := range hashes {
+		_, err := tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_tgid, code_hash) VALUES (?, ?)
+ON CONFLICT DO NOTHING`, tgHash, hash)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return fmt.Errorf("Пользователь не найден: %w", storage.ErrUserNotFound)
+			}
+			return fmt.Errorf("Ошибка базы данных: %w", err)
+		}
+	}
+	return nil
+}
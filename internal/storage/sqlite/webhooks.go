@@ -0,0 +1,244 @@
+package sqlite
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// insertEvents пишет события outbox в транзакции изменения, которое они описывают.
+func insertEvents(ctx context.Context, tx *sql.Tx, events []models.OutboxEvent) error {
+	for _, e := range events {
+		_, err := tx.ExecContext(ctx, `INSERT INTO outbox_events (type, subject, app_id, data, created_at) VALUES (?, ?, ?, ?, ?)`,
+			e.Type, e.Subject, e.AppID, string(e.Data), e.CreatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("Ошибка записи события outbox: %w", err)
+		}
+	}
+	return nil
+}
+
+// Events возвращает события после afterID, видимые приложению appID: произошедшие
+// в нем и события пользователей, входивших в это приложение (app_members).
+func (s *Storage) Events(ctx context.Context, appID, afterID int64, limit int) ([]models.OutboxEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT e.id, e.type, e.data, e.created_at FROM outbox_events e
+WHERE e.id > ?2 AND (e.app_id = ?1
+    OR EXISTS (SELECT 1 FROM app_members m WHERE m.user_tgid = e.subject AND m.app_id = ?1))
+ORDER BY e.id LIMIT ?3`, appID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var (
+			e    models.OutboxEvent
+			data string
+		)
+		if err := rows.Scan(&e.ID, &e.Type, &data, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения события: %w", err)
+		}
+		e.Data = json.RawMessage(data)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (s *Storage) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("events: %w", err)
+	}
+	err = s.db.QueryRowContext(ctx, `INSERT INTO webhooks (app_id, url, events, created_at) VALUES (?, ?, ?, ?) RETURNING id`,
+		webhook.AppID, webhook.URL, string(events), webhook.CreatedAt.UTC()).Scan(&webhook.ID)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("Ошибка сохранения вебхука: %w", err)
+	}
+	return webhook, nil
+}
+
+func (s *Storage) Webhooks(ctx context.Context, appID int64) ([]models.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, app_id, url, events, created_at FROM webhooks WHERE app_id = ? ORDER BY id`, appID)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var (
+			w      models.Webhook
+			events string
+		)
+		if err := rows.Scan(&w.ID, &w.AppID, &w.URL, &events, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("Ошибка чтения вебхука: %w", err)
+		}
+		if err := json.Unmarshal([]byte(events), &w.Events); err != nil {
+			return nil, fmt.Errorf("events: %w", err)
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, appID, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ? AND app_id = ?`, id, appID)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Вебхук не найден: %w", storage.ErrWebhookNotFound)
+	}
+	return nil
+}
+
+// DispatchEvents раскладывает еще не обработанные события outbox по доставкам.
+// Событие получают вебхуки приложения, где оно произошло, и приложений, в которые
+// пользователь входил, даже если он уже вышел.
+func (s *Storage) DispatchEvents(ctx context.Context, now time.Time, limit int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	var last sql.NullInt64
+	err = tx.QueryRowContext(ctx, `SELECT MAX(id) FROM (
+    SELECT id FROM outbox_events WHERE dispatched_at IS NULL ORDER BY id LIMIT ?)`, limit).Scan(&last)
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if !last.Valid {
+		return 0, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO webhook_deliveries (webhook_id, event_id, status, next_attempt_at, created_at, updated_at)
+SELECT w.id, e.id, 'pending', ?1, ?1, ?1
+FROM outbox_events e JOIN webhooks w
+WHERE e.dispatched_at IS NULL AND e.id <= ?2
+  AND EXISTS (SELECT 1 FROM json_each(w.events) j WHERE j.value = e.type)
+  AND (w.app_id = e.app_id
+    OR EXISTS (SELECT 1 FROM app_members m WHERE m.user_tgid = e.subject AND m.app_id = w.app_id))`, now.UTC(), last.Int64)
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка раскладки событий: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE outbox_events SET dispatched_at = ? WHERE dispatched_at IS NULL AND id <= ?`, now.UTC(), last.Int64)
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("Ошибка комита: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// ClaimDeliveries забирает доставки, время которых пришло, и откладывает их до
+// leaseUntil: если процесс упадет посреди отправки, доставка повторится позже.
+func (s *Storage) ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.Delivery, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT d.id, d.webhook_id, d.event_id, d.status, d.attempts, d.created_at,
+    w.url, a.secret, e.type, e.data, e.created_at
+FROM webhook_deliveries d
+JOIN webhooks w ON w.id = d.webhook_id
+JOIN outbox_events e ON e.id = d.event_id
+JOIN apps a ON a.id = w.app_id
+WHERE d.status = 'pending' AND d.next_attempt_at <= ?
+ORDER BY d.next_attempt_at, d.id LIMIT ?`, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+
+	var deliveries []models.Delivery
+	for rows.Next() {
+		var (
+			d    models.Delivery
+			data string
+		)
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Status, &d.Attempts, &d.CreatedAt,
+			&d.URL, &d.Secret, &d.EventType, &data, &d.Event.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("Ошибка чтения доставки: %w", err)
+		}
+		d.Event.ID, d.Event.Type, d.Event.Data = d.EventID, d.EventType, json.RawMessage(data)
+		deliveries = append(deliveries, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+
+	for _, d := range deliveries {
+		if _, err := tx.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?`, leaseUntil.UTC(), d.ID); err != nil {
+			return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("Ошибка комита: %w", err)
+	}
+	return deliveries, nil
+}
+
+// UpdateDelivery сохраняет результат попытки доставки.
+func (s *Storage) UpdateDelivery(ctx context.Context, d models.Delivery) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries
+SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, response_status = ?, updated_at = ?
+WHERE id = ?`, d.Status, d.Attempts, d.NextAttemptAt.UTC(), d.LastError, d.ResponseStatus, d.UpdatedAt.UTC(), d.ID)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	return nil
+}
+
+// Deliveries возвращает доставки вебхуков приложения в статусе status по возрастанию id.
+func (s *Storage) Deliveries(ctx context.Context, appID int64, status string, afterID int64, limit int) ([]models.Delivery, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT d.id, d.webhook_id, d.event_id, e.type, w.url, d.status, d.attempts,
+    d.next_attempt_at, d.last_error, d.response_status, d.created_at, d.updated_at
+FROM webhook_deliveries d
+JOIN webhooks w ON w.id = d.webhook_id
+JOIN outbox_events e ON e.id = d.event_id
+WHERE w.app_id = ? AND d.status = ? AND d.id > ?
+ORDER BY d.id LIMIT ?`, appID, status, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.Delivery{}
+	for rows.Next() {
+		var d models.Delivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.URL, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastError, &d.ResponseStatus, &d.CreatedAt, &d.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения доставки: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RedeliverDelivery возвращает доставку приложения appID в очередь с обнуленным
+// счетчиком попыток.
+func (s *Storage) RedeliverDelivery(ctx context.Context, appID, id int64, now time.Time) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = ?1, last_error = '', response_status = 0, updated_at = ?1
+WHERE id = ?2 AND webhook_id IN (SELECT id FROM webhooks WHERE app_id = ?3)`, now.UTC(), id, appID)
+	if err != nil {
+		return fmt.Errorf("Ошибка базы данных: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("Доставка не найдена: %w", storage.ErrDeliveryNotFound)
+	}
+	return nil
+}
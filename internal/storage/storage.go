@@ -1,9 +1,89 @@
 package storage
 
-import "errors"
+import (
+	"auth-service/internal/domains/models"
+	"context"
+	"errors"
+	"time"
+)
 
 var (
 	ErrUserExist    = errors.New("User already exists")
 	ErrUserNotFound = errors.New("User not found")
+	ErrUserBanned   = errors.New("User is banned")
 	ErrAppNotFound  = errors.New("App not found")
+
+	ErrSessionNotFound  = errors.New("Session not found")
+	ErrWebhookNotFound  = errors.New("Webhook not found")
+	ErrDeliveryNotFound = errors.New("Delivery not found")
+	ErrHandoffNotFound  = errors.New("Handoff not found")
+	ErrIdentityNotFound = errors.New("Identity not found")
+	ErrIdentityExists   = errors.New("Identity already linked")
+
+	ErrEmailTokenNotFound = errors.New("Email token not found")
+	ErrTOTPNotFound       = errors.New("TOTP factor not found")
+	ErrTOTPExists         = errors.New("TOTP factor already enabled")
 )
+
+// Storage - полный набор методов, который реализует каждый бэкенд
+// (postgres, sqlite, memory). Сервисы зависят от своих узких интерфейсов.
+type Storage interface {
+	SaveUser(ctx context.Context, tgId string, User models.User, events ...models.OutboxEvent) error
+	ValidateUser(ctx context.Context, tgHash string) (models.UserResponse, error)
+	IsAdmin(ctx context.Context, tgHash string) (bool, error)
+	User(ctx context.Context, tgHash string) (models.User, error)
+	UserByUsername(ctx context.Context, username string) (models.User, error)
+	SetBanned(ctx context.Context, tgHash string, banned bool, events ...models.OutboxEvent) error
+	SetAdmin(ctx context.Context, tgHash string, admin bool, events ...models.OutboxEvent) error
+	UpdateProfile(ctx context.Context, tgHash string, profile models.User, events ...models.OutboxEvent) (bool, error)
+	App(ctx context.Context, serviceId int64) (models.App, error)
+	Apps(ctx context.Context) ([]models.App, error)
+	CreateSession(ctx context.Context, session models.Session) error
+	Session(ctx context.Context, id string) (models.Session, error)
+	Sessions(ctx context.Context, tgHash string) ([]models.Session, error)
+	TouchSession(ctx context.Context, id string, at time.Time) error
+	RevokeSession(ctx context.Context, tgHash, id string, at time.Time, events ...models.OutboxEvent) error
+	SessionHistory(ctx context.Context, tgHash string) ([]models.Session, error)
+	AppMemberships(ctx context.Context, tgHash string) ([]models.Membership, error)
+	ScheduleErasure(ctx context.Context, tgHash string, eraseAt *time.Time) error
+	ClaimErasures(ctx context.Context, now time.Time, limit int) ([]string, error)
+	EraseUser(ctx context.Context, tgHash, pseudonym string, now time.Time, events ...models.OutboxEvent) error
+	CreateHandoff(ctx context.Context, handoff models.Handoff) error
+	Handoff(ctx context.Context, code string, now time.Time) (models.Handoff, error)
+	ApproveHandoff(ctx context.Context, code, tgHash string, now time.Time) (models.Handoff, error)
+	ClaimHandoff(ctx context.Context, deviceHash string, now time.Time) (models.Handoff, error)
+	LinkIdentity(ctx context.Context, tgHash string, identity models.Identity) (models.Identity, error)
+	Identities(ctx context.Context, tgHash string) ([]models.Identity, error)
+	UnlinkIdentity(ctx context.Context, tgHash, provider string) error
+	UserByIdentity(ctx context.Context, provider, subject string) (models.User, error)
+	SaveEmailUser(ctx context.Context, tgHash string, user models.User, identity models.Identity, events ...models.OutboxEvent) (models.Identity, error)
+	EmailIdentity(ctx context.Context, email string) (models.Identity, error)
+	RecordLoginFailure(ctx context.Context, id int64, maxFailures int, lockUntil time.Time) (bool, error)
+	ResetLoginFailures(ctx context.Context, id int64) error
+	CreateEmailToken(ctx context.Context, token models.EmailToken) error
+	VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (models.Identity, error)
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (models.Identity, error)
+	SaveTOTP(ctx context.Context, factor models.TOTPFactor) error
+	TOTP(ctx context.Context, tgHash string) (models.TOTPFactor, error)
+	ConfirmTOTP(ctx context.Context, tgHash string, step int64, at time.Time, recoveryHashes []string) error
+	UseTOTPStep(ctx context.Context, tgHash string, step int64, at time.Time) (bool, error)
+	RecordOTPFailure(ctx context.Context, tgHash string, maxFailures int, lockUntil time.Time) (bool, error)
+	DeleteTOTP(ctx context.Context, tgHash string) error
+	ReplaceRecoveryCodes(ctx context.Context, tgHash string, hashes []string) error
+	UseRecoveryCode(ctx context.Context, tgHash, hash string, at time.Time) (bool, error)
+	RecoveryCodesLeft(ctx context.Context, tgHash string) (int, error)
+	StepUpSession(ctx context.Context, id string, at time.Time) error
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+	AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+	Events(ctx context.Context, appID, afterID int64, limit int) ([]models.OutboxEvent, error)
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	Webhooks(ctx context.Context, appID int64) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, appID, id int64) error
+	DispatchEvents(ctx context.Context, now time.Time, limit int) (int, error)
+	ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.Delivery, error)
+	UpdateDelivery(ctx context.Context, delivery models.Delivery) error
+	Deliveries(ctx context.Context, appID int64, status string, afterID int64, limit int) ([]models.Delivery, error)
+	RedeliverDelivery(ctx context.Context, appID, id int64, now time.Time) error
+	Ping(ctx context.Context) error
+	Close()
+}
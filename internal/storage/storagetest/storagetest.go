@@ -0,0 +1,1167 @@
+// Package storagetest содержит общий набор проверок, который должен проходить
+// любой бэкенд хранилища, чтобы его можно было передать в auth.New.
+package storagetest
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Storage interface {
+	SaveUser(ctx context.Context, tgId string, User models.User, events ...models.OutboxEvent) error
+	ValidateUser(ctx context.Context, tgHash string) (models.UserResponse, error)
+	IsAdmin(ctx context.Context, tgHash string) (bool, error)
+	User(ctx context.Context, tgHash string) (models.User, error)
+	UserByUsername(ctx context.Context, username string) (models.User, error)
+	SetBanned(ctx context.Context, tgHash string, banned bool, events ...models.OutboxEvent) error
+	SetAdmin(ctx context.Context, tgHash string, admin bool, events ...models.OutboxEvent) error
+	UpdateProfile(ctx context.Context, tgHash string, profile models.User, events ...models.OutboxEvent) (bool, error)
+	App(ctx context.Context, serviceId int64) (models.App, error)
+	Apps(ctx context.Context) ([]models.App, error)
+	Ping(ctx context.Context) error
+	CreateSession(ctx context.Context, session models.Session) error
+	Session(ctx context.Context, id string) (models.Session, error)
+	Sessions(ctx context.Context, tgHash string) ([]models.Session, error)
+	TouchSession(ctx context.Context, id string, at time.Time) error
+	RevokeSession(ctx context.Context, tgHash, id string, at time.Time, events ...models.OutboxEvent) error
+	SessionHistory(ctx context.Context, tgHash string) ([]models.Session, error)
+	AppMemberships(ctx context.Context, tgHash string) ([]models.Membership, error)
+	ScheduleErasure(ctx context.Context, tgHash string, eraseAt *time.Time) error
+	ClaimErasures(ctx context.Context, now time.Time, limit int) ([]string, error)
+	EraseUser(ctx context.Context, tgHash, pseudonym string, now time.Time, events ...models.OutboxEvent) error
+	CreateHandoff(ctx context.Context, handoff models.Handoff) error
+	Handoff(ctx context.Context, code string, now time.Time) (models.Handoff, error)
+	ApproveHandoff(ctx context.Context, code, tgHash string, now time.Time) (models.Handoff, error)
+	ClaimHandoff(ctx context.Context, deviceHash string, now time.Time) (models.Handoff, error)
+	LinkIdentity(ctx context.Context, tgHash string, identity models.Identity) (models.Identity, error)
+	Identities(ctx context.Context, tgHash string) ([]models.Identity, error)
+	UnlinkIdentity(ctx context.Context, tgHash, provider string) error
+	UserByIdentity(ctx context.Context, provider, subject string) (models.User, error)
+	SaveEmailUser(ctx context.Context, tgHash string, user models.User, identity models.Identity, events ...models.OutboxEvent) (models.Identity, error)
+	EmailIdentity(ctx context.Context, email string) (models.Identity, error)
+	RecordLoginFailure(ctx context.Context, id int64, maxFailures int, lockUntil time.Time) (bool, error)
+	ResetLoginFailures(ctx context.Context, id int64) error
+	CreateEmailToken(ctx context.Context, token models.EmailToken) error
+	VerifyEmail(ctx context.Context, tokenHash string, now time.Time) (models.Identity, error)
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string, now time.Time) (models.Identity, error)
+	SaveTOTP(ctx context.Context, factor models.TOTPFactor) error
+	TOTP(ctx context.Context, tgHash string) (models.TOTPFactor, error)
+	ConfirmTOTP(ctx context.Context, tgHash string, step int64, at time.Time, recoveryHashes []string) error
+	UseTOTPStep(ctx context.Context, tgHash string, step int64, at time.Time) (bool, error)
+	RecordOTPFailure(ctx context.Context, tgHash string, maxFailures int, lockUntil time.Time) (bool, error)
+	DeleteTOTP(ctx context.Context, tgHash string) error
+	ReplaceRecoveryCodes(ctx context.Context, tgHash string, hashes []string) error
+	UseRecoveryCode(ctx context.Context, tgHash, hash string, at time.Time) (bool, error)
+	RecoveryCodesLeft(ctx context.Context, tgHash string) (int, error)
+	StepUpSession(ctx context.Context, id string, at time.Time) error
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+	AuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+	Events(ctx context.Context, appID, afterID int64, limit int) ([]models.OutboxEvent, error)
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	Webhooks(ctx context.Context, appID int64) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, appID, id int64) error
+	DispatchEvents(ctx context.Context, now time.Time, limit int) (int, error)
+	ClaimDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.Delivery, error)
+	UpdateDelivery(ctx context.Context, delivery models.Delivery) error
+	Deliveries(ctx context.Context, appID int64, status string, afterID int64, limit int) ([]models.Delivery, error)
+	RedeliverDelivery(ctx context.Context, appID, id int64, now time.Time) error
+}
+
+// Run прогоняет набор проверок. newStorage должен возвращать хранилище,
+// в котором есть приложение с id 1 (его добавляет миграция 2_add_app).
+// Для Postgres вызывающий код сам решает, доступна ли база, и пропускает тест через t.Skip.
+func Run(t *testing.T, newStorage func(t *testing.T) Storage) {
+	t.Helper()
+
+	t.Run("SaveAndValidate", func(t *testing.T) { testSaveAndValidate(t, newStorage(t)) })
+	t.Run("UniqueTgId", func(t *testing.T) { testUniqueTgId(t, newStorage(t)) })
+	t.Run("UserNotFound", func(t *testing.T) { testUserNotFound(t, newStorage(t)) })
+	t.Run("Banned", func(t *testing.T) { testBanned(t, newStorage(t)) })
+	t.Run("IsAdmin", func(t *testing.T) { testIsAdmin(t, newStorage(t)) })
+	t.Run("User", func(t *testing.T) { testUser(t, newStorage(t)) })
+	t.Run("UserByUsername", func(t *testing.T) { testUserByUsername(t, newStorage(t)) })
+	t.Run("App", func(t *testing.T) { testApp(t, newStorage(t)) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, newStorage(t)) })
+	t.Run("Handoffs", func(t *testing.T) { testHandoffs(t, newStorage(t)) })
+	t.Run("Identities", func(t *testing.T) { testIdentities(t, newStorage(t)) })
+	t.Run("Passwords", func(t *testing.T) { testPasswords(t, newStorage(t)) })
+	t.Run("LinkedEmailHijack", func(t *testing.T) { testLinkedEmailHijack(t, newStorage(t)) })
+	t.Run("TwoFactor", func(t *testing.T) { testTwoFactor(t, newStorage(t)) })
+	t.Run("AuditEvents", func(t *testing.T) { testAuditEvents(t, newStorage(t)) })
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newStorage(t)) })
+	t.Run("Events", func(t *testing.T) { testEvents(t, newStorage(t)) })
+	t.Run("Erasure", func(t *testing.T) { testErasure(t, newStorage(t)) })
+}
+
+// uniqueTgId не дает тестам конфликтовать между собой на общей базе.
+func uniqueTgId(t *testing.T) string {
+	return fmt.Sprintf("test-%s-%d", t.Name(), time.Now().UnixNano())
+}
+
+func testSaveAndValidate(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+
+	err := s.SaveUser(ctx, tgId, models.User{
+		FirstName:      "Pavel",
+		LastName:       "Durov",
+		Username:       "durov",
+		UserNameLocale: "durov_locale",
+		PhotoURL:       "https://t.me/i/userpic/durov.jpg",
+	})
+	if err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	user, err := s.ValidateUser(ctx, tgId)
+	if err != nil {
+		t.Fatalf("ValidateUser: %v", err)
+	}
+	if user.TgId != tgId {
+		t.Errorf("TgId = %q, want %q", user.TgId, tgId)
+	}
+	if user.ID == "" {
+		t.Error("ID is empty")
+	}
+	if user.Username != "durov" || user.UserNameLocale != "durov_locale" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if user.IsBanned {
+		t.Error("new user is banned")
+	}
+}
+
+func testUniqueTgId(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+
+	if err := s.SaveUser(ctx, tgId, models.User{Username: "first"}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	err := s.SaveUser(ctx, tgId, models.User{Username: "second"})
+	if !errors.Is(err, storage.ErrUserExist) {
+		t.Fatalf("second SaveUser error = %v, want %v", err, storage.ErrUserExist)
+	}
+}
+
+func testUserNotFound(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+
+	if _, err := s.ValidateUser(ctx, tgId); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("ValidateUser error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+	if _, err := s.IsAdmin(ctx, tgId); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("IsAdmin error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+	if _, err := s.User(ctx, tgId); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("User error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+	if err := s.SetBanned(ctx, tgId, true); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("SetBanned error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+}
+
+func testBanned(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+
+	if err := s.SaveUser(ctx, tgId, models.User{}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.SetBanned(ctx, tgId, true); err != nil {
+		t.Fatalf("SetBanned(true): %v", err)
+	}
+	if _, err := s.ValidateUser(ctx, tgId); !errors.Is(err, storage.ErrUserBanned) {
+		t.Fatalf("ValidateUser error = %v, want %v", err, storage.ErrUserBanned)
+	}
+	if err := s.SetBanned(ctx, tgId, false); err != nil {
+		t.Fatalf("SetBanned(false): %v", err)
+	}
+	if _, err := s.ValidateUser(ctx, tgId); err != nil {
+		t.Fatalf("ValidateUser after unban: %v", err)
+	}
+}
+
+func testIsAdmin(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+
+	if err := s.SaveUser(ctx, tgId, models.User{IsAdmin: true}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	isAdmin, err := s.IsAdmin(ctx, tgId)
+	if err != nil {
+		t.Fatalf("IsAdmin: %v", err)
+	}
+	if !isAdmin {
+		t.Error("IsAdmin = false, want true")
+	}
+
+	if err := s.SetAdmin(ctx, tgId, false); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+	if isAdmin, _ := s.IsAdmin(ctx, tgId); isAdmin {
+		t.Error("IsAdmin after SetAdmin(false) = true, want false")
+	}
+	if err := s.SetAdmin(ctx, uniqueTgId(t), true); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("SetAdmin(unknown) error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+}
+
+// testUser проверяет, что User отдает и забаненного пользователя - решение
+// принимает вызывающий код.
+func testUser(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+
+	if err := s.SaveUser(ctx, tgId, models.User{Username: "durov", IsAdmin: true}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.SetBanned(ctx, tgId, true); err != nil {
+		t.Fatalf("SetBanned: %v", err)
+	}
+	user, err := s.User(ctx, tgId)
+	if err != nil {
+		t.Fatalf("User: %v", err)
+	}
+	if user.TgId != tgId || user.Username != "durov" || !user.IsAdmin || !user.IsBanned {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+// testUserByUsername проверяет поиск по username без учета регистра.
+func testUserByUsername(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+	username := fmt.Sprintf("Moder_%d", time.Now().UnixNano())
+
+	if err := s.SaveUser(ctx, tgId, models.User{Username: username}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	user, err := s.UserByUsername(ctx, strings.ToLower(username))
+	if err != nil {
+		t.Fatalf("UserByUsername: %v", err)
+	}
+	if user.TgId != tgId || user.Username != username {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if _, err := s.UserByUsername(ctx, username+"_missing"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("UserByUsername(missing) error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+}
+
+func testApp(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	app, err := s.App(ctx, 1)
+	if err != nil {
+		t.Fatalf("App(1): %v", err)
+	}
+	if app.ID != 1 || app.Secret == "" {
+		t.Errorf("unexpected app %+v", app)
+	}
+	if _, err := s.App(ctx, -1); !errors.Is(err, storage.ErrAppNotFound) {
+		t.Errorf("App(-1) error = %v, want %v", err, storage.ErrAppNotFound)
+	}
+
+	apps, err := s.Apps(ctx)
+	if err != nil {
+		t.Fatalf("Apps: %v", err)
+	}
+	if !slices.ContainsFunc(apps, func(a models.App) bool { return a.ID == app.ID && a.Secret == app.Secret }) {
+		t.Errorf("Apps = %+v, want app 1", apps)
+	}
+	if err := s.Ping(ctx); err != nil {
+		t.Errorf("Ping: %v", err)
+	}
+}
+
+func testSessions(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+
+	if err := s.SaveUser(ctx, tgId, models.User{}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	created := time.Now().Add(-time.Hour).Truncate(time.Second)
+	older := models.Session{ID: tgId + "-1", UserID: tgId, AppID: 1, Platform: "ios", UserAgent: "ua", IP: "10.0.0.1", CreatedAt: created, LastSeenAt: created}
+	newer := models.Session{ID: tgId + "-2", UserID: tgId, AppID: 1, CreatedAt: created.Add(time.Minute), LastSeenAt: created.Add(time.Minute)}
+	for _, session := range []models.Session{older, newer} {
+		if err := s.CreateSession(ctx, session); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+	}
+
+	sessions, err := s.Sessions(ctx, tgId)
+	if err != nil {
+		t.Fatalf("Sessions: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != newer.ID || sessions[1].ID != older.ID {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+	if got := sessions[1]; got.Platform != "ios" || got.IP != "10.0.0.1" || !got.CreatedAt.Equal(created) {
+		t.Errorf("unexpected session %+v", got)
+	}
+
+	seen := created.Add(30 * time.Minute)
+	if err := s.TouchSession(ctx, older.ID, seen); err != nil {
+		t.Fatalf("TouchSession: %v", err)
+	}
+	if err := s.TouchSession(ctx, older.ID, created); err != nil {
+		t.Fatalf("TouchSession: %v", err)
+	}
+	got, err := s.Session(ctx, older.ID)
+	if err != nil {
+		t.Fatalf("Session: %v", err)
+	}
+	if !got.LastSeenAt.Equal(seen) {
+		t.Errorf("LastSeenAt = %v, want %v", got.LastSeenAt, seen)
+	}
+
+	if err := s.RevokeSession(ctx, "someone-else", older.ID, seen); !errors.Is(err, storage.ErrSessionNotFound) {
+		t.Errorf("RevokeSession by another user error = %v, want %v", err, storage.ErrSessionNotFound)
+	}
+	if err := s.RevokeSession(ctx, tgId, older.ID, seen); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if err := s.RevokeSession(ctx, tgId, older.ID, seen); !errors.Is(err, storage.ErrSessionNotFound) {
+		t.Errorf("second RevokeSession error = %v, want %v", err, storage.ErrSessionNotFound)
+	}
+	if got, err := s.Session(ctx, older.ID); err != nil || got.Active() {
+		t.Errorf("Session after revoke = %+v, %v", got, err)
+	}
+	if sessions, err := s.Sessions(ctx, tgId); err != nil || len(sessions) != 1 {
+		t.Errorf("Sessions after revoke = %+v, %v", sessions, err)
+	}
+	if _, err := s.Session(ctx, tgId+"-missing"); !errors.Is(err, storage.ErrSessionNotFound) {
+		t.Errorf("Session error = %v, want %v", err, storage.ErrSessionNotFound)
+	}
+}
+
+// testHandoffs проверяет вход по коду: подтверждается один раз, до подтверждения
+// опрос видит код без пользователя, подтвержденный код отдается только один раз.
+func testHandoffs(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if err := s.SaveUser(ctx, tgId, models.User{}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	handoff := models.Handoff{
+		Code:       "code-" + tgId,
+		DeviceHash: "device-" + tgId,
+		AppID:      1,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(5 * time.Minute),
+		IP:         "203.0.113.7",
+		UserAgent:  "Mozilla/5.0 (X11; Linux x86_64)",
+		Platform:   "web",
+	}
+	if err := s.CreateHandoff(ctx, handoff); err != nil {
+		t.Fatalf("CreateHandoff: %v", err)
+	}
+
+	got, err := s.Handoff(ctx, handoff.Code, now)
+	if err != nil {
+		t.Fatalf("Handoff: %v", err)
+	}
+	if got.AppID != 1 || got.IP != handoff.IP || got.UserAgent != handoff.UserAgent || got.Platform != handoff.Platform ||
+		!got.ExpiresAt.Equal(handoff.ExpiresAt) {
+		t.Errorf("Handoff = %+v, want requester of %+v", got, handoff)
+	}
+	if _, err := s.Handoff(ctx, "missing-"+tgId, now); !errors.Is(err, storage.ErrHandoffNotFound) {
+		t.Errorf("Handoff(missing) error = %v, want %v", err, storage.ErrHandoffNotFound)
+	}
+
+	pending, err := s.ClaimHandoff(ctx, handoff.DeviceHash, now)
+	if err != nil {
+		t.Fatalf("ClaimHandoff(pending): %v", err)
+	}
+	if pending.UserID != "" || pending.AppID != 1 {
+		t.Errorf("pending handoff = %+v, want no user", pending)
+	}
+
+	approved, err := s.ApproveHandoff(ctx, handoff.Code, tgId, now)
+	if err != nil {
+		t.Fatalf("ApproveHandoff: %v", err)
+	}
+	if approved.UserID != tgId || approved.ApprovedAt == nil || approved.IP != handoff.IP {
+		t.Errorf("approved handoff = %+v", approved)
+	}
+	if _, err := s.Handoff(ctx, handoff.Code, now); !errors.Is(err, storage.ErrHandoffNotFound) {
+		t.Errorf("Handoff(approved) error = %v, want %v", err, storage.ErrHandoffNotFound)
+	}
+	if _, err := s.ApproveHandoff(ctx, handoff.Code, tgId, now); !errors.Is(err, storage.ErrHandoffNotFound) {
+		t.Errorf("second ApproveHandoff error = %v, want %v", err, storage.ErrHandoffNotFound)
+	}
+
+	claimed, err := s.ClaimHandoff(ctx, handoff.DeviceHash, now)
+	if err != nil {
+		t.Fatalf("ClaimHandoff: %v", err)
+	}
+	if claimed.UserID != tgId || claimed.AppID != 1 {
+		t.Errorf("claimed handoff = %+v", claimed)
+	}
+	if _, err := s.ClaimHandoff(ctx, handoff.DeviceHash, now); !errors.Is(err, storage.ErrHandoffNotFound) {
+		t.Errorf("second ClaimHandoff error = %v, want %v", err, storage.ErrHandoffNotFound)
+	}
+
+	expired := handoff
+	expired.Code, expired.DeviceHash = "expired-"+tgId, "expired-device-"+tgId
+	if err := s.CreateHandoff(ctx, expired); err != nil {
+		t.Fatalf("CreateHandoff(expired): %v", err)
+	}
+	later := expired.ExpiresAt.Add(time.Second)
+	if _, err := s.Handoff(ctx, expired.Code, later); !errors.Is(err, storage.ErrHandoffNotFound) {
+		t.Errorf("Handoff(expired) error = %v, want %v", err, storage.ErrHandoffNotFound)
+	}
+	if _, err := s.ApproveHandoff(ctx, expired.Code, tgId, later); !errors.Is(err, storage.ErrHandoffNotFound) {
+		t.Errorf("ApproveHandoff(expired) error = %v, want %v", err, storage.ErrHandoffNotFound)
+	}
+	if _, err := s.ClaimHandoff(ctx, expired.DeviceHash, later); !errors.Is(err, storage.ErrHandoffNotFound) {
+		t.Errorf("ClaimHandoff(expired) error = %v, want %v", err, storage.ErrHandoffNotFound)
+	}
+}
+
+// testIdentities проверяет привязку внешних учетных записей: одна на провайдера
+// у аккаунта, одна учетная запись провайдера - одному аккаунту.
+func testIdentities(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId, other := uniqueTgId(t), uniqueTgId(t)+"-other"
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, id := range []string{tgId, other} {
+		if err := s.SaveUser(ctx, id, models.User{FirstName: "Pavel"}); err != nil {
+			t.Fatalf("SaveUser: %v", err)
+		}
+	}
+	google := models.Identity{Provider: models.ProviderGoogle, Subject: "google-" + tgId, Email: "pavel@example.com", CreatedAt: now}
+	linked, err := s.LinkIdentity(ctx, tgId, google)
+	if err != nil {
+		t.Fatalf("LinkIdentity: %v", err)
+	}
+	if linked.ID == 0 || linked.UserID != tgId {
+		t.Errorf("linked identity = %+v", linked)
+	}
+	if _, err := s.LinkIdentity(ctx, "missing-"+tgId, google); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("LinkIdentity(missing user) error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+	if _, err := s.LinkIdentity(ctx, other, google); !errors.Is(err, storage.ErrIdentityExists) {
+		t.Errorf("LinkIdentity(taken subject) error = %v, want %v", err, storage.ErrIdentityExists)
+	}
+	second := google
+	second.Subject = "google2-" + tgId
+	if _, err := s.LinkIdentity(ctx, tgId, second); !errors.Is(err, storage.ErrIdentityExists) {
+		t.Errorf("LinkIdentity(second google) error = %v, want %v", err, storage.ErrIdentityExists)
+	}
+	apple := models.Identity{Provider: models.ProviderApple, Subject: "apple-" + tgId, CreatedAt: now}
+	if _, err := s.LinkIdentity(ctx, tgId, apple); err != nil {
+		t.Fatalf("LinkIdentity(apple): %v", err)
+	}
+
+	identities, err := s.Identities(ctx, tgId)
+	if err != nil || len(identities) != 2 || identities[0].Provider != models.ProviderGoogle ||
+		identities[0].Email != google.Email || !identities[0].CreatedAt.Equal(now) {
+		t.Errorf("Identities = %+v, %v", identities, err)
+	}
+	user, err := s.UserByIdentity(ctx, models.ProviderApple, apple.Subject)
+	if err != nil || user.TgId != tgId {
+		t.Errorf("UserByIdentity = %+v, %v", user, err)
+	}
+	if _, err := s.UserByIdentity(ctx, models.ProviderGoogle, apple.Subject); !errors.Is(err, storage.ErrIdentityNotFound) {
+		t.Errorf("UserByIdentity(wrong provider) error = %v, want %v", err, storage.ErrIdentityNotFound)
+	}
+
+	if err := s.UnlinkIdentity(ctx, other, models.ProviderGoogle); !errors.Is(err, storage.ErrIdentityNotFound) {
+		t.Errorf("UnlinkIdentity(other user) error = %v, want %v", err, storage.ErrIdentityNotFound)
+	}
+	if err := s.UnlinkIdentity(ctx, tgId, models.ProviderGoogle); err != nil {
+		t.Fatalf("UnlinkIdentity: %v", err)
+	}
+	if _, err := s.UserByIdentity(ctx, models.ProviderGoogle, google.Subject); !errors.Is(err, storage.ErrIdentityNotFound) {
+		t.Errorf("UserByIdentity after unlink error = %v, want %v", err, storage.ErrIdentityNotFound)
+	}
+	if _, err := s.LinkIdentity(ctx, other, google); err != nil {
+		t.Errorf("LinkIdentity after unlink: %v", err)
+	}
+}
+
+func testPasswords(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id := uniqueTgId(t)
+	tgId := models.AccountPrefix + id
+	email := id + "@example.com"
+	now := time.Now().UTC().Truncate(time.Second)
+
+	identity := models.Identity{Provider: models.ProviderEmail, Subject: email, Email: email, CreatedAt: now, PasswordHash: "hash-1"}
+	saved, err := s.SaveEmailUser(ctx, tgId, models.User{FirstName: "Pavel"}, identity)
+	if err != nil {
+		t.Fatalf("SaveEmailUser: %v", err)
+	}
+	if saved.ID == 0 || saved.UserID != tgId {
+		t.Errorf("saved identity = %+v", saved)
+	}
+	if user, err := s.User(ctx, tgId); err != nil || user.FirstName != "Pavel" {
+		t.Errorf("User = %+v, %v", user, err)
+	}
+	if _, err := s.SaveEmailUser(ctx, tgId+"-other", models.User{}, identity); !errors.Is(err, storage.ErrIdentityExists) {
+		t.Errorf("SaveEmailUser(taken email) error = %v, want %v", err, storage.ErrIdentityExists)
+	}
+	if _, err := s.User(ctx, tgId+"-other"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("User after failed SaveEmailUser error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+
+	got, err := s.EmailIdentity(ctx, email)
+	if err != nil || got.ID != saved.ID || got.UserID != tgId || got.PasswordHash != "hash-1" || got.VerifiedAt != nil {
+		t.Errorf("EmailIdentity = %+v, %v", got, err)
+	}
+	if _, err := s.EmailIdentity(ctx, "missing-"+email); !errors.Is(err, storage.ErrIdentityNotFound) {
+		t.Errorf("EmailIdentity(missing) error = %v, want %v", err, storage.ErrIdentityNotFound)
+	}
+
+	lockUntil := now.Add(15 * time.Minute)
+	for i := 1; i <= 3; i++ {
+		locked, err := s.RecordLoginFailure(ctx, saved.ID, 3, lockUntil)
+		if err != nil || locked != (i == 3) {
+			t.Errorf("RecordLoginFailure #%d = %v, %v", i, locked, err)
+		}
+	}
+	got, _ = s.EmailIdentity(ctx, email)
+	if got.FailedLogins != 0 || got.LockedUntil == nil || !got.LockedUntil.Equal(lockUntil) {
+		t.Errorf("identity after lockout = %+v", got)
+	}
+	s.RecordLoginFailure(ctx, saved.ID, 3, lockUntil)
+	if err := s.ResetLoginFailures(ctx, saved.ID); err != nil {
+		t.Fatalf("ResetLoginFailures: %v", err)
+	}
+	got, _ = s.EmailIdentity(ctx, email)
+	if got.FailedLogins != 0 || got.LockedUntil != nil {
+		t.Errorf("identity after ResetLoginFailures = %+v", got)
+	}
+
+	verify := models.EmailToken{Hash: "verify-" + tgId, IdentityID: saved.ID, Purpose: models.EmailTokenVerify, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if err := s.CreateEmailToken(ctx, verify); err != nil {
+		t.Fatalf("CreateEmailToken: %v", err)
+	}
+	if _, err := s.ResetPassword(ctx, verify.Hash, "hash-2", now); !errors.Is(err, storage.ErrEmailTokenNotFound) {
+		t.Errorf("ResetPassword(verify token) error = %v, want %v", err, storage.ErrEmailTokenNotFound)
+	}
+	if _, err := s.VerifyEmail(ctx, verify.Hash, verify.ExpiresAt); !errors.Is(err, storage.ErrEmailTokenNotFound) {
+		t.Errorf("VerifyEmail(expired) error = %v, want %v", err, storage.ErrEmailTokenNotFound)
+	}
+	verified, err := s.VerifyEmail(ctx, verify.Hash, now)
+	if err != nil || verified.ID != saved.ID || verified.VerifiedAt == nil || !verified.VerifiedAt.Equal(now) {
+		t.Errorf("VerifyEmail = %+v, %v", verified, err)
+	}
+	if _, err := s.VerifyEmail(ctx, verify.Hash, now); !errors.Is(err, storage.ErrEmailTokenNotFound) {
+		t.Errorf("VerifyEmail(reused) error = %v, want %v", err, storage.ErrEmailTokenNotFound)
+	}
+
+	// Новый токен сброса отменяет прежний.
+	first := models.EmailToken{Hash: "reset1-" + tgId, IdentityID: saved.ID, Purpose: models.EmailTokenReset, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	second := first
+	second.Hash = "reset2-" + tgId
+	for _, token := range []models.EmailToken{first, second} {
+		if err := s.CreateEmailToken(ctx, token); err != nil {
+			t.Fatalf("CreateEmailToken(reset): %v", err)
+		}
+	}
+	if _, err := s.ResetPassword(ctx, first.Hash, "hash-2", now); !errors.Is(err, storage.ErrEmailTokenNotFound) {
+		t.Errorf("ResetPassword(replaced token) error = %v, want %v", err, storage.ErrEmailTokenNotFound)
+	}
+	s.RecordLoginFailure(ctx, saved.ID, 1, lockUntil)
+	reset, err := s.ResetPassword(ctx, second.Hash, "hash-2", now.Add(time.Minute))
+	if err != nil || reset.PasswordHash != "hash-2" || reset.LockedUntil != nil || !reset.VerifiedAt.Equal(now) {
+		t.Errorf("ResetPassword = %+v, %v", reset, err)
+	}
+
+	if err := s.CreateEmailToken(ctx, models.EmailToken{Hash: "missing-" + tgId, IdentityID: -1, Purpose: models.EmailTokenVerify, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}); !errors.Is(err, storage.ErrIdentityNotFound) {
+		t.Errorf("CreateEmailToken(missing identity) error = %v, want %v", err, storage.ErrIdentityNotFound)
+	}
+}
+
+// testLinkedEmailHijack проверяет, что чужой адрес, привязанный к своему аккаунту
+// без подтверждения, не перехватывает его владельца: сброс пароля по письму не
+// подтверждает такую учетную запись, а регистрация с этим адресом ее заменяет.
+func testLinkedEmailHijack(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id := uniqueTgId(t)
+	attacker, victim := id, models.AccountPrefix+id
+	email := id + "@example.com"
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if err := s.SaveUser(ctx, attacker, models.User{FirstName: "Mallory"}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	identity := models.Identity{Provider: models.ProviderEmail, Subject: email, Email: email, CreatedAt: now, PasswordHash: "attacker-hash"}
+	linked, err := s.LinkIdentity(ctx, attacker, identity)
+	if err != nil {
+		t.Fatalf("LinkIdentity: %v", err)
+	}
+
+	// Владелец адреса получает письмо о занятом адресе и сбрасывает пароль.
+	reset := models.EmailToken{Hash: "reset-" + id, IdentityID: linked.ID, Purpose: models.EmailTokenReset, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if err := s.CreateEmailToken(ctx, reset); err != nil {
+		t.Fatalf("CreateEmailToken: %v", err)
+	}
+	got, err := s.ResetPassword(ctx, reset.Hash, "victim-hash", now)
+	if err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if got.VerifiedAt != nil {
+		t.Errorf("ResetPassword verified identity linked by another account: %+v", got)
+	}
+
+	pending := models.EmailToken{Hash: "verify-" + id, IdentityID: linked.ID, Purpose: models.EmailTokenVerify, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if err := s.CreateEmailToken(ctx, pending); err != nil {
+		t.Fatalf("CreateEmailToken: %v", err)
+	}
+	identity.PasswordHash = "victim-hash"
+	saved, err := s.SaveEmailUser(ctx, victim, models.User{FirstName: "Pavel"}, identity)
+	if err != nil {
+		t.Fatalf("SaveEmailUser over unverified linked identity: %v", err)
+	}
+	if got, err := s.EmailIdentity(ctx, email); err != nil || got.ID != saved.ID || got.UserID != victim {
+		t.Errorf("EmailIdentity after registration = %+v, %v, want identity of %s", got, err, victim)
+	}
+	if identities, err := s.Identities(ctx, attacker); err != nil || len(identities) != 0 {
+		t.Errorf("attacker identities after registration = %+v, %v", identities, err)
+	}
+	if _, err := s.VerifyEmail(ctx, pending.Hash, now); !errors.Is(err, storage.ErrEmailTokenNotFound) {
+		t.Errorf("VerifyEmail(token of replaced identity) error = %v, want %v", err, storage.ErrEmailTokenNotFound)
+	}
+
+	// Адрес, с которым зарегистрирован аккаунт, повторная регистрация не отнимает.
+	if _, err := s.SaveEmailUser(ctx, victim+"-other", models.User{}, identity); !errors.Is(err, storage.ErrIdentityExists) {
+		t.Errorf("SaveEmailUser(taken email) error = %v, want %v", err, storage.ErrIdentityExists)
+	}
+}
+
+// testTwoFactor проверяет жизненный цикл TOTP: заведенный фактор можно
+// перезавести, включенный - нет; код шага принимается один раз, коды
+// восстановления погашаются по одному.
+func testTwoFactor(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if err := s.SaveTOTP(ctx, models.TOTPFactor{UserID: tgId, Secret: "s", CreatedAt: now}); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("SaveTOTP(missing user) error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+	if err := s.SaveUser(ctx, tgId, models.User{}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if _, err := s.TOTP(ctx, tgId); !errors.Is(err, storage.ErrTOTPNotFound) {
+		t.Errorf("TOTP(missing) error = %v, want %v", err, storage.ErrTOTPNotFound)
+	}
+	for _, secret := range []string{"secret-1", "secret-2"} {
+		if err := s.SaveTOTP(ctx, models.TOTPFactor{UserID: tgId, Secret: secret, CreatedAt: now}); err != nil {
+			t.Fatalf("SaveTOTP(%s): %v", secret, err)
+		}
+	}
+	factor, err := s.TOTP(ctx, tgId)
+	if err != nil || factor.Secret != "secret-2" || factor.Confirmed() || factor.LastStep != 0 {
+		t.Errorf("TOTP = %+v, %v", factor, err)
+	}
+
+	if err := s.ConfirmTOTP(ctx, tgId, 100, now, []string{"code-1", "code-2"}); err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	if err := s.ConfirmTOTP(ctx, tgId, 101, now, nil); !errors.Is(err, storage.ErrTOTPNotFound) {
+		t.Errorf("second ConfirmTOTP error = %v, want %v", err, storage.ErrTOTPNotFound)
+	}
+	if err := s.SaveTOTP(ctx, models.TOTPFactor{UserID: tgId, Secret: "secret-3", CreatedAt: now}); !errors.Is(err, storage.ErrTOTPExists) {
+		t.Errorf("SaveTOTP(confirmed) error = %v, want %v", err, storage.ErrTOTPExists)
+	}
+	factor, _ = s.TOTP(ctx, tgId)
+	if factor.Secret != "secret-2" || factor.ConfirmedAt == nil || !factor.ConfirmedAt.Equal(now) || factor.LastStep != 100 {
+		t.Errorf("TOTP after confirm = %+v", factor)
+	}
+
+	later := now.Add(time.Minute)
+	for step, want := range map[int64]bool{100: false, 99: false} {
+		if ok, err := s.UseTOTPStep(ctx, tgId, step, later); err != nil || ok != want {
+			t.Errorf("UseTOTPStep(%d) = %v, %v, want %v", step, ok, err, want)
+		}
+	}
+	if ok, err := s.UseTOTPStep(ctx, tgId, 101, later); err != nil || !ok {
+		t.Errorf("UseTOTPStep(101) = %v, %v", ok, err)
+	}
+	factor, _ = s.TOTP(ctx, tgId)
+	if factor.LastStep != 101 || factor.LastVerifiedAt == nil || !factor.LastVerifiedAt.Equal(later) {
+		t.Errorf("TOTP after UseTOTPStep = %+v", factor)
+	}
+
+	lockUntil := now.Add(15 * time.Minute)
+	for i := 1; i <= 2; i++ {
+		if locked, err := s.RecordOTPFailure(ctx, tgId, 2, lockUntil); err != nil || locked != (i == 2) {
+			t.Errorf("RecordOTPFailure #%d = %v, %v", i, locked, err)
+		}
+	}
+	factor, _ = s.TOTP(ctx, tgId)
+	if factor.FailedAttempts != 0 || factor.LockedUntil == nil || !factor.LockedUntil.Equal(lockUntil) {
+		t.Errorf("TOTP after lockout = %+v", factor)
+	}
+
+	if n, err := s.RecoveryCodesLeft(ctx, tgId); err != nil || n != 2 {
+		t.Errorf("RecoveryCodesLeft = %d, %v, want 2", n, err)
+	}
+	recovered := now.Add(2 * time.Minute)
+	if ok, err := s.UseRecoveryCode(ctx, tgId, "code-1", recovered); err != nil || !ok {
+		t.Errorf("UseRecoveryCode = %v, %v", ok, err)
+	}
+	for _, code := range []string{"code-1", "missing"} {
+		if ok, err := s.UseRecoveryCode(ctx, tgId, code, recovered); err != nil || ok {
+			t.Errorf("UseRecoveryCode(%s) = %v, %v, want false", code, ok, err)
+		}
+	}
+	factor, _ = s.TOTP(ctx, tgId)
+	if factor.LockedUntil != nil || !factor.LastVerifiedAt.Equal(recovered) {
+		t.Errorf("TOTP after UseRecoveryCode = %+v", factor)
+	}
+	if n, _ := s.RecoveryCodesLeft(ctx, tgId); n != 1 {
+		t.Errorf("RecoveryCodesLeft after use = %d, want 1", n)
+	}
+	if err := s.ReplaceRecoveryCodes(ctx, tgId, []string{"code-3", "code-4", "code-5"}); err != nil {
+		t.Fatalf("ReplaceRecoveryCodes: %v", err)
+	}
+	if ok, _ := s.UseRecoveryCode(ctx, tgId, "code-2", recovered); ok {
+		t.Errorf("UseRecoveryCode(replaced) = true")
+	}
+	if n, _ := s.RecoveryCodesLeft(ctx, tgId); n != 3 {
+		t.Errorf("RecoveryCodesLeft after replace = %d, want 3", n)
+	}
+
+	session := models.Session{ID: tgId + "-1", UserID: tgId, AppID: 1, CreatedAt: now, LastSeenAt: now}
+	if err := s.CreateSession(ctx, session); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := s.StepUpSession(ctx, session.ID, later); err != nil {
+		t.Fatalf("StepUpSession: %v", err)
+	}
+	if got, err := s.Session(ctx, session.ID); err != nil || got.MFAAt == nil || !got.MFAAt.Equal(later) {
+		t.Errorf("Session after StepUpSession = %+v, %v", got, err)
+	}
+	s.RevokeSession(ctx, tgId, session.ID, later)
+	if err := s.StepUpSession(ctx, session.ID, later); !errors.Is(err, storage.ErrSessionNotFound) {
+		t.Errorf("StepUpSession(revoked) error = %v, want %v", err, storage.ErrSessionNotFound)
+	}
+
+	if err := s.DeleteTOTP(ctx, tgId); err != nil {
+		t.Fatalf("DeleteTOTP: %v", err)
+	}
+	if err := s.DeleteTOTP(ctx, tgId); !errors.Is(err, storage.ErrTOTPNotFound) {
+		t.Errorf("second DeleteTOTP error = %v, want %v", err, storage.ErrTOTPNotFound)
+	}
+	if n, _ := s.RecoveryCodesLeft(ctx, tgId); n != 0 {
+		t.Errorf("RecoveryCodesLeft after DeleteTOTP = %d, want 0", n)
+	}
+}
+
+func testAuditEvents(t *testing.T, s Storage) {
+	ctx := context.Background()
+	user := uniqueTgId(t)
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	events := []models.AuditEvent{
+		{Type: models.AuditLogin, Actor: user, Subject: user, AppID: 1, IP: "10.0.0.1", Outcome: models.AuditSuccess, CreatedAt: start},
+		{Type: models.AuditLogin, Subject: user, AppID: 1, Outcome: models.AuditFailure, Reason: "user_banned", CreatedAt: start.Add(time.Minute)},
+		{Type: models.AuditBan, Actor: "app:2", Subject: user, AppID: 2, Outcome: models.AuditSuccess, CreatedAt: start.Add(2 * time.Minute)},
+	}
+	for _, e := range events {
+		if err := s.SaveAuditEvent(ctx, e); err != nil {
+			t.Fatalf("SaveAuditEvent: %v", err)
+		}
+	}
+
+	all, err := s.AuditEvents(ctx, models.AuditFilter{User: user, Limit: 10})
+	if err != nil {
+		t.Fatalf("AuditEvents: %v", err)
+	}
+	if len(all) != 3 || all[0].Type != models.AuditLogin || all[2].Type != models.AuditBan {
+		t.Fatalf("unexpected events %+v", all)
+	}
+	if all[0].ID >= all[1].ID || all[0].IP != "10.0.0.1" || !all[0].CreatedAt.Equal(start) {
+		t.Errorf("unexpected event %+v", all[0])
+	}
+
+	filters := map[string]models.AuditFilter{
+		"app":   {User: user, AppID: 2, Limit: 10},
+		"type":  {User: user, Type: models.AuditBan, Limit: 10},
+		"range": {User: user, Since: start.Add(2 * time.Minute), Until: start.Add(3 * time.Minute), Limit: 10},
+		"after": {User: user, AfterID: all[1].ID, Limit: 10},
+	}
+	for name, filter := range filters {
+		got, err := s.AuditEvents(ctx, filter)
+		if err != nil {
+			t.Fatalf("AuditEvents(%s): %v", name, err)
+		}
+		if len(got) != 1 || got[0].ID != all[2].ID {
+			t.Errorf("AuditEvents(%s) = %+v, want only the ban", name, got)
+		}
+	}
+
+	page, err := s.AuditEvents(ctx, models.AuditFilter{User: user, Limit: 2})
+	if err != nil {
+		t.Fatalf("AuditEvents: %v", err)
+	}
+	if len(page) != 2 {
+		t.Errorf("len(page) = %d, want 2", len(page))
+	}
+}
+
+func testWebhooks(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+	now := time.Now().Truncate(time.Second)
+
+	webhook, err := s.CreateWebhook(ctx, models.Webhook{
+		AppID:     1,
+		URL:       "https://example.com/hook",
+		Events:    []string{models.EventUserRegistered, models.EventSessionRevoked},
+		CreatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	webhooks, err := s.Webhooks(ctx, 1)
+	if err != nil {
+		t.Fatalf("Webhooks: %v", err)
+	}
+	found := false
+	for _, w := range webhooks {
+		if w.ID == webhook.ID {
+			found = len(w.Events) == 2 && w.URL == webhook.URL
+		}
+	}
+	if !found {
+		t.Fatalf("webhook %+v not found in %+v", webhook, webhooks)
+	}
+
+	event := func(typ string, appID int64) models.OutboxEvent {
+		return models.OutboxEvent{Type: typ, Subject: tgId, AppID: appID, Data: []byte(`{"sub":"` + tgId + `"}`), CreatedAt: now}
+	}
+	// user.registered в приложении 1 доставляется, user.banned без сессии в приложении 1 - нет.
+	if err := s.SaveUser(ctx, tgId, models.User{FirstName: "Pavel"}, event(models.EventUserRegistered, 1)); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.SetBanned(ctx, tgId, false, event(models.EventUserBanned, 0)); err != nil {
+		t.Fatalf("SetBanned: %v", err)
+	}
+	// session.revoked доставляется по сессии пользователя в приложении 1.
+	session := models.Session{ID: tgId, UserID: tgId, AppID: 1, CreatedAt: now, LastSeenAt: now}
+	if err := s.CreateSession(ctx, session); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := s.RevokeSession(ctx, tgId, session.ID, now, event(models.EventSessionRevoked, 0)); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+
+	changed, err := s.UpdateProfile(ctx, tgId, models.User{FirstName: "Pavel", Username: "durov"})
+	if err != nil || !changed {
+		t.Errorf("UpdateProfile = %v, %v, want true", changed, err)
+	}
+	changed, err = s.UpdateProfile(ctx, tgId, models.User{FirstName: "Pavel", Username: "durov"})
+	if err != nil || changed {
+		t.Errorf("repeated UpdateProfile = %v, %v, want false", changed, err)
+	}
+
+	for {
+		n, err := s.DispatchEvents(ctx, now, 100)
+		if err != nil {
+			t.Fatalf("DispatchEvents: %v", err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	claimed, err := s.ClaimDeliveries(ctx, now.Add(time.Second), now.Add(time.Minute), 1000)
+	if err != nil {
+		t.Fatalf("ClaimDeliveries: %v", err)
+	}
+	var mine []models.Delivery
+	for _, d := range claimed {
+		if d.WebhookID == webhook.ID {
+			mine = append(mine, d)
+		}
+	}
+	if len(mine) != 2 || mine[0].EventType == mine[1].EventType {
+		t.Fatalf("unexpected deliveries %+v", mine)
+	}
+	for _, d := range mine {
+		if d.URL != webhook.URL || d.Secret == "" || d.Event.Type != d.EventType || len(d.Event.Data) == 0 {
+			t.Errorf("unexpected delivery %+v", d)
+		}
+	}
+	if again, err := s.ClaimDeliveries(ctx, now.Add(time.Second), now.Add(time.Minute), 1000); err != nil || len(again) != 0 {
+		t.Errorf("ClaimDeliveries during lease = %+v, %v", again, err)
+	}
+
+	dead := mine[0]
+	dead.Status, dead.Attempts, dead.LastError, dead.ResponseStatus, dead.UpdatedAt = models.DeliveryDead, 3, "boom", 500, now
+	if err := s.UpdateDelivery(ctx, dead); err != nil {
+		t.Fatalf("UpdateDelivery: %v", err)
+	}
+	deliveries, err := s.Deliveries(ctx, 1, models.DeliveryDead, 0, 1000)
+	if err != nil {
+		t.Fatalf("Deliveries: %v", err)
+	}
+	found = false
+	for _, d := range deliveries {
+		if d.ID == dead.ID {
+			found = d.Attempts == 3 && d.LastError == "boom" && d.ResponseStatus == 500 && d.EventType == dead.EventType
+		}
+	}
+	if !found {
+		t.Errorf("dead delivery %d not found in %+v", dead.ID, deliveries)
+	}
+
+	if err := s.RedeliverDelivery(ctx, 2, dead.ID, now); !errors.Is(err, storage.ErrDeliveryNotFound) {
+		t.Errorf("RedeliverDelivery from another app error = %v, want %v", err, storage.ErrDeliveryNotFound)
+	}
+	if err := s.RedeliverDelivery(ctx, 1, dead.ID, now); err != nil {
+		t.Fatalf("RedeliverDelivery: %v", err)
+	}
+	pending, err := s.Deliveries(ctx, 1, models.DeliveryPending, dead.ID-1, 1)
+	if err != nil || len(pending) != 1 || pending[0].ID != dead.ID || pending[0].Attempts != 0 {
+		t.Errorf("Deliveries after redeliver = %+v, %v", pending, err)
+	}
+
+	if err := s.DeleteWebhook(ctx, 2, webhook.ID); !errors.Is(err, storage.ErrWebhookNotFound) {
+		t.Errorf("DeleteWebhook from another app error = %v, want %v", err, storage.ErrWebhookNotFound)
+	}
+	if err := s.DeleteWebhook(ctx, 1, webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if err := s.DeleteWebhook(ctx, 1, webhook.ID); !errors.Is(err, storage.ErrWebhookNotFound) {
+		t.Errorf("second DeleteWebhook error = %v, want %v", err, storage.ErrWebhookNotFound)
+	}
+}
+
+func testEvents(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+	now := time.Now().Truncate(time.Second)
+	// Приложение, в котором больше никто не пишет события: outbox общий для всех тестов.
+	app := 1000 + now.UnixNano()%1_000_000_000
+
+	event := func(typ string, appID int64) models.OutboxEvent {
+		return models.OutboxEvent{Type: typ, Subject: tgId, AppID: appID, Data: []byte(`{"sub":"` + tgId + `"}`), CreatedAt: now}
+	}
+	if err := s.SaveUser(ctx, tgId, models.User{}, event(models.EventUserRegistered, app)); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	if err := s.SetBanned(ctx, tgId, true, event(models.EventUserBanned, 0)); err != nil {
+		t.Fatalf("SetBanned: %v", err)
+	}
+
+	own, err := s.Events(ctx, app, 0, 10)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	if len(own) != 1 || own[0].Type != models.EventUserRegistered || !own[0].CreatedAt.Equal(now) ||
+		!strings.Contains(string(own[0].Data), tgId) {
+		t.Fatalf("Events(own app) = %+v", own)
+	}
+	if rest, err := s.Events(ctx, app, own[0].ID, 10); err != nil || len(rest) != 0 {
+		t.Errorf("Events after cursor = %+v, %v", rest, err)
+	}
+
+	mine := func() []models.OutboxEvent {
+		events, err := s.Events(ctx, 1, own[0].ID-1, 1000)
+		if err != nil {
+			t.Fatalf("Events: %v", err)
+		}
+		var out []models.OutboxEvent
+		for _, e := range events {
+			if strings.Contains(string(e.Data), tgId) {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+	if got := mine(); len(got) != 0 {
+		t.Errorf("app without membership sees %+v", got)
+	}
+	session := models.Session{ID: tgId, UserID: tgId, AppID: 1, CreatedAt: now, LastSeenAt: now}
+	if err := s.CreateSession(ctx, session); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	got := mine()
+	if len(got) != 2 || got[0].ID >= got[1].ID || got[1].Type != models.EventUserBanned {
+		t.Errorf("member app sees %+v, want registered and banned in order", got)
+	}
+
+	// Выход из приложения не лишает его событий пользователя.
+	if err := s.RevokeSession(ctx, tgId, session.ID, now); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if err := s.SetBanned(ctx, tgId, false, event(models.EventUserUpdated, 0)); err != nil {
+		t.Fatalf("SetBanned: %v", err)
+	}
+	if got := mine(); len(got) != 3 || got[2].Type != models.EventUserUpdated {
+		t.Errorf("app after logout sees %+v, want the event after logout too", got)
+	}
+}
+
+func eventSub(t *testing.T, e models.OutboxEvent) string {
+	t.Helper()
+	var data struct {
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		t.Fatalf("event %d data: %v", e.ID, err)
+	}
+	return data.Sub
+}
+
+func testErasure(t *testing.T, s Storage) {
+	ctx := context.Background()
+	tgId := uniqueTgId(t)
+	now := time.Now().Truncate(time.Second)
+	app := 1000 + now.UnixNano()%1_000_000_000
+
+	registered := models.OutboxEvent{Type: models.EventUserRegistered, Subject: tgId, AppID: app,
+		Data: []byte(`{"sub":"` + tgId + `","first_name":"Pavel"}`), CreatedAt: now}
+	if err := s.SaveUser(ctx, tgId, models.User{FirstName: "Pavel"}, registered); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	revoked := models.Session{ID: tgId + "-1", UserID: tgId, AppID: 1, CreatedAt: now.Add(-time.Hour), LastSeenAt: now.Add(-time.Hour)}
+	active := models.Session{ID: tgId + "-2", UserID: tgId, AppID: 1, CreatedAt: now, LastSeenAt: now}
+	for _, session := range []models.Session{revoked, active} {
+		if err := s.CreateSession(ctx, session); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+	}
+	revokedEvent := models.OutboxEvent{Type: models.EventSessionRevoked, Subject: tgId, AppID: 1,
+		Data: []byte(`{"sub":"` + tgId + `","session_id":"` + revoked.ID + `"}`), CreatedAt: now}
+	if err := s.RevokeSession(ctx, tgId, revoked.ID, now, revokedEvent); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	history, err := s.SessionHistory(ctx, tgId)
+	if err != nil || len(history) != 2 || history[0].ID != active.ID || history[1].Active() {
+		t.Errorf("SessionHistory = %+v, %v", history, err)
+	}
+	members, err := s.AppMemberships(ctx, tgId)
+	if err != nil || len(members) != 1 || members[0].AppID != 1 || !members[0].FirstSeenAt.Equal(revoked.CreatedAt) {
+		t.Errorf("AppMemberships = %+v, %v, want app 1 since %v", members, err, revoked.CreatedAt)
+	}
+	audit := models.AuditEvent{Type: models.AuditLogin, Actor: tgId, Subject: tgId, AppID: 1, IP: "10.0.0.1", Outcome: models.AuditSuccess, CreatedAt: now}
+	if err := s.SaveAuditEvent(ctx, audit); err != nil {
+		t.Fatalf("SaveAuditEvent: %v", err)
+	}
+	identity := models.Identity{Provider: models.ProviderGoogle, Subject: "google-" + tgId, CreatedAt: now}
+	if _, err := s.LinkIdentity(ctx, tgId, identity); err != nil {
+		t.Fatalf("LinkIdentity: %v", err)
+	}
+
+	if err := s.ScheduleErasure(ctx, "missing-"+tgId, &now); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("ScheduleErasure(missing) error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+	eraseAt := now.Add(time.Hour)
+	if err := s.ScheduleErasure(ctx, tgId, &eraseAt); err != nil {
+		t.Fatalf("ScheduleErasure: %v", err)
+	}
+	if user, err := s.User(ctx, tgId); err != nil || user.EraseAt == nil || !user.EraseAt.Equal(eraseAt) {
+		t.Errorf("User after ScheduleErasure = %+v, %v", user, err)
+	}
+	due := func(at time.Time) bool {
+		subs, err := s.ClaimErasures(ctx, at, 1000)
+		if err != nil {
+			t.Fatalf("ClaimErasures: %v", err)
+		}
+		return slices.Contains(subs, tgId)
+	}
+	if due(now) || !due(eraseAt) {
+		t.Errorf("ClaimErasures does not respect erase_at %v", eraseAt)
+	}
+	if err := s.EraseUser(ctx, tgId, models.DeletedPrefix+tgId, now); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("EraseUser before erase_at error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+
+	if err := s.ScheduleErasure(ctx, tgId, nil); err != nil {
+		t.Fatalf("ScheduleErasure(nil): %v", err)
+	}
+	if due(eraseAt) {
+		t.Errorf("ClaimErasures returns user with canceled erasure")
+	}
+	if err := s.EraseUser(ctx, tgId, models.DeletedPrefix+tgId, eraseAt); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("EraseUser after cancel error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+
+	if err := s.ScheduleErasure(ctx, tgId, &now); err != nil {
+		t.Fatalf("ScheduleErasure: %v", err)
+	}
+	pseudonym := models.DeletedPrefix + uniqueTgId(t)
+	deleted := models.OutboxEvent{Type: models.EventUserDeleted, Subject: pseudonym, Data: []byte(`{"sub":"` + tgId + `"}`), CreatedAt: now}
+	if err := s.EraseUser(ctx, tgId, pseudonym, now, deleted); err != nil {
+		t.Fatalf("EraseUser: %v", err)
+	}
+
+	if _, err := s.User(ctx, tgId); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("User after erase error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+	if _, err := s.Session(ctx, active.ID); !errors.Is(err, storage.ErrSessionNotFound) {
+		t.Errorf("Session after erase error = %v, want %v", err, storage.ErrSessionNotFound)
+	}
+	if _, err := s.UserByIdentity(ctx, identity.Provider, identity.Subject); !errors.Is(err, storage.ErrIdentityNotFound) {
+		t.Errorf("UserByIdentity after erase error = %v, want %v", err, storage.ErrIdentityNotFound)
+	}
+	if events, err := s.AuditEvents(ctx, models.AuditFilter{User: tgId, Limit: 10}); err != nil || len(events) != 0 {
+		t.Errorf("AuditEvents by sub after erase = %+v, %v", events, err)
+	}
+	events, err := s.AuditEvents(ctx, models.AuditFilter{User: pseudonym, Limit: 10})
+	if err != nil || len(events) != 1 || events[0].Actor != pseudonym || events[0].IP != "" || events[0].Type != audit.Type {
+		t.Errorf("AuditEvents by pseudonym = %+v, %v", events, err)
+	}
+
+	// Событие, произошедшее в приложении, остается, но без профиля и без sub.
+	outbox, err := s.Events(ctx, app, 0, 10)
+	if err != nil || len(outbox) != 1 || outbox[0].Type != models.EventUserRegistered {
+		t.Fatalf("Events after erase = %+v, %v", outbox, err)
+	}
+	if sub := eventSub(t, outbox[0]); sub != pseudonym || strings.Contains(string(outbox[0].Data), "Pavel") {
+		t.Errorf("profile event not scrubbed: %s", outbox[0].Data)
+	}
+	// После удаления app_members и outbox указывают на псевдоним, а не на хэш tgid.
+	if members, err := s.AppMemberships(ctx, tgId); err != nil || len(members) != 0 {
+		t.Errorf("AppMemberships by sub after erase = %+v, %v", members, err)
+	}
+	if members, err := s.AppMemberships(ctx, pseudonym); err != nil || len(members) != 1 || members[0].AppID != 1 {
+		t.Errorf("AppMemberships by pseudonym = %+v, %v", members, err)
+	}
+	// Приложение, куда пользователь входил, получает user.deleted с настоящим sub -
+	// это единственное место, где он остается.
+	member, err := s.Events(ctx, 1, outbox[0].ID, 1000)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	var sawRevoked, sawDeleted bool
+	for _, e := range member {
+		switch {
+		case e.Type == models.EventUserDeleted && eventSub(t, e) == tgId:
+			sawDeleted = true
+		case strings.Contains(string(e.Data), `"`+tgId+`"`):
+			t.Errorf("event %d still holds sub after erase: %s", e.ID, e.Data)
+		case e.Type == models.EventSessionRevoked && strings.Contains(string(e.Data), revoked.ID):
+			sawRevoked = eventSub(t, e) == pseudonym
+		}
+	}
+	if !sawRevoked || !sawDeleted {
+		t.Errorf("member app events after erase: revoked with pseudonym %v, %s %v: %+v", sawRevoked, models.EventUserDeleted, sawDeleted, member)
+	}
+	if err := s.EraseUser(ctx, tgId, pseudonym, now); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("second EraseUser error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+}
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS email_tokens;
+
+ALTER TABLE identities DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE identities DROP COLUMN IF EXISTS failed_logins;
+ALTER TABLE identities DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE identities DROP COLUMN IF EXISTS password_hash;
@@ -0,0 +1,17 @@
+ALTER TABLE identities ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE identities ADD COLUMN IF NOT EXISTS verified_at TIMESTAMPTZ;
+ALTER TABLE identities ADD COLUMN IF NOT EXISTS failed_logins INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE identities ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+-- Одноразовые токены из писем: подтверждение email и сброс пароля. Хранится
+-- только хэш токена.
+CREATE TABLE IF NOT EXISTS email_tokens
+(
+    token_hash  TEXT PRIMARY KEY,
+    identity_id BIGINT      NOT NULL REFERENCES identities (id) ON DELETE CASCADE,
+    purpose     TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS email_tokens_expires_idx ON email_tokens (expires_at);
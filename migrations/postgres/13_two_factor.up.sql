@@ -0,0 +1,24 @@
+-- Второй фактор TOTP. Секрет зашифрован ключом сервиса, last_step защищает
+-- от повторного использования кода.
+CREATE TABLE IF NOT EXISTS totp_factors
+(
+    user_tgid        VARCHAR(255) PRIMARY KEY REFERENCES users (tgid) ON DELETE CASCADE,
+    secret           TEXT         NOT NULL,
+    created_at       TIMESTAMPTZ  NOT NULL,
+    confirmed_at     TIMESTAMPTZ,
+    last_step        BIGINT       NOT NULL DEFAULT 0,
+    last_verified_at TIMESTAMPTZ,
+    failed_attempts  INTEGER      NOT NULL DEFAULT 0,
+    locked_until     TIMESTAMPTZ
+);
+
+-- Одноразовые коды восстановления. Хранится только хэш кода.
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    user_tgid VARCHAR(255) NOT NULL REFERENCES users (tgid) ON DELETE CASCADE,
+    code_hash TEXT         NOT NULL,
+    used_at   TIMESTAMPTZ,
+    PRIMARY KEY (user_tgid, code_hash)
+);
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS mfa_at TIMESTAMPTZ;
@@ -0,0 +1,16 @@
+-- Приложения, в которые пользователь входил. Строки не удаляются ни при выходе
+-- и отзыве сессий, ни при удалении аккаунта: по ним приложение получает события
+-- пользователя, включая user.deleted. Хранится только хэш tgid, как в outbox.
+CREATE TABLE IF NOT EXISTS app_members
+(
+    user_tgid VARCHAR(255) NOT NULL,
+    app_id    INTEGER      NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    joined_at TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (user_tgid, app_id)
+);
+
+CREATE INDEX IF NOT EXISTS app_members_app_idx ON app_members (app_id);
+
+INSERT INTO app_members (user_tgid, app_id, joined_at)
+SELECT user_tgid, app_id, MIN(created_at) FROM sessions GROUP BY user_tgid, app_id
+ON CONFLICT DO NOTHING;
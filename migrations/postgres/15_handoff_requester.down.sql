@@ -0,0 +1,3 @@
+ALTER TABLE login_handoffs DROP COLUMN IF EXISTS platform;
+ALTER TABLE login_handoffs DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE login_handoffs DROP COLUMN IF EXISTS ip;
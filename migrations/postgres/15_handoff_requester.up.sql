@@ -0,0 +1,4 @@
+-- Браузер, запросивший код входа: его показывают подтверждающему пользователю.
+ALTER TABLE login_handoffs ADD COLUMN IF NOT EXISTS ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE login_handoffs ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE login_handoffs ADD COLUMN IF NOT EXISTS platform TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Время последней попытки удаления: аккаунт, удаление которого падает, уходит
+-- в конец очереди и не задерживает остальные.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erase_attempt_at TIMESTAMPTZ;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS rate_limits
+(
+    key        TEXT PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ      NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS allowed_origins TEXT[] NOT NULL DEFAULT '{}';
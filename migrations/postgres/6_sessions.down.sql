@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sessions;
+
+ALTER TABLE users ALTER COLUMN last_login TYPE DATE;
@@ -0,0 +1,16 @@
+ALTER TABLE users ALTER COLUMN last_login TYPE TIMESTAMPTZ USING last_login::timestamptz;
+
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id           TEXT PRIMARY KEY,
+    user_tgid    VARCHAR(255) NOT NULL REFERENCES users (tgid) ON DELETE CASCADE,
+    app_id       INTEGER      NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    platform     TEXT         NOT NULL DEFAULT '',
+    user_agent   TEXT         NOT NULL DEFAULT '',
+    ip           TEXT         NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ  NOT NULL,
+    last_seen_at TIMESTAMPTZ  NOT NULL,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_idx ON sessions (user_tgid, created_at DESC);
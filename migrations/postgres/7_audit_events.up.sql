@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    id         BIGSERIAL PRIMARY KEY,
+    type       TEXT        NOT NULL,
+    actor      TEXT        NOT NULL DEFAULT '',
+    subject    TEXT        NOT NULL DEFAULT '',
+    app_id     INTEGER     NOT NULL DEFAULT 0,
+    ip         TEXT        NOT NULL DEFAULT '',
+    outcome    TEXT        NOT NULL,
+    reason     TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_subject_idx ON audit_events (subject, id);
+CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (actor, id);
+CREATE INDEX IF NOT EXISTS audit_events_created_idx ON audit_events (created_at);
+
+-- Журнал только пополняется: изменить или удалить запись нельзя даже владельцу таблицы.
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_modify
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS outbox_events
+(
+    id            BIGSERIAL PRIMARY KEY,
+    type          TEXT        NOT NULL,
+    subject       TEXT        NOT NULL DEFAULT '',
+    app_id        INTEGER     NOT NULL DEFAULT 0,
+    data          JSONB       NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    -- dispatched_at - когда событие разложено по доставкам вебхуков.
+    dispatched_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx ON outbox_events (id) WHERE dispatched_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id         BIGSERIAL PRIMARY KEY,
+    app_id     INTEGER     NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    url        TEXT        NOT NULL,
+    events     TEXT[]      NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_app_idx ON webhooks (app_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              BIGSERIAL PRIMARY KEY,
+    webhook_id      BIGINT      NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id        BIGINT      NOT NULL REFERENCES outbox_events (id) ON DELETE CASCADE,
+    status          TEXT        NOT NULL,
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    response_status INTEGER     NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL,
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_status_idx ON webhook_deliveries (webhook_id, status, id);
@@ -0,0 +1,9 @@
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS users_erase_at_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS erase_at;
@@ -0,0 +1,20 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erase_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS users_erase_at_idx ON users (erase_at) WHERE erase_at IS NOT NULL;
+
+-- Единственное допустимое изменение журнала - обезличивание при удалении аккаунта:
+-- actor и subject заменяются псевдонимом deleted:*, ip стирается, остальное неизменно.
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND NEW.id = OLD.id AND NEW.type = OLD.type AND NEW.app_id = OLD.app_id
+        AND NEW.outcome = OLD.outcome AND NEW.reason = OLD.reason AND NEW.created_at = OLD.created_at
+        AND (NEW.actor = OLD.actor OR NEW.actor LIKE 'deleted:%')
+        AND (NEW.subject = OLD.subject OR NEW.subject LIKE 'deleted:%')
+        AND (NEW.ip = OLD.ip OR NEW.ip = '') THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
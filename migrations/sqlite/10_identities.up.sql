@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS identities
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER  NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider   TEXT     NOT NULL,
+    subject    TEXT     NOT NULL,
+    email      TEXT     NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS email_tokens;
+
+ALTER TABLE identities DROP COLUMN locked_until;
+ALTER TABLE identities DROP COLUMN failed_logins;
+ALTER TABLE identities DROP COLUMN verified_at;
+ALTER TABLE identities DROP COLUMN password_hash;
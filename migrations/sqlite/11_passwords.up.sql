@@ -0,0 +1,17 @@
+ALTER TABLE identities ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE identities ADD COLUMN verified_at DATETIME;
+ALTER TABLE identities ADD COLUMN failed_logins INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE identities ADD COLUMN locked_until DATETIME;
+
+-- Одноразовые токены из писем: подтверждение email и сброс пароля. Хранится
+-- только хэш токена.
+CREATE TABLE IF NOT EXISTS email_tokens
+(
+    token_hash  TEXT PRIMARY KEY,
+    identity_id INTEGER  NOT NULL REFERENCES identities (id) ON DELETE CASCADE,
+    purpose     TEXT     NOT NULL,
+    created_at  DATETIME NOT NULL,
+    expires_at  DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS email_tokens_expires_idx ON email_tokens (expires_at);
@@ -0,0 +1,24 @@
+-- Второй фактор TOTP. Секрет зашифрован ключом сервиса, last_step защищает
+-- от повторного использования кода.
+CREATE TABLE IF NOT EXISTS totp_factors
+(
+    user_tgid        TEXT PRIMARY KEY REFERENCES users (tgid) ON DELETE CASCADE,
+    secret           TEXT     NOT NULL,
+    created_at       DATETIME NOT NULL,
+    confirmed_at     DATETIME,
+    last_step        INTEGER  NOT NULL DEFAULT 0,
+    last_verified_at DATETIME,
+    failed_attempts  INTEGER  NOT NULL DEFAULT 0,
+    locked_until     DATETIME
+);
+
+-- Одноразовые коды восстановления. Хранится только хэш кода.
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    user_tgid TEXT NOT NULL REFERENCES users (tgid) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at   DATETIME,
+    PRIMARY KEY (user_tgid, code_hash)
+);
+
+ALTER TABLE sessions ADD COLUMN mfa_at DATETIME;
@@ -0,0 +1,15 @@
+-- Приложения, в которые пользователь входил. Строки не удаляются ни при выходе
+-- и отзыве сессий, ни при удалении аккаунта: по ним приложение получает события
+-- пользователя, включая user.deleted. Хранится только хэш tgid, как в outbox.
+CREATE TABLE IF NOT EXISTS app_members
+(
+    user_tgid TEXT     NOT NULL,
+    app_id    INTEGER  NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    joined_at DATETIME NOT NULL,
+    PRIMARY KEY (user_tgid, app_id)
+);
+
+CREATE INDEX IF NOT EXISTS app_members_app_idx ON app_members (app_id);
+
+INSERT OR IGNORE INTO app_members (user_tgid, app_id, joined_at)
+SELECT user_tgid, app_id, MIN(created_at) FROM sessions GROUP BY user_tgid, app_id;
@@ -0,0 +1,3 @@
+ALTER TABLE login_handoffs DROP COLUMN platform;
+ALTER TABLE login_handoffs DROP COLUMN user_agent;
+ALTER TABLE login_handoffs DROP COLUMN ip;
@@ -0,0 +1,4 @@
+-- Браузер, запросивший код входа: его показывают подтверждающему пользователю.
+ALTER TABLE login_handoffs ADD COLUMN ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE login_handoffs ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE login_handoffs ADD COLUMN platform TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN erase_attempt_at;
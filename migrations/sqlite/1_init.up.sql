@@ -0,0 +1,18 @@
+CREATE TABLE users (
+    tgid TEXT UNIQUE,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    first_name TEXT,
+    last_name TEXT,
+    user_name TEXT,
+    user_name_locale TEXT,
+    last_login DATETIME,
+    photo_url TEXT,
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    is_banned BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE TABLE IF NOT EXISTS apps
+(
+    id     INTEGER PRIMARY KEY,
+    name   TEXT NOT NULL UNIQUE,
+    secret TEXT NOT NULL UNIQUE
+);
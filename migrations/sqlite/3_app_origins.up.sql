@@ -0,0 +1 @@
+ALTER TABLE apps ADD COLUMN allowed_origins TEXT NOT NULL DEFAULT '[]';
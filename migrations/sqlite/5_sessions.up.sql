@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id           TEXT PRIMARY KEY,
+    user_tgid    TEXT     NOT NULL REFERENCES users (tgid) ON DELETE CASCADE,
+    app_id       INTEGER  NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    platform     TEXT     NOT NULL DEFAULT '',
+    user_agent   TEXT     NOT NULL DEFAULT '',
+    ip           TEXT     NOT NULL DEFAULT '',
+    created_at   DATETIME NOT NULL,
+    last_seen_at DATETIME NOT NULL,
+    revoked_at   DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_idx ON sessions (user_tgid, created_at DESC);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    type       TEXT     NOT NULL,
+    actor      TEXT     NOT NULL DEFAULT '',
+    subject    TEXT     NOT NULL DEFAULT '',
+    app_id     INTEGER  NOT NULL DEFAULT 0,
+    ip         TEXT     NOT NULL DEFAULT '',
+    outcome    TEXT     NOT NULL,
+    reason     TEXT     NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_subject_idx ON audit_events (subject, id);
+CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (actor, id);
+CREATE INDEX IF NOT EXISTS audit_events_created_idx ON audit_events (created_at);
+
+-- Журнал только пополняется.
+CREATE TRIGGER IF NOT EXISTS audit_events_no_update
+    BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_events_no_delete
+    BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS outbox_events
+(
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    type          TEXT     NOT NULL,
+    subject       TEXT     NOT NULL DEFAULT '',
+    app_id        INTEGER  NOT NULL DEFAULT 0,
+    data          TEXT     NOT NULL,
+    created_at    DATETIME NOT NULL,
+    dispatched_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx ON outbox_events (id) WHERE dispatched_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    app_id     INTEGER  NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    url        TEXT     NOT NULL,
+    events     TEXT     NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_app_idx ON webhooks (app_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id      INTEGER  NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id        INTEGER  NOT NULL REFERENCES outbox_events (id) ON DELETE CASCADE,
+    status          TEXT     NOT NULL,
+    attempts        INTEGER  NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_error      TEXT     NOT NULL DEFAULT '',
+    response_status INTEGER  NOT NULL DEFAULT 0,
+    created_at      DATETIME NOT NULL,
+    updated_at      DATETIME NOT NULL,
+    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_status_idx ON webhook_deliveries (webhook_id, status, id);
@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS audit_events_no_update;
+
+CREATE TRIGGER audit_events_no_update
+    BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+DROP INDEX IF EXISTS users_erase_at_idx;
+ALTER TABLE users DROP COLUMN erase_at;
@@ -0,0 +1,18 @@
+ALTER TABLE users ADD COLUMN erase_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS users_erase_at_idx ON users (erase_at) WHERE erase_at IS NOT NULL;
+
+-- Единственное допустимое изменение журнала - обезличивание при удалении аккаунта:
+-- actor и subject заменяются псевдонимом deleted:*, ip стирается, остальное неизменно.
+DROP TRIGGER IF EXISTS audit_events_no_update;
+
+CREATE TRIGGER audit_events_no_update
+    BEFORE UPDATE ON audit_events
+    WHEN NEW.id IS NOT OLD.id OR NEW.type IS NOT OLD.type OR NEW.app_id IS NOT OLD.app_id
+        OR NEW.outcome IS NOT OLD.outcome OR NEW.reason IS NOT OLD.reason OR NEW.created_at IS NOT OLD.created_at
+        OR (NEW.actor IS NOT OLD.actor AND NEW.actor NOT LIKE 'deleted:%')
+        OR (NEW.subject IS NOT OLD.subject AND NEW.subject NOT LIKE 'deleted:%')
+        OR (NEW.ip IS NOT OLD.ip AND NEW.ip != '')
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
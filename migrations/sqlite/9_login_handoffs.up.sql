@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS login_handoffs
+(
+    code        TEXT PRIMARY KEY,
+    device_hash TEXT     NOT NULL UNIQUE,
+    app_id      INTEGER  NOT NULL REFERENCES apps (id) ON DELETE CASCADE,
+    user_tgid   TEXT     REFERENCES users (tgid) ON DELETE CASCADE,
+    created_at  DATETIME NOT NULL,
+    expires_at  DATETIME NOT NULL,
+    approved_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS login_handoffs_expires_idx ON login_handoffs (expires_at);
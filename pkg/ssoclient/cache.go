@@ -0,0 +1,77 @@
+package ssoclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Cache запоминает результаты другого Verifier на ttl, но не дольше срока
+// действия токена. Отказы ErrInvalidToken тоже кэшируются, ошибки сети - нет.
+type Cache struct {
+	next Verifier
+	ttl  time.Duration
+	max  int
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]cacheEntry
+}
+
+type cacheEntry struct {
+	principal Principal
+	err       error
+	until     time.Time
+}
+
+// NewCache держит не больше max записей; ключом служит хэш токена, а не сам токен.
+func NewCache(next Verifier, ttl time.Duration, max int) *Cache {
+	return &Cache{next: next, ttl: ttl, max: max, entries: make(map[[sha256.Size]byte]cacheEntry)}
+}
+
+func (c *Cache) Verify(ctx context.Context, token string) (Principal, error) {
+	key := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.until) {
+		return entry.principal, entry.err
+	}
+
+	p, err := c.next.Verify(ctx, token)
+	if err != nil && !errors.Is(err, ErrInvalidToken) {
+		return Principal{}, err
+	}
+
+	until := now.Add(c.ttl)
+	if err == nil && !p.ExpiresAt.IsZero() && p.ExpiresAt.Before(until) {
+		until = p.ExpiresAt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= c.max {
+		c.evict(now)
+	}
+	c.entries[key] = cacheEntry{principal: p, err: err, until: until}
+
+	return p, err
+}
+
+// evict удаляет просроченные записи, а если их нет - произвольную.
+func (c *Cache) evict(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.until) {
+			delete(c.entries, key)
+		}
+	}
+	for key := range c.entries {
+		if len(c.entries) < c.max {
+			return
+		}
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,161 @@
+package ssoclient
+
+import (
+	"auth-service/internal/domains/models"
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// User и Me - те же структуры, что отдает SSO.
+type (
+	User = models.UserResponse
+	Me   = models.Me
+)
+
+type LoginResult struct {
+	Token string `json:"token"`
+	User  User   `json:"user"`
+}
+
+// transport - HTTP или gRPC. Каждый метод - одна попытка без повторов.
+type transport interface {
+	login(ctx context.Context, initData string, serviceID int64) (LoginResult, error)
+	register(ctx context.Context, initData, userNameLocale string, serviceID int64) (string, error)
+	adminCheck(ctx context.Context, initData, otp string) (bool, error)
+	me(ctx context.Context, token string) (Me, error)
+}
+
+// Client - типизированный клиент API SSO. Временные ошибки (429, 502-504,
+// Unavailable, сетевые) повторяются с экспоненциальной задержкой.
+type Client struct {
+	transport transport
+	retries   int
+	backoff   time.Duration
+	timeout   time.Duration
+}
+
+type Option func(*Client)
+
+// WithRetries задает число повторов после первой попытки. По умолчанию 2.
+func WithRetries(n int) Option {
+	return func(c *Client) { c.retries = n }
+}
+
+// WithBackoff задает задержку перед первым повтором, дальше она удваивается. По умолчанию 100ms.
+func WithBackoff(d time.Duration) Option {
+	return func(c *Client) { c.backoff = d }
+}
+
+// WithTimeout ограничивает каждую попытку. По умолчанию 5s.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+func newClient(t transport, opts []Option) *Client {
+	c := &Client{transport: t, retries: 2, backoff: 100 * time.Millisecond, timeout: 5 * time.Second}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Login обменивает initData на токен приложения serviceID.
+func (c *Client) Login(ctx context.Context, initData string, serviceID int64) (res LoginResult, err error) {
+	err = c.do(ctx, func(ctx context.Context) error {
+		res, err = c.transport.login(ctx, initData, serviceID)
+		return err
+	})
+	return res, err
+}
+
+// Register создает пользователя и возвращает токен. Повтор после таймаута может
+// вернуть ErrUserExists, если первая попытка все же дошла до SSO.
+func (c *Client) Register(ctx context.Context, initData, userNameLocale string, serviceID int64) (token string, err error) {
+	err = c.do(ctx, func(ctx context.Context) error {
+		token, err = c.transport.register(ctx, initData, userNameLocale, serviceID)
+		return err
+	})
+	return token, err
+}
+
+// IsAdmin сообщает, администратор ли пользователь initData. Администратору со
+// вторым фактором IsAdmin отвечает ErrStepUpRequired - нужен IsAdminWithOTP.
+func (c *Client) IsAdmin(ctx context.Context, initData string) (isAdmin bool, err error) {
+	return c.IsAdminWithOTP(ctx, initData, "")
+}
+
+// IsAdminWithOTP - IsAdmin с кодом TOTP или кодом восстановления. Код
+// действует один раз, поэтому после ErrInvalidOTP у пользователя нужно
+// спросить новый.
+func (c *Client) IsAdminWithOTP(ctx context.Context, initData, otp string) (isAdmin bool, err error) {
+	err = c.do(ctx, func(ctx context.Context) error {
+		isAdmin, err = c.transport.adminCheck(ctx, initData, otp)
+		return err
+	})
+	return isAdmin, err
+}
+
+// Me возвращает профиль и роли владельца token.
+func (c *Client) Me(ctx context.Context, token string) (me Me, err error) {
+	err = c.do(ctx, func(ctx context.Context) error {
+		me, err = c.transport.me(ctx, token)
+		return err
+	})
+	return me, err
+}
+
+func (c *Client) do(ctx context.Context, call func(ctx context.Context) error) error {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := call(attemptCtx)
+		cancel()
+
+		if err == nil || attempt >= c.retries || ctx.Err() != nil {
+			return err
+		}
+		wait, ok := retryDelay(err, backoff)
+		if !ok {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// retryDelay решает, повторять ли запрос, и сколько ждать: Retry-After сервера
+// важнее собственной задержки.
+func retryDelay(err error, backoff time.Duration) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if !apiErr.retryable() {
+			return 0, false
+		}
+		return max(backoff, apiErr.RetryAfter), true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return backoff, true
+	}
+	return 0, false
+}
+
+// adminCheckRequest - тело admin-check. otp передается, только если задан:
+// так запрос без кода совпадает с прежним.
+func adminCheckRequest(initData, otp string) map[string]any {
+	req := map[string]any{"initData": initData}
+	if otp != "" {
+		req["otp"] = otp
+	}
+	return req
+}
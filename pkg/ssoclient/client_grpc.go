@@ -0,0 +1,112 @@
+package ssoclient
+
+import (
+	ssov1 "auth-service/pkg/api/sso/v1"
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type grpcTransport struct {
+	client ssov1.AuthClient
+}
+
+// NewGRPC - клиент поверх gRPC-сервиса sso.v1.Auth. Соединение создает и
+// закрывает вызывающий код.
+func NewGRPC(cc grpc.ClientConnInterface, opts ...Option) *Client {
+	return newClient(&grpcTransport{client: ssov1.NewAuthClient(cc)}, opts)
+}
+
+func (t *grpcTransport) login(ctx context.Context, initData string, serviceID int64) (LoginResult, error) {
+	var header metadata.MD
+	res, err := t.client.Login(ctx, &ssov1.LoginRequest{InitData: initData, ServiceId: serviceID}, grpc.Header(&header))
+	if err != nil {
+		return LoginResult{}, fromStatus(err, header)
+	}
+	return LoginResult{Token: res.GetToken(), User: userFromProto(res.GetUser())}, nil
+}
+
+func (t *grpcTransport) register(ctx context.Context, initData, userNameLocale string, serviceID int64) (string, error) {
+	var header metadata.MD
+	res, err := t.client.Register(ctx, &ssov1.RegisterRequest{
+		InitData:       initData,
+		UserNameLocale: userNameLocale,
+		ServiceId:      serviceID,
+	}, grpc.Header(&header))
+	if err != nil {
+		return "", fromStatus(err, header)
+	}
+	return res.GetToken(), nil
+}
+
+func (t *grpcTransport) adminCheck(ctx context.Context, initData, otp string) (bool, error) {
+	var header metadata.MD
+	res, err := t.client.AdminCheck(ctx, &ssov1.AdminCheckRequest{InitData: initData, Otp: otp}, grpc.Header(&header))
+	if err != nil {
+		return false, fromStatus(err, header)
+	}
+	return res.GetIsAdmin(), nil
+}
+
+func (t *grpcTransport) me(ctx context.Context, token string) (Me, error) {
+	var header metadata.MD
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	res, err := t.client.Me(ctx, &ssov1.MeRequest{}, grpc.Header(&header))
+	if err != nil {
+		return Me{}, fromStatus(err, header)
+	}
+	me := Me{
+		User:           userFromProto(res.GetUser()),
+		Roles:          res.GetRoles(),
+		ServiceID:      res.GetServiceId(),
+		StepUpRequired: res.GetStepUpRequired(),
+	}
+	if res.EraseAt != nil {
+		eraseAt := res.GetEraseAt().AsTime()
+		me.EraseAt = &eraseAt
+	}
+	return me, nil
+}
+
+func userFromProto(u *ssov1.User) User {
+	return User{
+		ID:             u.GetId(),
+		TgId:           u.GetTgid(),
+		FirstName:      u.GetFirstName(),
+		LastName:       u.GetLastName(),
+		Username:       u.GetUserName(),
+		UserNameLocale: u.GetUserNameLocale(),
+		PhotoURL:       u.GetPhotoUrl(),
+		IsBanned:       u.GetIsBanned(),
+	}
+}
+
+// fromStatus превращает статус gRPC в APIError: код /v1 берется из
+// ErrorInfo.Reason, задержка - из заголовка retry-after.
+func fromStatus(err error, header metadata.MD) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	apiErr := &APIError{
+		Code:     codeFromGRPC(st.Code()),
+		Message:  st.Message(),
+		GRPCCode: st.Code(),
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Reason != "" {
+			apiErr.Code = info.Reason
+		}
+	}
+	if v := header.Get("retry-after"); len(v) > 0 {
+		if seconds, err := strconv.Atoi(v[0]); err == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+	return apiErr
+}
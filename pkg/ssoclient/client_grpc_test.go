@@ -0,0 +1,96 @@
+package ssoclient_test
+
+import (
+	"auth-service/pkg/ssoclient"
+	"auth-service/pkg/ssoclient/ssofake"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func newGRPCClient(t *testing.T) (*ssofake.Server, *ssoclient.Client) {
+	t.Helper()
+	fake, err := ssofake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(fake.Close)
+
+	cc, err := grpc.NewClient(fake.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return fake, ssoclient.NewGRPC(cc)
+}
+
+func TestGRPCRoundTrip(t *testing.T) {
+	fake, client := newGRPCClient(t)
+	ctx := context.Background()
+	initData := fake.InitData(42, "alice")
+
+	if _, err := client.Register(ctx, initData, "Alice", ssofake.ServiceID); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	res, err := client.Login(ctx, initData, ssofake.ServiceID)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if res.Token == "" || res.User.ID == "" || res.User.Username != "alice" {
+		t.Fatalf("Login = %+v", res)
+	}
+
+	me, err := client.Me(ctx, res.Token)
+	if err != nil {
+		t.Fatalf("Me: %v", err)
+	}
+	if me.User.ID != res.User.ID || me.ServiceID != ssofake.ServiceID || me.EraseAt != nil {
+		t.Fatalf("Me = %+v, want user %s of app %d", me, res.User.ID, ssofake.ServiceID)
+	}
+}
+
+func TestGRPCErrorReason(t *testing.T) {
+	_, client := newGRPCClient(t)
+
+	_, err := client.Me(context.Background(), "not-a-token")
+	var apiErr *ssoclient.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Me error = %v, want *APIError", err)
+	}
+	if apiErr.GRPCCode != codes.Unauthenticated || apiErr.Code != "invalid_token" {
+		t.Fatalf("Me error = %+v, want Unauthenticated invalid_token", apiErr)
+	}
+}
+
+func TestGRPCAdminWithoutFactor(t *testing.T) {
+	fake, client := newGRPCClient(t)
+	if err := fake.AddUser(7, "admin", true); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := client.IsAdmin(context.Background(), fake.InitData(7, "admin"))
+	var apiErr *ssoclient.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("IsAdmin error = %v, want *APIError", err)
+	}
+	if apiErr.GRPCCode != codes.PermissionDenied || apiErr.Code != "step_up_required" {
+		t.Fatalf("IsAdmin error = %+v, want PermissionDenied step_up_required", apiErr)
+	}
+
+	res, err := client.Login(context.Background(), fake.InitData(7, "admin"), ssofake.ServiceID)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	me, err := client.Me(context.Background(), res.Token)
+	if err != nil {
+		t.Fatalf("Me: %v", err)
+	}
+	if slices.Contains(me.Roles, ssoclient.RoleAdmin) || !me.StepUpRequired {
+		t.Fatalf("Me = %+v, want no admin role and step_up_required", me)
+	}
+}
@@ -0,0 +1,130 @@
+package ssoclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type httpTransport struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTP - клиент поверх /v1. httpClient == nil означает http.DefaultClient,
+// таймауты задаются через WithTimeout.
+func NewHTTP(baseURL string, httpClient *http.Client, opts ...Option) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return newClient(&httpTransport{baseURL: strings.TrimRight(baseURL, "/"), client: httpClient}, opts)
+}
+
+func (t *httpTransport) login(ctx context.Context, initData string, serviceID int64) (LoginResult, error) {
+	var res LoginResult
+	err := t.call(ctx, http.MethodPost, "/v1/auth/login", "", map[string]any{
+		"initData":  initData,
+		"serviceId": serviceID,
+	}, &res)
+	return res, err
+}
+
+func (t *httpTransport) register(ctx context.Context, initData, userNameLocale string, serviceID int64) (string, error) {
+	var res struct {
+		Token string `json:"token"`
+	}
+	err := t.call(ctx, http.MethodPost, "/v1/auth/register", "", map[string]any{
+		"initData":       initData,
+		"userNameLocale": userNameLocale,
+		"serviceId":      serviceID,
+	}, &res)
+	return res.Token, err
+}
+
+func (t *httpTransport) adminCheck(ctx context.Context, initData, otp string) (bool, error) {
+	var res struct {
+		IsAdmin bool `json:"isAdmin"`
+	}
+	err := t.call(ctx, http.MethodPost, "/v1/auth/admin-check", "", adminCheckRequest(initData, otp), &res)
+	return res.IsAdmin, err
+}
+
+func (t *httpTransport) me(ctx context.Context, token string) (Me, error) {
+	var res Me
+	err := t.call(ctx, http.MethodGet, "/v1/me", token, nil, &res)
+	return res, err
+}
+
+// call отправляет запрос и разбирает конверт {"data": ...} или {"error": ...}.
+func (t *httpTransport) call(ctx context.Context, method, path, token string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("ssoclient: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, t.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("ssoclient: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ssoclient: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		envelope := struct {
+			Data any `json:"data"`
+		}{Data: out}
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+			return fmt.Errorf("ssoclient: decode response: %w", err)
+		}
+		return nil
+	}
+
+	apiErr := &APIError{
+		HTTPStatus: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var envelope struct {
+		Error struct {
+			Code      string `json:"code"`
+			Message   string `json:"message"`
+			RequestID string `json:"request_id"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &envelope) == nil && envelope.Error.Code != "" {
+		apiErr.Code = envelope.Error.Code
+		apiErr.Message = envelope.Error.Message
+		if envelope.Error.RequestID != "" {
+			apiErr.RequestID = envelope.Error.RequestID
+		}
+		return apiErr
+	}
+
+	apiErr.Code = codeFromHTTPStatus(resp.StatusCode)
+	apiErr.Message = strings.TrimSpace(string(raw))
+	return apiErr
+}
@@ -0,0 +1,126 @@
+package ssoclient
+
+import (
+	"auth-service/internal/storage"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Ошибки хранилища SSO переиспользуются как есть, поэтому errors.Is дает один
+// и тот же ответ и в сервисе, и у клиента.
+var (
+	ErrUserNotFound    = storage.ErrUserNotFound
+	ErrUserBanned      = storage.ErrUserBanned
+	ErrUserExists      = storage.ErrUserExist
+	ErrUnknownService  = storage.ErrAppNotFound
+	ErrInvalidInitData = errors.New("ssoclient: invalid initData")
+	ErrBadRequest      = errors.New("ssoclient: bad request")
+	ErrRateLimited     = errors.New("ssoclient: rate limited")
+	// ErrStepUpRequired - администратору нужен код второго фактора, см. IsAdminWithOTP.
+	ErrStepUpRequired = errors.New("ssoclient: second factor required")
+	ErrInvalidOTP     = errors.New("ssoclient: invalid one-time code")
+)
+
+// APIError - ответ SSO с ошибкой. Code совпадает с кодом из конверта /v1
+// (или ErrorInfo.Reason в gRPC), Unwrap отдает соответствующую ошибку выше.
+type APIError struct {
+	Code      string
+	Message   string
+	RequestID string
+	// HTTPStatus заполняется для HTTP-транспорта, GRPCCode - для gRPC.
+	HTTPStatus int
+	GRPCCode   codes.Code
+	// RetryAfter - из заголовка Retry-After, если сервер его прислал.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("sso: %s: %s (request_id %s)", e.Code, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("sso: %s: %s", e.Code, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	switch e.Code {
+	case "user_not_found":
+		return ErrUserNotFound
+	case "user_banned":
+		return ErrUserBanned
+	case "user_exists":
+		return ErrUserExists
+	case "unknown_service":
+		return ErrUnknownService
+	case "invalid_init_data":
+		return ErrInvalidInitData
+	case "invalid_token":
+		return ErrInvalidToken
+	case "bad_request":
+		return ErrBadRequest
+	case "rate_limited":
+		return ErrRateLimited
+	case "step_up_required":
+		return ErrStepUpRequired
+	case "invalid_otp":
+		return ErrInvalidOTP
+	default:
+		return nil
+	}
+}
+
+// retryable - ошибки, после которых запрос имеет смысл повторить.
+func (e *APIError) retryable() bool {
+	switch e.HTTPStatus {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return e.GRPCCode == codes.Unavailable || e.GRPCCode == codes.ResourceExhausted
+}
+
+// codeFromHTTPStatus нужен для ответов без JSON-конверта: 429 от лимитера,
+// ошибки балансировщика и legacy-маршрутов.
+func codeFromHTTPStatus(status int) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return "rate_limited"
+	case status == http.StatusBadRequest:
+		return "bad_request"
+	case status == http.StatusUnauthorized:
+		return "unauthorized"
+	case status == http.StatusForbidden:
+		return "forbidden"
+	case status == http.StatusNotFound:
+		return "not_found"
+	case status == http.StatusConflict:
+		return "conflict"
+	case status >= 500:
+		return "unavailable"
+	default:
+		return "unknown"
+	}
+}
+
+func codeFromGRPC(code codes.Code) string {
+	switch code {
+	case codes.ResourceExhausted:
+		return "rate_limited"
+	case codes.InvalidArgument:
+		return "bad_request"
+	case codes.Unauthenticated:
+		return "unauthorized"
+	case codes.PermissionDenied:
+		return "forbidden"
+	case codes.NotFound:
+		return "not_found"
+	case codes.AlreadyExists:
+		return "conflict"
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return "unavailable"
+	default:
+		return "internal"
+	}
+}
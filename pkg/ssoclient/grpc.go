@@ -0,0 +1,66 @@
+package ssoclient
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor проверяет токен из метаданных authorization: Bearer
+// и кладет Principal в контекст обработчика.
+func UnaryServerInterceptor(v Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor - то же для стримов, проверка один раз при открытии.
+func StreamServerInterceptor(v Verifier) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), v)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &principalStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, v Verifier) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	for _, header := range md.Get("authorization") {
+		if t, ok := bearer(header); ok {
+			token = t
+			break
+		}
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, ErrNoToken.Error())
+	}
+
+	p, err := v.Verify(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return nil, status.Error(codes.Unavailable, "token verification unavailable")
+	}
+	return WithPrincipal(ctx, p), nil
+}
+
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,59 @@
+package ssoclient
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Middleware пропускает запрос дальше только с действительным токеном из
+// Authorization: Bearer и кладет владельца токена в контекст. Ответ на отказ -
+// тот же JSON-конверт, что у API самого SSO.
+func Middleware(v Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearer(r.Header.Get("Authorization"))
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "unauthorized", ErrNoToken.Error())
+				return
+			}
+
+			p, err := v.Verify(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, ErrInvalidToken) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					writeError(w, http.StatusUnauthorized, "unauthorized", "invalid token")
+					return
+				}
+				writeError(w, http.StatusServiceUnavailable, "unavailable", "token verification unavailable")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// RequireRole отвечает 403, если у Principal из контекста нет role.
+// Ставится после Middleware.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p, ok := FromContext(r.Context()); !ok || !p.HasRole(role) {
+				writeError(w, http.StatusForbidden, "forbidden", "role "+role+" required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{
+		"code":    code,
+		"message": message,
+	}})
+}
@@ -0,0 +1,97 @@
+package ssoclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IntrospectionVerifier отдает проверку самому SSO через GET /v1/me. Секрет
+// приложения сервису не нужен, а роли, бан и завершение сессии берутся из актуальных данных.
+// Каждый вызов - сетевой запрос, поэтому его стоит оборачивать в Cache.
+// /v1/me принимает только пользовательские токены, сервисные так не проверить.
+type IntrospectionVerifier struct {
+	baseURL   string
+	client    *http.Client
+	serviceID int64
+}
+
+// NewIntrospectionVerifier принимает только токены для serviceID (0 - любого приложения).
+func NewIntrospectionVerifier(baseURL string, serviceID int64, client *http.Client) *IntrospectionVerifier {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &IntrospectionVerifier{baseURL: strings.TrimRight(baseURL, "/"), client: client, serviceID: serviceID}
+}
+
+type meResponse struct {
+	Data struct {
+		User struct {
+			ID       string `json:"id"`
+			TgId     string `json:"tgid"`
+			IsBanned bool   `json:"is_banned"`
+		} `json:"user"`
+		Roles     []string `json:"roles"`
+		ServiceID int64    `json:"serviceId"`
+	} `json:"data"`
+}
+
+func (v *IntrospectionVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.baseURL+"/v1/me", nil)
+	if err != nil {
+		return Principal{}, fmt.Errorf("introspect: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return Principal{}, fmt.Errorf("introspect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusNotFound:
+		return Principal{}, fmt.Errorf("%w: introspection status %d", ErrInvalidToken, resp.StatusCode)
+	default:
+		return Principal{}, fmt.Errorf("introspect: unexpected status %d", resp.StatusCode)
+	}
+
+	var me meResponse
+	if err := json.NewDecoder(resp.Body).Decode(&me); err != nil {
+		return Principal{}, fmt.Errorf("introspect: %w", err)
+	}
+	if me.Data.User.IsBanned {
+		return Principal{}, fmt.Errorf("%w: user is banned", ErrInvalidToken)
+	}
+	if v.serviceID != 0 && me.Data.ServiceID != v.serviceID {
+		return Principal{}, fmt.Errorf("%w: token issued for service %d", ErrInvalidToken, me.Data.ServiceID)
+	}
+
+	return Principal{
+		UserID:      me.Data.User.TgId,
+		SubjectType: SubjectUser,
+		ServiceID:   me.Data.ServiceID,
+		Roles:       me.Data.Roles,
+		ExpiresAt:   unverifiedExpiry(token),
+	}, nil
+}
+
+// unverifiedExpiry читает exp без проверки подписи - токен уже проверил SSO,
+// значение нужно только Cache, чтобы не держать результат дольше жизни токена.
+func unverifiedExpiry(token string) time.Time {
+	var claims jwt.MapClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return time.Time{}
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}
+	}
+	return exp.Time
+}
@@ -0,0 +1,171 @@
+package ssoclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksRefetchInterval ограничивает повторную загрузку набора при неизвестном kid,
+// чтобы поток мусорных токенов не превращался в поток запросов к SSO.
+const jwksRefetchInterval = 30 * time.Second
+
+// JWKSVerifier проверяет асимметрично подписанные токены (RS*, ES*) ключами из
+// JWK Set - у SSO это <адрес>/.well-known/jwks.json. Набор кэшируется на
+// refresh и перечитывается раньше, если пришел токен с незнакомым kid, например
+// после ротации ключей.
+type JWKSVerifier struct {
+	url       string
+	client    *http.Client
+	serviceID int64
+	refresh   time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+// NewJWKSVerifier принимает только токены для serviceID (0 - любого приложения).
+// client == nil означает http.Client с таймаутом 5 секунд.
+func NewJWKSVerifier(url string, serviceID int64, refresh time.Duration, client *http.Client) *JWKSVerifier {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &JWKSVerifier{url: url, client: client, serviceID: serviceID, refresh: refresh}
+}
+
+func (v *JWKSVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	var claims jwt.MapClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	}, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}), jwt.WithExpirationRequired())
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return principalFromClaims(claims, v.serviceID)
+}
+
+func (v *JWKSVerifier) key(ctx context.Context, kid string) (any, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	stale := time.Since(v.fetchedAt) > v.refresh
+	_, known := v.keys[kid]
+	if stale || (!known && time.Since(v.fetchedAt) > jwksRefetchInterval) {
+		if err := v.fetch(ctx); err != nil && v.keys == nil {
+			return nil, err
+		}
+	}
+
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	return key, nil
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *JWKSVerifier) fetch(ctx context.Context) error {
+	// Даже неудачная попытка сдвигает fetchedAt: при недоступном SSO работаем
+	// на прошлом наборе, а не ходим за ним на каждый запрос.
+	v.fetchedAt = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return fmt.Errorf("jwks: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			// Ключи неизвестных типов пропускаем, остальные остаются рабочими.
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	v.keys = keys
+	return nil
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, errors.New("unsupported key type " + k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
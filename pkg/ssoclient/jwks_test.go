@@ -0,0 +1,59 @@
+package ssoclient_test
+
+import (
+	"auth-service/pkg/ssoclient"
+	"auth-service/pkg/ssoclient/ssofake"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWKSVerifier(t *testing.T) {
+	fake, err := ssofake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fake.Close()
+	ctx := context.Background()
+
+	client := ssoclient.NewHTTP(fake.URL, nil)
+	token, err := client.Register(ctx, fake.InitData(42, "alice"), "Alice", ssofake.ServiceID)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	p, err := fake.Verifier().Verify(ctx, token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if p.UserID == "" || p.ServiceID != ssofake.ServiceID || !p.HasRole(ssoclient.RoleUser) || p.SessionID == "" {
+		t.Fatalf("unexpected principal %+v", p)
+	}
+
+	other := ssoclient.NewJWKSVerifier(fake.JWKSURL, ssofake.ServiceID+1, time.Minute, nil)
+	if _, err := other.Verify(ctx, token); !errors.Is(err, ssoclient.ErrInvalidToken) {
+		t.Fatalf("token of another service: %v, want %v", err, ssoclient.ErrInvalidToken)
+	}
+
+	// Токен того же вида, но подписанный чужим ключом, не проходит.
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodES256, parsed.Claims)
+	forged.Header["kid"] = parsed.Header["kid"]
+	forgedToken, _ := forged.SignedString(key)
+	if _, err := fake.Verifier().Verify(ctx, forgedToken); !errors.Is(err, ssoclient.ErrInvalidToken) {
+		t.Fatalf("forged token: %v, want %v", err, ssoclient.ErrInvalidToken)
+	}
+	if _, err := ssoclient.NewSecretVerifier(ssofake.ServiceID, ssofake.AppSecret).Verify(ctx, token); !errors.Is(err, ssoclient.ErrInvalidToken) {
+		t.Fatalf("ES256 token passed SecretVerifier: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package ssoclient проверяет токены, выпущенные auth-sso, в сервисах-потребителях.
+//
+// Токен проверяется одним из Verifier: открытыми ключами SSO из
+// /.well-known/jwks.json (JWKSVerifier, если в SSO заданы signing.key_files),
+// общим секретом приложения (SecretVerifier, для HS256-токенов) или запросом к
+// самому SSO (IntrospectionVerifier), который заодно видит отзыв сессии и роль admin.
+// Middleware и интерсепторы gRPC кладут результат в контекст как Principal.
+package ssoclient
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Роли и типы субъекта совпадают со значениями из models в SSO.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+
+	SubjectUser    = "user"
+	SubjectService = "service"
+)
+
+// Principal - владелец токена.
+type Principal struct {
+	// UserID - claim sub, под которым SSO хранит пользователя. У сервисных
+	// токенов это "app:<id>".
+	UserID string
+	// SubjectType - SubjectUser или SubjectService (токен client_credentials).
+	SubjectType string
+	ServiceID   int64
+	// Roles берутся из claim roles, при интроспекции - из ответа /v1/me.
+	Roles []string
+	// Scopes - права сервисного токена или токена, полученного обменом.
+	Scopes []string
+	// Actor задан у токенов, полученных обменом (RFC 8693): какое приложение
+	// действует от имени пользователя.
+	Actor *Actor
+	// SessionID - claim sid, общий для токенов одного входа. Проверка подписью
+	// не знает о завершенных сессиях, это видит только IntrospectionVerifier.
+	SessionID string
+	ExpiresAt time.Time
+}
+
+// Actor - звено claim act, вложенный Actor - предыдущий обмен в цепочке.
+type Actor struct {
+	Subject string
+	Actor   *Actor
+}
+
+func (p Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает Principal, положенный Middleware или интерсептором.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
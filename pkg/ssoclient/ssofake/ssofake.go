@@ -0,0 +1,272 @@
+// Package ssofake поднимает настоящие HTTP- и gRPC-обработчики SSO поверх
+// хранилища в памяти - для тестов сервисов, которые ходят в SSO через ssoclient.
+//
+//	fake, err := ssofake.New()
+//	defer fake.Close()
+//	client := ssoclient.NewHTTP(fake.URL, nil)
+//	res, err := client.Register(ctx, fake.InitData(42, "durov"), "durov", ssofake.ServiceID)
+//
+// Фейк подписывает токены ES256 ключом, созданным при запуске, поэтому их
+// проверяет JWKSVerifier с fake.JWKSURL или IntrospectionVerifier.
+package ssofake
+
+import (
+	"auth-service/internal/domains/models"
+	"auth-service/internal/grpc/api"
+	authgrpc "auth-service/internal/grpc/auth"
+	"auth-service/internal/grpc/middleware"
+	"auth-service/internal/lib/crypto"
+	"auth-service/internal/lib/jwt"
+	"auth-service/internal/lib/mail"
+	"auth-service/internal/lib/oidc"
+	"auth-service/internal/lib/oidc/oidcfake"
+	"auth-service/internal/lib/totp"
+	"auth-service/internal/services/auth"
+	"auth-service/internal/storage/memory"
+	"auth-service/pkg/ssoclient"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+	"google.golang.org/grpc"
+)
+
+const (
+	// BotToken подписывает initData, которые выдает InitData.
+	BotToken = "1:ssofake-bot-token"
+	// ServiceID и AppSecret - приложение, которое есть в фейке сразу.
+	ServiceID = 1
+	AppSecret = "test-secret"
+	// ClientID - aud ID-токенов Google и Apple, которые выдает IDToken.
+	ClientID = "ssofake-client"
+
+	tokenTTL   = time.Hour
+	handoffTTL = 5 * time.Minute
+	// maxFailures - после стольких неверных паролей или кодов второго фактора
+	// подряд вход блокируется.
+	maxFailures = 5
+	stepUpTTL   = 15 * time.Minute
+)
+
+type Server struct {
+	// URL - адрес HTTP API, GRPCAddr - адрес gRPC-сервиса sso.v1.Auth,
+	// JWKSURL - открытые ключи для JWKSVerifier.
+	URL      string
+	GRPCAddr string
+	JWKSURL  string
+
+	storage   *memory.Storage
+	http      *httptest.Server
+	grpc      *grpc.Server
+	providers map[string]*oidcfake.Server
+	mailDir   string
+}
+
+// Mail - письмо, которое фейк "отправил" пользователю.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+func New() (*Server, error) {
+	if !crypto.Initialized() {
+		crypto.InitCrypto("ssofake")
+	}
+
+	st := memory.New()
+	providers := map[string]*oidcfake.Server{
+		models.ProviderGoogle: oidcfake.New(),
+		models.ProviderApple:  oidcfake.New(),
+	}
+	verifiers := make(map[string]auth.IdentityVerifier, len(providers))
+	for name, provider := range providers {
+		verifiers[name] = oidc.New(provider.Provider(ClientID), time.Second)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("ssofake: %w", err)
+	}
+	keys, err := jwt.NewKeys(key)
+	if err != nil {
+		return nil, fmt.Errorf("ssofake: %w", err)
+	}
+	mailDir, err := os.MkdirTemp("", "ssofake-mail-")
+	if err != nil {
+		return nil, fmt.Errorf("ssofake: %w", err)
+	}
+	password := auth.PasswordPolicy{
+		Enabled:         true,
+		MaxFailures:     maxFailures,
+		LockoutDuration: 15 * time.Minute,
+		VerifyTTL:       time.Hour,
+		ResetTTL:        time.Hour,
+	}
+	twoFactor := auth.TwoFactorPolicy{
+		Enabled:           true,
+		RequiredForAdmins: true,
+		StepUpTTL:         stepUpTTL,
+		Issuer:            "ssofake",
+		MaxFailures:       maxFailures,
+		LockoutDuration:   15 * time.Minute,
+	}
+	authService := auth.New(auth.Deps{
+		Log:          slog.New(slog.DiscardHandler),
+		UserSaver:    st,
+		UserProvider: st,
+		AppProvider:  st,
+		Sessions:     st,
+		Audit:        st,
+		Outbox:       st,
+		Accounts:     st,
+		Handoffs:     st,
+		Identities:   st,
+		Passwords:    st,
+		Factors:      st,
+		Providers:    verifiers,
+		Mailer:       mail.NewFile(filepath.Join(mailDir, "mail.jsonl")),
+	}, auth.Config{
+		TokenTTL:        tokenTTL,
+		ServiceTokenTTL: tokenTTL,
+		Handoff:         auth.HandoffPolicy{TTL: handoffTTL},
+		Password:        password,
+		TwoFactor:       twoFactor,
+		BotToken:        BotToken,
+		Keys:            keys,
+	})
+
+	router := mux.NewRouter()
+	router.Use(middleware.ClientInfo(0))
+	api.RegisterDocs(router)
+	authgrpc.RegisterV1(router, *authService)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("ssofake: %w", err)
+	}
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(middleware.UnaryClientInfo()),
+		grpc.StreamInterceptor(middleware.StreamClientInfo()),
+	)
+	authgrpc.RegisterGRPC(grpcServer, *authService)
+	go grpcServer.Serve(l)
+
+	httpServer := httptest.NewServer(router)
+
+	return &Server{
+		URL:       httpServer.URL,
+		GRPCAddr:  l.Addr().String(),
+		JWKSURL:   httpServer.URL + "/.well-known/jwks.json",
+		storage:   st,
+		http:      httpServer,
+		grpc:      grpcServer,
+		providers: providers,
+		mailDir:   mailDir,
+	}, nil
+}
+
+func (s *Server) Close() {
+	s.http.Close()
+	s.grpc.Stop()
+	for _, provider := range s.providers {
+		provider.Close()
+	}
+	os.RemoveAll(s.mailDir)
+}
+
+// Mails возвращает отправленные письма в порядке отправки. Письма подтверждения
+// email и сброса пароля содержат код - токен для /v1/auth/email/verify
+// и /v1/auth/email/reset/confirm.
+func (s *Server) Mails() ([]Mail, error) {
+	messages, err := mail.ReadFile(filepath.Join(s.mailDir, "mail.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("ssofake: %w", err)
+	}
+	mails := make([]Mail, len(messages))
+	for i, m := range messages {
+		mails[i] = Mail{To: m.To, Subject: m.Subject, Body: m.Body}
+	}
+	return mails, nil
+}
+
+// InitData возвращает initData пользователя tgID, подписанные BotToken.
+func (s *Server) InitData(tgID int64, username string) string {
+	user, _ := json.Marshal(map[string]any{
+		"id":         tgID,
+		"first_name": username,
+		"username":   username,
+	})
+	authDate := time.Now()
+	payload := map[string]string{"user": string(user)}
+
+	values := url.Values{}
+	values.Set("user", string(user))
+	values.Set("auth_date", strconv.FormatInt(authDate.Unix(), 10))
+	values.Set("hash", initdata.Sign(payload, BotToken, authDate))
+	return values.Encode()
+}
+
+// IDToken возвращает ID-токен провайдера models.ProviderGoogle или
+// models.ProviderApple для пользователя subject у провайдера.
+func (s *Server) IDToken(provider, subject, email string) string {
+	return s.providers[provider].IDToken(ClientID, subject, email)
+}
+
+// TOTPCode возвращает текущий код для секрета из ответа /v1/me/2fa/totp.
+// Код шага принимается один раз, поэтому следующий код нужен уже от
+// TOTPCodeAt со временем следующего шага.
+func (s *Server) TOTPCode(secret string) string {
+	return s.TOTPCodeAt(secret, time.Now())
+}
+
+func (s *Server) TOTPCodeAt(secret string, at time.Time) string {
+	code, _ := totp.Code(secret, totp.Step(at))
+	return code
+}
+
+// AddApp регистрирует еще одно приложение.
+func (s *Server) AddApp(id int32, name, secret string) {
+	s.storage.SaveApp(context.Background(), models.App{ID: id, Name: name, Secret: secret})
+}
+
+// AddUser сохраняет пользователя напрямую, например сразу администратором.
+// Администратору, как и в SSO по умолчанию, для admin-check и роли admin в
+// /v1/me нужен подтвержденный TOTP (/v1/me/2fa/totp) и свежий код.
+func (s *Server) AddUser(tgID int64, username string, admin bool) error {
+	tgHash, err := crypto.HashTgID(tgID)
+	if err != nil {
+		return err
+	}
+	return s.storage.SaveUser(context.Background(), tgHash, models.User{
+		FirstName:      username,
+		Username:       username,
+		UserNameLocale: username,
+		IsAdmin:        admin,
+	})
+}
+
+func (s *Server) SetBanned(tgID int64, banned bool) error {
+	tgHash, err := crypto.HashTgID(tgID)
+	if err != nil {
+		return err
+	}
+	return s.storage.SetBanned(context.Background(), tgHash, banned)
+}
+
+// Verifier проверяет токены, выпущенные фейком для ServiceID, по его JWKS.
+func (s *Server) Verifier() ssoclient.Verifier {
+	return ssoclient.NewJWKSVerifier(s.JWKSURL, ServiceID, time.Minute, nil)
+}
@@ -0,0 +1,113 @@
+package ssoclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrNoToken      = errors.New("ssoclient: no bearer token")
+	ErrInvalidToken = errors.New("ssoclient: invalid token")
+)
+
+// Verifier проверяет токен и возвращает его владельца.
+type Verifier interface {
+	Verify(ctx context.Context, token string) (Principal, error)
+}
+
+// SecretVerifier проверяет HS256-токены секретом приложения из таблицы apps -
+// тем же, которым их подписывает SSO без ключей signing.key_files.
+type SecretVerifier struct {
+	serviceID int64
+	secret    []byte
+}
+
+// NewSecretVerifier принимает только токены, выпущенные для serviceID.
+func NewSecretVerifier(serviceID int64, secret string) *SecretVerifier {
+	return &SecretVerifier{serviceID: serviceID, secret: []byte(secret)}
+}
+
+func (v *SecretVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	var claims jwt.MapClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return v.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return principalFromClaims(claims, v.serviceID)
+}
+
+// principalFromClaims разбирает claims jwt.NewToken: sub, serviceID, exp и
+// необязательный roles. При serviceID != 0 токен другого приложения отклоняется.
+func principalFromClaims(claims jwt.MapClaims, serviceID int64) (Principal, error) {
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return Principal{}, fmt.Errorf("%w: sub missing", ErrInvalidToken)
+	}
+	tokenService, ok := claims["serviceID"].(float64)
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: serviceID missing", ErrInvalidToken)
+	}
+	if serviceID != 0 && int64(tokenService) != serviceID {
+		return Principal{}, fmt.Errorf("%w: token issued for service %d", ErrInvalidToken, int64(tokenService))
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return Principal{}, fmt.Errorf("%w: exp missing", ErrInvalidToken)
+	}
+
+	scope, _ := claims["scope"].(string)
+	sid, _ := claims["sid"].(string)
+	p := Principal{
+		UserID:      sub,
+		SubjectType: SubjectUser,
+		ServiceID:   int64(tokenService),
+		Scopes:      strings.Fields(scope),
+		Actor:       parseActor(claims["act"]),
+		SessionID:   sid,
+		ExpiresAt:   exp.Time,
+	}
+	if subType, _ := claims["sub_type"].(string); subType == SubjectService {
+		// Сервисный токен client_credentials: вместо ролей - права из claim scope.
+		p.SubjectType = SubjectService
+		return p, nil
+	}
+
+	roles, ok := claims["roles"].([]any)
+	if !ok {
+		// Пользовательские токены jwt.NewToken ролей не несут: известно лишь, что
+		// это зарегистрированный пользователь. Права администратора дает только интроспекция.
+		p.Roles = []string{RoleUser}
+		return p, nil
+	}
+	for _, role := range roles {
+		if s, ok := role.(string); ok {
+			p.Roles = append(p.Roles, s)
+		}
+	}
+	return p, nil
+}
+
+func parseActor(v any) *Actor {
+	act, ok := v.(map[string]any)
+	if !ok {
+		return nil
+	}
+	sub, _ := act["sub"].(string)
+	return &Actor{Subject: sub, Actor: parseActor(act["act"])}
+}
+
+// bearer достает токен из значения заголовка Authorization.
+func bearer(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,99 @@
+package ssoclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Типы событий вебхуков SSO.
+const (
+	EventUserRegistered = "user.registered"
+	EventUserUpdated    = "user.updated"
+	EventUserBanned     = "user.banned"
+	EventUserDeleted    = "user.deleted"
+	EventSessionRevoked = "session.revoked"
+)
+
+// DefaultWebhookTolerance - насколько X-SSO-Timestamp может разойтись с часами
+// получателя. Защищает от повторной отправки перехваченного запроса.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// maxWebhookBody ограничивает тело события, которое читает ParseWebhook.
+const maxWebhookBody = 1 << 20
+
+var ErrInvalidSignature = errors.New("ssoclient: invalid webhook signature")
+
+// WebhookEvent - тело запроса доставки. ID события стабилен между повторами
+// и подходит как ключ идемпотентности; Data разбирается в UserEvent или SessionEvent по Type.
+type WebhookEvent struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// UserEvent - данные событий user.*. Для user.deleted заполнен только Sub.
+type UserEvent struct {
+	Sub            string `json:"sub"`
+	FirstName      string `json:"first_name,omitempty"`
+	LastName       string `json:"last_name,omitempty"`
+	Username       string `json:"user_name,omitempty"`
+	UserNameLocale string `json:"user_name_locale,omitempty"`
+	PhotoURL       string `json:"photo_url,omitempty"`
+	IsBanned       bool   `json:"is_banned"`
+}
+
+// SessionEvent - данные события session.revoked.
+type SessionEvent struct {
+	Sub       string `json:"sub"`
+	SessionID string `json:"session_id"`
+}
+
+// ParseWebhook проверяет подпись запроса доставки секретом приложения и
+// разбирает событие. tolerance <= 0 означает DefaultWebhookTolerance.
+func ParseWebhook(r *http.Request, secret string, tolerance time.Duration) (WebhookEvent, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		return WebhookEvent{}, fmt.Errorf("ssoclient: read webhook: %w", err)
+	}
+	if err := VerifyWebhook(secret, r.Header.Get("X-SSO-Timestamp"), r.Header.Get("X-SSO-Signature"), body, tolerance); err != nil {
+		return WebhookEvent{}, err
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return WebhookEvent{}, fmt.Errorf("ssoclient: decode webhook: %w", err)
+	}
+	return event, nil
+}
+
+// VerifyWebhook проверяет заголовки X-SSO-Timestamp и X-SSO-Signature для тела body.
+func VerifyWebhook(secret, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrInvalidSignature)
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(want)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}